@@ -0,0 +1,159 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/songtools/songtools"
+)
+
+// KeyCandidate is a key a song might be in, along with how confident the guess is.
+type KeyCandidate struct {
+	Key songtools.Key
+	// Confidence is between 0 and 1. The confidences of all the candidates add up to 1.
+	Confidence float64
+	// Score is the raw score the key received.
+	Score float64
+}
+
+var candidateKeys = mustParseKeys(
+	"C", "Db", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B",
+	"Cm", "C#m", "Dm", "Ebm", "Em", "Fm", "F#m", "Gm", "G#m", "Am", "Bbm", "Bm",
+)
+
+func mustParseKeys(names ...string) []songtools.Key {
+	keys := []songtools.Key{}
+	for _, name := range names {
+		keys = append(keys, songtools.MustParseKey(name))
+	}
+
+	return keys
+}
+
+const (
+	diatonicScore          = 1.0
+	secondaryDominantScore = 0.4
+	borrowedScore          = 0.3
+	chromaticScore         = -0.5
+	tonicScore             = 0.5
+	firstOrLastWeight      = 3.0
+	authenticCadenceScore  = 2.0
+	plagalCadenceScore     = 1.0
+)
+
+// DetectKey scores all 24 major and minor keys against the chords of the song and returns the
+// keys that fit, best first. Chords that fit the key's scale score higher than chromatic ones,
+// the first and last chords count more than the rest, and cadences into the tonic add to the score.
+// Only the chords before the song's first key change are used, so the key found is the one the
+// song starts in.
+func DetectKey(s *songtools.Song) []*KeyCandidate {
+	chords := []*songtools.Chord{}
+	for _, kc := range s.KeyedChords() {
+		if kc.Key != s.Key {
+			break
+		}
+		chords = append(chords, kc.Chord)
+	}
+
+	return DetectKeyFromChords(chords)
+}
+
+// DetectKeyFromChords scores all 24 major and minor keys against the chords, as DetectKey does.
+func DetectKeyFromChords(chords []*songtools.Chord) []*KeyCandidate {
+	known := []*songtools.Chord{}
+	for _, c := range chords {
+		if !c.Unknown {
+			known = append(known, c)
+		}
+	}
+
+	if len(known) == 0 {
+		return nil
+	}
+
+	candidates := []*KeyCandidate{}
+	total := 0.0
+	for _, key := range candidateKeys {
+		score := scoreKey(known, key)
+		if score <= 0 {
+			continue
+		}
+
+		candidates = append(candidates, &KeyCandidate{
+			Key:   key,
+			Score: score,
+		})
+		total += score
+	}
+
+	for _, c := range candidates {
+		c.Confidence = c.Score / total
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	return candidates
+}
+
+func scoreKey(chords []*songtools.Chord, key songtools.Key) float64 {
+	score := 0.0
+	var previous *Numeral
+	for i, c := range chords {
+		n, ok := AnalyzeChord(c, key)
+		if !ok {
+			previous = nil
+			continue
+		}
+
+		weight := 1.0
+		if i == 0 || i == len(chords)-1 {
+			weight = firstOrLastWeight
+		}
+
+		fit := chromaticScore
+		switch n.Function {
+		case Diatonic:
+			fit = diatonicScore
+		case SecondaryDominant:
+			fit = secondaryDominantScore
+		case Borrowed:
+			fit = borrowedScore
+		}
+
+		if isTonic(n, key) {
+			fit += tonicScore
+			if previous != nil {
+				score += cadenceScore(previous)
+			}
+		}
+
+		score += weight * fit
+		previous = n
+	}
+
+	return score
+}
+
+// isTonic indicates whether the chord is the tonic chord of the key.
+func isTonic(n *Numeral, key songtools.Key) bool {
+	if n.Degree != 1 || n.Accidental != 0 {
+		return false
+	}
+
+	return n.Chord.Quality == triadQuality(key.Scale(), 0)
+}
+
+// cadenceScore scores the chord that moves to the tonic.
+func cadenceScore(n *Numeral) float64 {
+	switch {
+	case n.Degree == 5 && n.Accidental == 0 && n.Chord.Quality == songtools.Major:
+		return authenticCadenceScore
+	case n.Degree == 7 && n.Chord.Quality == songtools.Diminished:
+		return authenticCadenceScore
+	case n.Degree == 4 && n.Accidental == 0:
+		return plagalCadenceScore
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+// parseChords parses the chords separated by spaces.
+func parseChords(names string) []*songtools.Chord {
+	chords := []*songtools.Chord{}
+	for _, name := range strings.Fields(names) {
+		c, ok := songtools.ParseChord(name)
+		if !ok {
+			c = songtools.UnknownChord(name)
+		}
+		chords = append(chords, c)
+	}
+
+	return chords
+}
+
+func TestScoreKey(t *testing.T) {
+	tests := []struct {
+		chords   string
+		key      string
+		expected float64
+	}{
+		// the first and last chords count three times.
+		{"C", "C", 3 * (diatonicScore + tonicScore)},
+		{"C Dm C", "C", 3*(diatonicScore+tonicScore)*2 + diatonicScore},
+		{"G C", "C", 3*diatonicScore + 3*(diatonicScore+tonicScore) + authenticCadenceScore},
+		{"F C", "C", 3*diatonicScore + 3*(diatonicScore+tonicScore) + plagalCadenceScore},
+		{"Bdim C", "C", 3*diatonicScore + 3*(diatonicScore+tonicScore) + authenticCadenceScore},
+		{"C D7 G C", "C", 3*(diatonicScore+tonicScore)*2 + secondaryDominantScore + diatonicScore + authenticCadenceScore},
+		{"C Bb F C", "C", 3*(diatonicScore+tonicScore)*2 + borrowedScore + diatonicScore + plagalCadenceScore},
+		{"C F# C", "C", 3*(diatonicScore+tonicScore)*2 + chromaticScore},
+		{"Am Dm E7 Am", "Am", 3*(diatonicScore+tonicScore)*2 + 2*diatonicScore + authenticCadenceScore},
+		{"Am Dm E7 Am", "C", 3*diatonicScore*2 + diatonicScore + secondaryDominantScore},
+		// a chord that can't be analyzed breaks a cadence.
+		{"G N.C. C", "C", 3*diatonicScore + 3*(diatonicScore+tonicScore)},
+	}
+
+	for _, test := range tests {
+		if actual := scoreKey(parseChords(test.chords), songtools.MustParseKey(test.key)); math.Abs(actual-test.expected) > 1e-9 {
+			t.Errorf("scoreKey(%q, %v) = %v, expected %v", test.chords, test.key, actual, test.expected)
+		}
+	}
+}
+
+func TestDetectKeyFromChords(t *testing.T) {
+	tests := []struct {
+		chords   string
+		expected string
+	}{
+		{"C F G C", "C"},
+		{"G D Em C G", "G"},
+		{"Am Dm E7 Am", "Am"},
+		{"Em Am B7 Em", "Em"},
+		{"Bb Eb F7 Bb", "Bb"},
+		{"F#m Bm C#7 F#m", "F#m"},
+		{"D G A7 D", "D"},
+		{"N.C. E A B7 E", "E"},
+	}
+
+	for _, test := range tests {
+		candidates := DetectKeyFromChords(parseChords(test.chords))
+		if len(candidates) == 0 {
+			t.Errorf("DetectKeyFromChords(%q) found no keys", test.chords)
+			continue
+		}
+		if candidates[0].Key.String() != test.expected {
+			t.Errorf("DetectKeyFromChords(%q) = %v, expected %v", test.chords, candidates[0].Key, test.expected)
+		}
+
+		total := 0.0
+		for i, c := range candidates {
+			total += c.Confidence
+			if c.Score <= 0 {
+				t.Errorf("DetectKeyFromChords(%q) kept %v, which scored %v", test.chords, c.Key, c.Score)
+			}
+			if i > 0 && c.Score > candidates[i-1].Score {
+				t.Errorf("DetectKeyFromChords(%q) put %v before %v", test.chords, candidates[i-1].Key, c.Key)
+			}
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Errorf("DetectKeyFromChords(%q) has confidences adding up to %v", test.chords, total)
+		}
+	}
+
+	if candidates := DetectKeyFromChords(parseChords("N.C. x")); candidates != nil {
+		t.Errorf("expected no keys without any known chords, but found %v", candidates)
+	}
+}
+
+func TestDetectKeyConfidence(t *testing.T) {
+	// a cadence into the tonic makes a key more certain.
+	plain := DetectKeyFromChords(parseChords("C F G Am"))
+	cadence := DetectKeyFromChords(parseChords("C F G C"))
+	if cadence[0].Key.String() != "C" || cadence[0].Confidence <= confidenceOf(plain, "C") {
+		t.Errorf("expected C to be more certain with a cadence, %v against %v", cadence[0].Confidence, confidenceOf(plain, "C"))
+	}
+}
+
+// confidenceOf gets the confidence of the key among the candidates.
+func confidenceOf(candidates []*KeyCandidate, key string) float64 {
+	for _, c := range candidates {
+		if c.Key.String() == key {
+			return c.Confidence
+		}
+	}
+
+	return 0
+}
+
+func TestDetectKeyStopsAtAKeyChange(t *testing.T) {
+	line := func(chords string) *songtools.Line {
+		l := &songtools.Line{}
+		for i, c := range parseChords(chords) {
+			l.Chords = append(l.Chords, c)
+			l.ChordPositions = append(l.ChordPositions, i)
+		}
+		return l
+	}
+
+	s := &songtools.Song{
+		Key: songtools.MustParseKey("G"),
+		Nodes: []songtools.SongNode{
+			&songtools.Section{Nodes: []songtools.SectionNode{line("G C D G")}},
+			&songtools.KeyChange{Key: songtools.MustParseKey("A")},
+			&songtools.Section{Nodes: []songtools.SectionNode{line("A D E A A D E A")}},
+		},
+	}
+
+	if candidates := DetectKey(s); len(candidates) == 0 || candidates[0].Key.String() != "G" {
+		t.Errorf("expected the song to start in G, but found %v", candidates)
+	}
+
+	// the chords after the key change are in the new key.
+	after := []*songtools.Chord{}
+	for _, kc := range s.KeyedChords() {
+		if kc.Key.String() == "A" {
+			after = append(after, kc.Chord)
+		}
+	}
+	if candidates := DetectKeyFromChords(after); len(candidates) == 0 || candidates[0].Key.String() != "A" {
+		t.Errorf("expected the song to change to A, but found %v", candidates)
+	}
+}
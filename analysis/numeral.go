@@ -0,0 +1,260 @@
+// Package analysis provides harmonic analysis of songs.
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+// Function describes the role a chord plays in a key.
+type Function int
+
+// The functions a chord can have.
+const (
+	// Diatonic chords are made up entirely of notes from the key's scale.
+	Diatonic Function = iota
+	// SecondaryDominant chords are the dominant, or leading-tone, chord of a diatonic chord other than the tonic.
+	SecondaryDominant
+	// Borrowed chords are diatonic to the parallel major or minor key.
+	Borrowed
+	// Chromatic chords are everything else.
+	Chromatic
+)
+
+func (f Function) String() string {
+	switch f {
+	case Diatonic:
+		return "diatonic"
+	case SecondaryDominant:
+		return "secondary dominant"
+	case Borrowed:
+		return "borrowed"
+	case Chromatic:
+		return "chromatic"
+	default:
+		return "unknown"
+	}
+}
+
+// Numeral is the Roman numeral analysis of a chord in a key, such as I, ii, V7/V or bVII.
+type Numeral struct {
+	Chord    *songtools.Chord
+	Function Function
+
+	// Degree is the scale degree of the chord's root, starting from 1, and Accidental
+	// is how far the root is raised or lowered from the key's scale.
+	Degree     int
+	Accidental int
+
+	// Target is the numeral of the chord a secondary dominant resolves to.
+	Target *Numeral
+
+	text string
+}
+
+func (n *Numeral) String() string {
+	return n.text
+}
+
+var romanNumerals = []string{"I", "II", "III", "IV", "V", "VI", "VII"}
+
+// AnalyzeChord returns the Roman numeral of the chord in the key.
+func AnalyzeChord(c *songtools.Chord, key songtools.Key) (*Numeral, bool) {
+	scale := key.Scale()
+	if scale == nil || c.Unknown {
+		return nil, false
+	}
+
+	root := c.RootSpelling(key)
+	degree, accidental, ok := key.ScaleDegree(root)
+	if !ok {
+		return nil, false
+	}
+
+	n := &Numeral{
+		Chord:      c,
+		Degree:     degree,
+		Accidental: accidental,
+	}
+
+	switch {
+	case inScale(c, scale) || (key.Mode == songtools.Aeolian && inScale(c, harmonicMinor(scale))):
+		n.Function = Diatonic
+	case analyzeSecondary(n, key, scale):
+		n.Function = SecondaryDominant
+	case inScale(c, parallelScale(key)):
+		n.Function = Borrowed
+	default:
+		n.Function = Chromatic
+	}
+
+	if n.Function != SecondaryDominant {
+		prefix := accidentalPrefix(n.Accidental)
+		if key.Mode == songtools.Aeolian && n.Degree == 7 && n.Accidental == 1 {
+			// the raised leading tone of a minor key is written without an accidental.
+			prefix = ""
+		}
+		n.text = prefix + figure(c, romanNumerals[n.Degree-1])
+	}
+
+	return n, true
+}
+
+// Analyze returns the Roman numerals of all the chords in the song, in the song's key or in the
+// key of the latest key change. Chords that cannot be analyzed, such as unknown chords, are skipped.
+func Analyze(s *songtools.Song) ([]*Numeral, error) {
+	if s.Key.IsZero() {
+		return nil, fmt.Errorf("unable to analyze without a key")
+	}
+
+	numerals := []*Numeral{}
+	for _, kc := range s.KeyedChords() {
+		if n, ok := AnalyzeChord(kc.Chord, kc.Key); ok {
+			numerals = append(numerals, n)
+		}
+	}
+
+	return numerals, nil
+}
+
+// analyzeSecondary determines whether the chord is a dominant or leading-tone chord of a
+// diatonic chord other than the tonic, filling out the numeral when it is.
+func analyzeSecondary(n *Numeral, key songtools.Key, scale []songtools.Spelling) bool {
+	c := n.Chord
+
+	var targetRoot songtools.Note
+	var text string
+	switch {
+	case c.Quality == songtools.Major && !c.MajorSeventh && len(c.Alterations) == 0:
+		targetRoot = c.Root.Interval(-7)
+		text = figure(c, "V")
+	case c.Quality == songtools.Diminished || c.Quality == songtools.HalfDiminished:
+		targetRoot = c.Root.Interval(1)
+		text = figure(c, "vii")
+	default:
+		return false
+	}
+
+	for i, s := range scale {
+		if i == 0 || s.Note() != targetRoot {
+			continue
+		}
+
+		target := triadQuality(scale, i)
+		if target == songtools.Diminished {
+			// diminished chords aren't tonicized.
+			return false
+		}
+
+		targetName := romanNumerals[i]
+		if target == songtools.Minor {
+			targetName = strings.ToLower(targetName)
+		}
+
+		n.Target = &Numeral{
+			Function: Diatonic,
+			Degree:   i + 1,
+			text:     targetName,
+		}
+		n.text = text + "/" + targetName
+		return true
+	}
+
+	return false
+}
+
+// figure writes the numeral in the case and with the symbols that match the chord's quality.
+func figure(c *songtools.Chord, numeral string) string {
+	switch c.Quality {
+	case songtools.Minor:
+		numeral = strings.ToLower(numeral)
+		if c.Extension == 7 && hasAlteration(c, 5, -1) {
+			// a minor seventh with a flat five is half-diminished.
+			return numeral + "ø7"
+		}
+	case songtools.Diminished:
+		numeral = strings.ToLower(numeral) + "°"
+	case songtools.HalfDiminished:
+		numeral = strings.ToLower(numeral) + "ø"
+	case songtools.Augmented:
+		numeral += "+"
+	}
+
+	if c.Extension > 0 {
+		if c.MajorSeventh {
+			numeral += "maj"
+		}
+		numeral += fmt.Sprint(c.Extension)
+	}
+
+	return numeral
+}
+
+func accidentalPrefix(accidental int) string {
+	if accidental < 0 {
+		return strings.Repeat("b", -accidental)
+	}
+
+	return strings.Repeat("#", accidental)
+}
+
+func hasAlteration(c *songtools.Chord, degree, accidental int) bool {
+	for _, a := range c.Alterations {
+		if a.Degree == degree && a.Accidental == accidental {
+			return true
+		}
+	}
+
+	return false
+}
+
+// harmonicMinor raises the 7th of the natural minor scale.
+func harmonicMinor(scale []songtools.Spelling) []songtools.Spelling {
+	harmonic := append([]songtools.Spelling{}, scale...)
+	harmonic[6].Accidental++
+	return harmonic
+}
+
+// inScale indicates whether all the notes of the chord are in the scale.
+func inScale(c *songtools.Chord, scale []songtools.Spelling) bool {
+	for _, note := range c.Notes() {
+		found := false
+		for _, s := range scale {
+			if s.Note() == note {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// triadQuality is the quality of the triad built on the degree of the scale.
+func triadQuality(scale []songtools.Spelling, degree int) songtools.Quality {
+	root := scale[degree].Note()
+	third := int(scale[(degree+2)%len(scale)].Note().Interval(-int(root)))
+	fifth := int(scale[(degree+4)%len(scale)].Note().Interval(-int(root)))
+
+	switch {
+	case third == 4 && fifth == 7:
+		return songtools.Major
+	case third == 3 && fifth == 7:
+		return songtools.Minor
+	case third == 3 && fifth == 6:
+		return songtools.Diminished
+	default:
+		return songtools.Augmented
+	}
+}
+
+// parallelScale is the scale of the major or minor key with the same tonic.
+func parallelScale(key songtools.Key) []songtools.Spelling {
+	return key.Parallel().Scale()
+}
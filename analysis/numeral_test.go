@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestAnalyzeChord(t *testing.T) {
+	tests := []struct {
+		chord      string
+		key        string
+		expected   string
+		function   Function
+		degree     int
+		accidental int
+	}{
+		{"C", "C", "I", Diatonic, 1, 0},
+		{"Dm", "C", "ii", Diatonic, 2, 0},
+		{"G7", "C", "V7", Diatonic, 5, 0},
+		{"Cmaj7", "C", "Imaj7", Diatonic, 1, 0},
+		{"Bdim", "C", "vii°", Diatonic, 7, 0},
+		{"Bm7b5", "C", "viiø7", Diatonic, 7, 0},
+		{"Eb", "Bb", "IV", Diatonic, 4, 0},
+		// secondary dominants and leading-tone chords.
+		{"D", "C", "V/V", SecondaryDominant, 2, 0},
+		{"D7", "C", "V7/V", SecondaryDominant, 2, 0},
+		{"E7", "C", "V7/vi", SecondaryDominant, 3, 0},
+		{"C7", "C", "V7/IV", SecondaryDominant, 1, 0},
+		{"F#dim7", "C", "vii°7/V", SecondaryDominant, 4, 1},
+		{"B7", "G", "V7/vi", SecondaryDominant, 3, 0},
+		{"A", "Am", "V/iv", SecondaryDominant, 1, 0},
+		// borrowed from the parallel key.
+		{"Fm", "C", "iv", Borrowed, 4, 0},
+		{"Bb", "C", "bVII", Borrowed, 7, -1},
+		{"Ab", "C", "bVI", Borrowed, 6, -1},
+		{"Eb", "C", "bIII", Borrowed, 3, -1},
+		{"Bb", "D", "bVI", Borrowed, 6, -1},
+		// the harmonic minor's raised leading tone is diatonic and written without an accidental.
+		{"Am", "Am", "i", Diatonic, 1, 0},
+		{"C", "Am", "III", Diatonic, 3, 0},
+		{"G", "Am", "VII", Diatonic, 7, 0},
+		{"E7", "Am", "V7", Diatonic, 5, 0},
+		{"E", "Am", "V", Diatonic, 5, 0},
+		{"G#dim", "Am", "vii°", Diatonic, 7, 1},
+		{"G#dim7", "Am", "vii°7", Diatonic, 7, 1},
+		{"F#", "C", "#IV", Chromatic, 4, 1},
+		{"Db+", "C", "bII+", Chromatic, 2, -1},
+	}
+
+	for _, test := range tests {
+		c, _ := songtools.ParseChord(test.chord)
+		n, ok := AnalyzeChord(c, songtools.MustParseKey(test.key))
+		if !ok {
+			t.Errorf("AnalyzeChord(%q, %v) failed", test.chord, test.key)
+			continue
+		}
+
+		if n.String() != test.expected || n.Function != test.function || n.Degree != test.degree || n.Accidental != test.accidental {
+			t.Errorf("AnalyzeChord(%q, %v) = %v, %v on %d%+d, expected %v, %v on %d%+d",
+				test.chord, test.key, n, n.Function, n.Degree, n.Accidental, test.expected, test.function, test.degree, test.accidental)
+		}
+	}
+}
+
+func TestAnalyzeChordTargets(t *testing.T) {
+	tests := []struct {
+		chord  string
+		key    string
+		target string
+		degree int
+	}{
+		{"D7", "C", "V", 5},
+		{"E", "C", "vi", 6},
+		{"A7", "C", "ii", 2},
+		{"C#dim", "C", "ii", 2},
+		{"C7", "Am", "VI", 6},
+	}
+
+	for _, test := range tests {
+		c, _ := songtools.ParseChord(test.chord)
+		n, ok := AnalyzeChord(c, songtools.MustParseKey(test.key))
+		if !ok || n.Target == nil {
+			t.Errorf("AnalyzeChord(%q, %v) = %v, expected a secondary dominant", test.chord, test.key, n)
+			continue
+		}
+
+		if n.Target.String() != test.target || n.Target.Degree != test.degree {
+			t.Errorf("AnalyzeChord(%q, %v) targets %v on %d, expected %v on %d", test.chord, test.key, n.Target, n.Target.Degree, test.target, test.degree)
+		}
+	}
+
+	// a dominant of the tonic or of a diminished chord isn't a secondary dominant.
+	for _, chord := range []string{"G7", "F#7"} {
+		c, _ := songtools.ParseChord(chord)
+		if n, _ := AnalyzeChord(c, songtools.MustParseKey("C")); n.Function == SecondaryDominant {
+			t.Errorf("AnalyzeChord(%q, C) = %v, expected it not to be a secondary dominant", chord, n)
+		}
+	}
+}
+
+func TestAnalyzeChordFailures(t *testing.T) {
+	c, _ := songtools.ParseChord("C")
+	if _, ok := AnalyzeChord(c, songtools.Key{}); ok {
+		t.Errorf("expected no numeral without a key")
+	}
+	if _, ok := AnalyzeChord(songtools.UnknownChord("N.C."), songtools.MustParseKey("C")); ok {
+		t.Errorf("expected no numeral for an unknown chord")
+	}
+}
+
+func TestAnalyzeFollowsKeyChanges(t *testing.T) {
+	line := func(chords string) *songtools.Line {
+		l := &songtools.Line{}
+		for i, c := range parseChords(chords) {
+			l.Chords = append(l.Chords, c)
+			l.ChordPositions = append(l.ChordPositions, i)
+		}
+		return l
+	}
+
+	s := &songtools.Song{
+		Key: songtools.MustParseKey("G"),
+		Nodes: []songtools.SongNode{
+			&songtools.Section{Nodes: []songtools.SectionNode{line("G C D")}},
+			&songtools.KeyChange{Key: songtools.MustParseKey("A")},
+			&songtools.Section{Nodes: []songtools.SectionNode{
+				line("A D E"),
+				&songtools.KeyChange{Key: songtools.MustParseKey("Bm")},
+				line("Bm Em F#"),
+			}},
+		},
+	}
+
+	numerals, err := Analyze(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"I", "IV", "V", "I", "IV", "V", "i", "iv", "V"}
+	actual := []string{}
+	for _, n := range numerals {
+		actual = append(actual, n.String())
+	}
+	if strings.Join(actual, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, but got %v", expected, actual)
+	}
+}
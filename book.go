@@ -0,0 +1,80 @@
+package songtools
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Book is a songbook. Its songs are numbered from one in the order they appear, and it has a
+// title page, a table of contents and an index of the titles and first lines of its songs.
+type Book struct {
+	Title    string
+	Subtitle string
+	Songs    []*Song
+}
+
+// IndexEntry is an entry in the index of a book, either the title or the first line of a song.
+type IndexEntry struct {
+	Text string
+	// FirstLine indicates the entry is the first line of the song rather than its title.
+	FirstLine bool
+	// Song is the position of the song in the book. Its number is one more.
+	Song int
+}
+
+// SortByTitle puts the songs of the book in alphabetical order of their titles, which changes
+// their numbers.
+func (b *Book) SortByTitle() {
+	sort.SliceStable(b.Songs, func(i, j int) bool {
+		return indexKey(b.Songs[i].Title) < indexKey(b.Songs[j].Title)
+	})
+}
+
+// Index gets the titles and first lines of the songs in alphabetical order. A first line is
+// left out when it is the same as the song's title.
+func (b *Book) Index() []IndexEntry {
+	entries := []IndexEntry{}
+	for i, s := range b.Songs {
+		if title := strings.TrimSpace(s.Title); title != "" {
+			entries = append(entries, IndexEntry{Text: title, Song: i})
+		}
+
+		if first := s.FirstLine(); first != "" && indexKey(first) != indexKey(s.Title) {
+			entries = append(entries, IndexEntry{Text: first, FirstLine: true, Song: i})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return indexKey(entries[i].Text) < indexKey(entries[j].Text)
+	})
+
+	return entries
+}
+
+// indexKey is the text as it is alphabetized. Case is ignored, as is anything before the
+// first letter or digit, like quotes.
+func indexKey(text string) string {
+	return strings.ToLower(strings.TrimLeftFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+// FirstLine gets the first line of lyrics in the song, with its syllables joined into words and
+// without trailing punctuation.
+func (s *Song) FirstLine() string {
+	for _, n := range s.Nodes {
+		section, ok := n.(*Section)
+		if !ok {
+			continue
+		}
+
+		for _, sn := range section.Nodes {
+			if l, ok := sn.(*Line); ok && strings.TrimSpace(l.Text) != "" {
+				return strings.TrimRightFunc(joinSyllables(l), unicode.IsPunct)
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,50 @@
+package songtools
+
+import "testing"
+
+func TestSongFirstLine(t *testing.T) {
+	tests := []struct {
+		chords      string
+		text        string
+		chordsAbove bool
+		expected    string
+	}{
+		{"", "Amazing grace, how sweet the sound,", false, "Amazing grace, how sweet the sound"},
+		{"    C   D", "Hal-le-lu-jah, a well-known song", false, "Hallelujah, a well-known song"},
+		{"G   C    D", "A - ma - zing grace!", true, "Amazing grace"},
+		{"       G", "Lord - you are my strength.", false, "Lord - you are my strength"},
+	}
+
+	for _, test := range tests {
+		l := lyricLine(test.chords, test.text)
+		l.ChordsAbove = test.chordsAbove
+		s := &Song{Nodes: []SongNode{&Section{Nodes: []SectionNode{&Line{}, l}}}}
+		if actual := s.FirstLine(); actual != test.expected {
+			t.Errorf("FirstLine() of %q = %q, expected %q", test.text, actual, test.expected)
+		}
+	}
+}
+
+func TestBookIndex(t *testing.T) {
+	hallelujah := lyricLine("    C", "Hal-le-lu-jah, a well-known song")
+	b := &Book{Songs: []*Song{
+		{Title: "Hallelujah", Nodes: []SongNode{&Section{Nodes: []SectionNode{hallelujah}}}},
+		{Title: "“Amazing Grace", Nodes: []SongNode{&Section{Nodes: []SectionNode{&Line{Text: "Amazing grace"}}}}},
+	}}
+
+	expected := []IndexEntry{
+		{Text: "“Amazing Grace", Song: 1},
+		{Text: "Hallelujah", Song: 0},
+		{Text: "Hallelujah, a well-known song", FirstLine: true, Song: 0},
+	}
+
+	actual := b.Index()
+	if len(actual) != len(expected) {
+		t.Fatalf("Index() = %+v, expected %+v", actual, expected)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Index()[%d] = %+v, expected %+v", i, actual[i], expected[i])
+		}
+	}
+}
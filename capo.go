@@ -0,0 +1,186 @@
+package songtools
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxCapo is the highest fret SuggestCapo considers.
+const maxCapo = 7
+
+// CapoPosition is a fret to put the capo on in order to play a song in its sounding key.
+type CapoPosition struct {
+	Fret int
+	// Shape is the key of the chord shapes that are played with the capo on the fret.
+	Shape Key
+	// Barres is the number of different chords in the song that need a barre in the shape key.
+	Barres int
+	Score  float64
+}
+
+const (
+	openChordScore = 1.0
+	barreScore     = -2.0
+	openKeyScore   = 2.0
+	fretScore      = -0.25
+)
+
+// openKeys are the keys whose tonic chord is one of the favoured open shapes.
+var openKeys = map[string]bool{
+	"G": true, "C": true, "D": true, "A": true, "E": true,
+	"Em": true, "Am": true, "Dm": true,
+}
+
+// openShapes are the chords that are played in first position without a barre.
+var openShapes = map[string]bool{
+	"C": true, "D": true, "E": true, "G": true, "A": true,
+	"Am": true, "Dm": true, "Em": true,
+	"C7": true, "D7": true, "E7": true, "G7": true, "A7": true, "B7": true,
+	"Cmaj7": true, "Dmaj7": true, "Emaj7": true, "Fmaj7": true, "Gmaj7": true, "Amaj7": true,
+	"Am7": true, "Bm7": true, "Dm7": true, "Em7": true,
+	"Asus2": true, "Dsus2": true,
+	"Asus4": true, "Dsus4": true, "Esus4": true,
+	"A5": true, "D5": true, "E5": true, "G5": true,
+}
+
+// SuggestCapo scores each capo position, from no capo up to the 7th fret, for playing the song in
+// the sounding key, and returns them best first. The song's chords are taken to be the shapes for
+// its current capo, in the key in effect where they appear. Positions that put the song in a G, C,
+// D, A or E shape score highest, and each chord that needs a barre counts against a position, as
+// does every fret the capo is moved up.
+func SuggestCapo(s *Song, sounding Key) ([]*CapoPosition, error) {
+	if sounding.IsZero() {
+		return nil, fmt.Errorf("unable to suggest a capo without the sounding key")
+	}
+
+	positions := []*CapoPosition{}
+	for fret := 0; fret <= maxCapo; fret++ {
+		p := &CapoPosition{
+			Fret:  fret,
+			Shape: shapeKey(sounding, fret),
+		}
+
+		if openKeys[shapeName(p.Shape)] {
+			p.Score += openKeyScore
+		}
+
+		shapes := []*Chord{}
+		seen := map[string]bool{}
+		m := &chordMapper{key: sounding, chord: func(c *Chord, key Key) (*Chord, error) {
+			t, err := newTransposer(shapeKey(key, s.Capo), shapeKey(key, fret))
+			if err != nil {
+				return nil, err
+			}
+
+			shape, err := t.chord(c)
+			if err == nil && !shape.Unknown && !seen[shape.Name] {
+				shapes = append(shapes, shape)
+				seen[shape.Name] = true
+			}
+			return shape, err
+		}}
+		if _, err := m.song(s); err != nil {
+			return nil, err
+		}
+
+		for _, shape := range shapes {
+			if openShapes[chordShapeName(shape)] {
+				p.Score += openChordScore
+			} else {
+				p.Barres++
+				p.Score += barreScore
+			}
+		}
+
+		p.Score += fretScore * float64(fret)
+		positions = append(positions, p)
+	}
+
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].Score > positions[j].Score
+	})
+
+	return positions, nil
+}
+
+// CapoSong rewrites the chords of the song as the shapes played with a capo on the fret. The
+// song's key is the sounding key and stays the same. A fret of 0 removes the capo. The chord
+// definitions move with their chords as they do in TransposeSong.
+func CapoSong(s *Song, fret int) (*Song, error) {
+	if s.Key.IsZero() {
+		return nil, fmt.Errorf("unable to place a capo without knowing the song's key")
+	}
+	if fret < 0 {
+		return nil, fmt.Errorf("the capo must be on a fret, not %d", fret)
+	}
+
+	m := &chordMapper{key: s.Key, chord: func(c *Chord, key Key) (*Chord, error) {
+		t, err := newTransposer(shapeKey(key, s.Capo), shapeKey(key, fret))
+		if err != nil {
+			return nil, err
+		}
+
+		return t.chord(c)
+	}}
+
+	newSong, err := m.song(s)
+	if err != nil {
+		return nil, err
+	}
+
+	newSong.Capo = fret
+	return newSong, nil
+}
+
+// shapeKey is the key of the shapes played with a capo on the fret to sound in the key.
+func shapeKey(sounding Key, fret int) Key {
+	if fret == 0 {
+		return sounding
+	}
+
+	return sounding.Interval(-fret)
+}
+
+// shapeName names the key by its tonic chord, such as G or Em.
+func shapeName(k Key) string {
+	if k.IsMinor() {
+		return k.Tonic.String() + "m"
+	}
+
+	return k.Tonic.String()
+}
+
+// chordShapeName names the shape of the chord, leaving out anything that doesn't change
+// how it is fingered in first position, such as a bass note. Chords like diminished
+// chords that are never played open have no name.
+func chordShapeName(c *Chord) string {
+	if c.Unknown {
+		return ""
+	}
+
+	root := c.Root.String()
+	switch c.Quality {
+	case Major:
+		switch {
+		case c.Extension >= 7 && c.MajorSeventh:
+			return root + "maj7"
+		case c.Extension >= 7:
+			return root + "7"
+		default:
+			return root
+		}
+	case Minor:
+		if c.Extension >= 7 && !c.MajorSeventh {
+			return root + "m7"
+		}
+		return root + "m"
+	case Suspended2:
+		return root + "sus2"
+	case Suspended4:
+		return root + "sus4"
+	case Power:
+		return root + "5"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,110 @@
+package songtools
+
+import "testing"
+
+// chordSong builds a song in the key with a single line of the chords.
+func chordSong(key string, capo int, chords string) *Song {
+	return &Song{
+		Key:   MustParseKey(key),
+		Capo:  capo,
+		Nodes: []SongNode{&Section{Nodes: []SectionNode{chordLine(chords)}}},
+	}
+}
+
+func TestSuggestCapo(t *testing.T) {
+	tests := []struct {
+		key    string
+		capo   int
+		chords string
+		fret   int
+		shape  string
+		barres int
+		score  float64
+	}{
+		// G, C, D and Em shapes are all open.
+		{"Bb", 0, "Bb Eb F Gm", 3, "G", 0, 2 + 4 - 0.75},
+		{"G", 0, "G C D Em", 0, "G", 0, 2 + 4},
+		// the chords are shapes for the capo already on the song.
+		{"Bb", 3, "G C D Em", 3, "G", 0, 2 + 4 - 0.75},
+		{"Fm", 0, "Fm Bbm C7", 1, "Em", 0, 2 + 3 - 0.25},
+	}
+
+	for _, test := range tests {
+		positions, err := SuggestCapo(chordSong(test.key, test.capo, test.chords), MustParseKey(test.key))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.chords, err)
+			continue
+		}
+		if len(positions) != maxCapo+1 {
+			t.Errorf("%v: expected %d positions, but got %d", test.chords, maxCapo+1, len(positions))
+			continue
+		}
+
+		best := positions[0]
+		if best.Fret != test.fret || best.Shape.String() != test.shape || best.Barres != test.barres || best.Score != test.score {
+			t.Errorf("%v in %v: best position = fret %d in %v with %d barres scoring %v, expected fret %d in %v with %d barres scoring %v",
+				test.chords, test.key, best.Fret, best.Shape, best.Barres, best.Score, test.fret, test.shape, test.barres, test.score)
+		}
+	}
+}
+
+func TestSuggestCapoScoresEveryFret(t *testing.T) {
+	positions, err := SuggestCapo(chordSong("Bb", 0, "Bb Eb F Gm"), MustParseKey("Bb"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	scores := map[int]float64{}
+	for _, p := range positions {
+		scores[p.Fret] = p.Score
+	}
+
+	// without a capo every chord needs a barre, and at the 7th fret the Eb shapes do too.
+	expected := map[int]float64{0: -8, 3: 5.25, 7: -8 - 1.75}
+	for fret, score := range expected {
+		if scores[fret] != score {
+			t.Errorf("the capo on fret %d scored %v, expected %v", fret, scores[fret], score)
+		}
+	}
+
+	if _, err := SuggestCapo(chordSong("Bb", 0, "Bb"), Key{}); err == nil {
+		t.Errorf("expected an error without the sounding key")
+	}
+}
+
+func TestCapoSong(t *testing.T) {
+	tests := []struct {
+		key      string
+		capo     int
+		chords   string
+		fret     int
+		expected string
+	}{
+		{"Bb", 0, "Bb Eb F Gm", 3, "G C D Em"},
+		// a fret of 0 takes the capo off, so the shapes are the sounding chords.
+		{"Bb", 3, "G C D Em", 0, "Bb Eb F Gm"},
+		{"A", 2, "G C D", 7, "D G A"},
+		{"A", 0, "A D E", 0, "A D E"},
+	}
+
+	for _, test := range tests {
+		s, err := CapoSong(chordSong(test.key, test.capo, test.chords), test.fret)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.chords, err)
+			continue
+		}
+
+		l := s.Nodes[0].(*Section).Nodes[0].(*Line)
+		if actual := chordNames(l); actual != test.expected || s.Capo != test.fret || s.Key.String() != test.key {
+			t.Errorf("capo %d on %v in %v = %q with a capo on %d in %v, expected %q with a capo on %d in %v",
+				test.fret, test.chords, test.key, actual, s.Capo, s.Key, test.expected, test.fret, test.key)
+		}
+	}
+
+	if _, err := CapoSong(chordSong("A", 0, "A"), -1); err == nil {
+		t.Errorf("expected an error for a negative fret")
+	}
+	if _, err := CapoSong(&Song{}, 2); err == nil {
+		t.Errorf("expected an error for a song without a key")
+	}
+}
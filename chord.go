@@ -11,14 +11,39 @@ type Chord struct {
 	Root   Note
 	Base   Note
 	Suffix string
+
+	// Quality, Extension, Alterations, Added and Omitted are the structure of the Suffix.
+	Quality      Quality
+	Extension    int
+	MajorSeventh bool
+	Alterations  []Alteration
+	Added        []int
+	Omitted      []int
+
+	// Unknown indicates the name could not be understood as a chord. Only
+	// the Name of an unknown chord is meaningful.
+	Unknown bool
+}
+
+// UnknownChord creates a chord that keeps a name that could not be parsed.
+func UnknownChord(name string) *Chord {
+	return &Chord{
+		Name:    name,
+		Unknown: true,
+	}
 }
 
 func (c *Chord) String() string {
 	return c.Name
 }
 
-// Interval returns a new chord at the specified interval.
+// Interval returns a new chord at the specified interval. Unknown
+// chords are returned unchanged.
 func (c *Chord) Interval(interval int, names *NoteNames) *Chord {
+	if c.Unknown {
+		return UnknownChord(c.Name)
+	}
+
 	newRoot := c.Root.Interval(interval)
 	newBase := c.Base.Interval(interval)
 	newName := newRoot.StringFromNames(names) + c.Suffix
@@ -27,21 +52,19 @@ func (c *Chord) Interval(interval int, names *NoteNames) *Chord {
 	}
 
 	return &Chord{
-		Name:   newName,
-		Root:   newRoot,
-		Base:   newBase,
-		Suffix: c.Suffix,
+		Name:         newName,
+		Root:         newRoot,
+		Base:         newBase,
+		Suffix:       c.Suffix,
+		Quality:      c.Quality,
+		Extension:    c.Extension,
+		MajorSeventh: c.MajorSeventh,
+		Alterations:  c.Alterations,
+		Added:        c.Added,
+		Omitted:      c.Omitted,
 	}
 }
 
-// Key is...
-type Key string
-
-var (
-	sharpKeys = []Key{"A", "F#m", "B", "G#m", "C", "Am", "C#", "A#m", "D", "Bm", "E", "C#m", "F#", "D#m", "G", "Em"}
-	flatKeys  = []Key{"Ab", "Fm", "Bb", "Gm", "Cb", "Abm", "Db", "Bbm", "Eb", "Cm", "F", "Dm", "Gb", "Ebm"}
-)
-
 // Note is a single note on a scale.
 type Note int
 
@@ -58,38 +81,31 @@ var (
 )
 
 // NoteNamesAndIntervalFromKeyToKey returns the NoteNames and the interval in order to transposed
-// the original key to the transposed key.
+// the original key to the transposed key. Transposing doesn't change the mode, so the transposed
+// key must have the same mode as the original key, such as Am to Cm.
 func NoteNamesAndIntervalFromKeyToKey(original, transposed Key) (*NoteNames, int, error) {
-	oChord, ok := ParseChord(string(original))
-	if !ok {
-		return nil, 0, fmt.Errorf("not a key: %v", original)
-	}
-	tChord, ok := ParseChord(string(transposed))
-	if !ok {
-		return nil, 0, fmt.Errorf("not a key: %v", original)
+	if original.IsZero() {
+		return nil, 0, fmt.Errorf("the original key is unknown")
 	}
 
 	names, err := NoteNamesFromKey(transposed)
 	if err != nil {
 		return nil, 0, err
 	}
+	if transposed.Mode != original.Mode {
+		return nil, 0, fmt.Errorf("the target key %v is %v, but the original key %v is %v, and transposing doesn't change the mode", transposed, transposed.Mode, original, original.Mode)
+	}
 
-	return names, int(tChord.Root) - int(oChord.Root), nil
+	return names, int(transposed.Tonic.Note()) - int(original.Tonic.Note()), nil
 }
 
 // NoteNamesFromKey gets the correct NoteNames for the given key.
 func NoteNamesFromKey(key Key) (*NoteNames, error) {
-	for i := 0; i < len(sharpKeys); i++ {
-		if sharpKeys[i] == key {
-			return sharpNoteNames, nil
-		}
-
-		if i < len(flatKeys) && flatKeys[i] == key {
-			return flatNoteNames, nil
-		}
+	if key.IsZero() {
+		return nil, fmt.Errorf("the key is unknown")
 	}
 
-	return nil, fmt.Errorf("invalid key name: %v", key)
+	return key.NoteNames(), nil
 }
 
 func (n Note) String() string {
@@ -103,14 +119,9 @@ func (n Note) StringFromNames(names *NoteNames) string {
 
 // Interval returns a new note at the specified interval.
 func (n Note) Interval(interval int) Note {
-	if interval < noteCount {
-		interval += noteCount
-	}
-	return Note((int(n) + interval) % noteCount)
+	return Note(((int(n)+interval)%noteCount + noteCount) % noteCount)
 }
 
-const validSuffixChars = "masd+M-245679("
-
 // ParseChord parses some text and returns a chord.
 func ParseChord(text string) (*Chord, bool) {
 	if text == "" {
@@ -120,65 +131,68 @@ func ParseChord(text string) (*Chord, bool) {
 	name := text
 
 	// root note
-	rootNote, ok := parseNote(text)
+	rootNote, n, ok := parseNotePrefix(text)
 	if !ok {
 		return nil, false
 	}
 
 	baseNote := rootNote
 	// base note
-	idx := strings.Index(text, "/")
-	if idx != -1 {
-		baseText := text[idx+1:]
-		baseNote, ok = parseNote(baseText)
-		if !ok {
-			return nil, false
+	idx := strings.LastIndex(text, "/")
+	if idx >= n {
+		if b, bn, ok := parseNotePrefix(text[idx+1:]); ok && bn == len(text)-idx-1 {
+			baseNote = b
+			text = text[:idx]
 		}
-
-		text = text[:idx-1]
 	}
 
 	// suffix
-	suffix := ""
-	offset := len(rootNote.String())
-	if len(text) > offset {
-		suffix = text[offset:]
-		found := false
-		for _, r := range validSuffixChars {
-			if r == rune(suffix[0]) {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			return nil, false
-		}
-	}
-
-	return &Chord{
+	c := &Chord{
 		Name:   name,
 		Root:   rootNote,
 		Base:   baseNote,
-		Suffix: suffix,
-	}, true
+		Suffix: text[n:],
+	}
+
+	if !parseSuffix(c.Suffix, c) {
+		return nil, false
+	}
+
+	return c, true
 }
 
-func parseNote(text string) (Note, bool) {
+var letterNotes = map[byte]Note{'A': 0, 'B': 2, 'C': 3, 'D': 5, 'E': 7, 'F': 8, 'G': 10}
+
+// parseNotePrefix parses the note at the start of the text, returning the note and the number
+// of bytes it used. A note is a letter followed by at most two sharps or flats.
+func parseNotePrefix(text string) (Note, int, bool) {
 	if text == "" {
-		return -1, false
+		return -1, 0, false
 	}
 
-	// this is pretty slow... We can do better.
-	for len(text) > 0 {
-		for i := 0; i < noteCount; i++ {
-			if text == sharpNoteNames[i] || text == flatNoteNames[i] {
-				return Note(i), true
-			}
-		}
+	note, ok := letterNotes[text[0]]
+	if !ok {
+		return -1, 0, false
+	}
 
-		text = text[0 : len(text)-1]
+	n := 1
+	accidental := 0
+	for i := 0; i < 2; i++ {
+		switch {
+		case strings.HasPrefix(text[n:], "#"):
+			accidental++
+			n++
+		case strings.HasPrefix(text[n:], "♯"):
+			accidental++
+			n += len("♯")
+		case strings.HasPrefix(text[n:], "b"):
+			accidental--
+			n++
+		case strings.HasPrefix(text[n:], "♭"):
+			accidental--
+			n += len("♭")
+		}
 	}
 
-	return -1, false
+	return note.Interval(accidental), n, true
 }
@@ -0,0 +1,71 @@
+package songtools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		name    string
+		ok      bool
+		quality Quality
+		// notes are the notes of the chord spelled in C, starting from its bass.
+		notes string
+	}{
+		{"C", true, Major, "C E G"},
+		{"Am", true, Minor, "A C E"},
+		{"G7", true, Major, "G B D F"},
+		{"Bb", true, Major, "Bb D F"},
+		{"G♯m", true, Minor, "G# B D#"},
+		{"G/B", true, Major, "B G D"},
+		{"Dm7/C", true, Minor, "C D F A"},
+		{"Bbmaj7/D", true, Major, "D Bb F A"},
+		{"Cmaj7#11", true, Major, "C E G B F#"},
+		{"E7b9", true, Major, "E G# B D F"},
+		{"F#m7b5", true, Minor, "F# A C E"},
+		{"Gm(maj7)", true, Minor, "G Bb D F#"},
+		{"C6/9", true, Major, "C E G A D"},
+		{"Cadd9", true, Major, "C E G D"},
+		{"Cmadd9", true, Minor, "C Eb G D"},
+		{"Cma7", true, Major, "C E G B"},
+		{"CMa7", true, Major, "C E G B"},
+		{"Cma9", true, Major, "C E G B D"},
+		{"Cm(ma7)", true, Minor, "C Eb G B"},
+		{"Comit3", true, Major, "C G"},
+		{"C7omit5", true, Major, "C E Bb"},
+		{"C9no3", true, Major, "C G Bb D"},
+		{"Csus", true, Suspended4, "C F G"},
+		{"Dsus4add9", true, Suspended4, "D G A E"},
+		{"C5", true, Power, "C G"},
+		{"C+", true, Augmented, "C E G#"},
+		{"C°", true, Diminished, "C Eb Gb"},
+		{"Cdim7", true, Diminished, "C Eb Gb Bbb"},
+		{"Co7", true, Diminished, "C Eb Gb Bbb"},
+		{"Cø", true, HalfDiminished, "C Eb Gb Bb"},
+		{"Amazing", false, Major, ""},
+		{"The", false, Major, ""},
+		{"Be", false, Major, ""},
+		{"Hx", false, Major, ""},
+		{"", false, Major, ""},
+	}
+
+	for _, test := range tests {
+		c, ok := ParseChord(test.name)
+		if ok != test.ok {
+			t.Errorf("ParseChord(%q) parsed: %v, expected %v", test.name, ok, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		names := []string{}
+		for _, s := range c.Spell(MustParseKey("C")) {
+			names = append(names, s.String())
+		}
+		if c.Quality != test.quality || strings.Join(names, " ") != test.notes {
+			t.Errorf("ParseChord(%q) = %v %q, expected %v %q", test.name, c.Quality, strings.Join(names, " "), test.quality, test.notes)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
+)
+
+// bookFormat is the format a book is written in when none is specified.
+const bookFormat = "html"
+
+// bookCommand compiles the songs in many files into a songbook.
+type bookCommand struct {
+	Title    string `long:"title" default:"Songbook" description:"The title of the book, which is written on its title page."`
+	Subtitle string `long:"subtitle" description:"The subtitle of the book, such as the year or the event it is for."`
+	Sort     bool   `long:"sort" description:"Number the songs in alphabetical order of their titles rather than in the order they were given."`
+}
+
+// execute reads the songs in each of the files and writes them as one book.
+func (b *bookCommand) execute(cmd *options, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no song files were given for the book")
+	}
+
+	name := cmd.ToFormat
+	if name == "" {
+		name = bookFormat
+	}
+	writeFormat, ok := format.ByName(name)
+	if !ok {
+		return fmt.Errorf("unable to find output format %q", name)
+	}
+	if !writeFormat.CanWriteBook() {
+		return fmt.Errorf("the format %q is unable to be used for writing a book", writeFormat.Name)
+	}
+
+	writeFormat, err := writeFormat.WithOptions(format.Options(cmd.Options))
+	if err != nil {
+		return fmt.Errorf("unable to use the options for %q: %v", name, err)
+	}
+
+	book := &songtools.Book{
+		Title:    b.Title,
+		Subtitle: b.Subtitle,
+	}
+	for _, file := range args {
+		input, err := readInput(file)
+		if err != nil {
+			return err
+		}
+
+		readFormat, err := findReadFormat(cmd.CurrentFormat, file, input)
+		if err != nil {
+			return fmt.Errorf("unable to find input format for %q: %v", file, err)
+		}
+
+		set, err := cmd.readSongs(readFormat, file, input)
+		if err != nil {
+			return err
+		}
+
+		if err = cmd.change(set); err != nil {
+			return err
+		}
+
+		book.Songs = append(book.Songs, set.Songs...)
+	}
+
+	if b.Sort {
+		book.SortByTitle()
+	}
+
+	out, err := cmd.openOut(book.Title, "", writeFormat)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return writeFormat.WriteBook(out, book)
+}
@@ -3,27 +3,46 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/analysis"
+	"github.com/songtools/songtools/fingering"
 	"github.com/songtools/songtools/format"
 	_ "github.com/songtools/songtools/format/chordpro"         // formats are registered in the init functions.
 	_ "github.com/songtools/songtools/format/chordsOverLyrics" // formats are registered in the init functions.
 	_ "github.com/songtools/songtools/format/html"             // formats are registered in the init functions.
+	_ "github.com/songtools/songtools/format/lyrics"           // formats are registered in the init functions.
+	_ "github.com/songtools/songtools/format/nashville"        // formats are registered in the init functions.
+	_ "github.com/songtools/songtools/format/pdf"              // formats are registered in the init functions.
 )
 
 var cli = flags.NewNamedParser("songtool", flags.Default)
 
+// sampleSize is the number of bytes inspected when detecting the format of a song.
+const sampleSize = 4096
+
+const (
+	nashvilleFormat = "nashville"
+	// letterFormat is the format a number chart is written in with letter chords.
+	letterFormat = "chordsOverLyrics"
+)
+
 type options struct {
-	CurrentFormat string `long:"currentFormat" description:"Specifies the format of the song. By default, an attempt will be made to discover it automatically."`
-	ToFormat      string `short:"f" long:"format" description:"The desired format of the song. When left unspecified, the 'currentFormat' will be used."`
-	CurrentKey    string `long:"currentKey" description:"The current key of the song. By default, an attempt will be made to discover it automatically."`
-	ToKey         string `short:"k" long:"key" description:"The desired key of the song. When left unspecified, no transposition will occur."`
-	Out           string `short:"o" long:"out" optional:"true" optional-value:"<unset>" description:"The file to write the transposed song. If left unspecified, stdout will be used. When specified without an argument, the song title will be used as the file name with the desired format's extension."`
+	CurrentFormat string            `long:"currentFormat" description:"Specifies the format of the song. By default, an attempt will be made to discover it automatically."`
+	ToFormat      string            `short:"f" long:"format" description:"The desired format of the song. When left unspecified, the 'currentFormat' will be used, except that a nashville chart given a 'key' is written with its chords in the chordsOverLyrics format."`
+	Lenient       bool              `long:"lenient" description:"Continue reading past problems in the song and report all of them rather than stopping at the first one."`
+	CurrentKey    string            `long:"currentKey" description:"The current key of the song. By default, an attempt will be made to discover it automatically."`
+	ToKey         string            `short:"k" long:"key" description:"The desired key of the song, such as G, F#m or D dorian. A key without a mode, such as G, is in the mode of the song, and a key with one must have the song's mode. When left unspecified, no transposition will occur."`
+	Capo          string            `long:"capo" description:"Rewrite the chords as the shapes played with a capo on the given fret, keeping the sounding key. Use 'auto' to pick the fret with the most open chords, or 0 to remove the capo."`
+	Options       map[string]string `short:"O" long:"option" key-value-delimiter:"=" description:"An option for the desired format, given as name=value. For instance, 'numerals=true' annotates the chords with Roman numerals in the html and chordsOverLyrics formats and 'diagrams=true' adds a chord diagram for each chord to the html format, for the instrument given by 'tuning'. The html format also takes a 'theme', such as print, dark, large-print or projector, along with 'css' and 'template' files, and 'print=true' lays songs out for printing, with 'columns', 'fit' and 'paper'. The pdf format takes 'font', either proportional, monospace or a TrueType file to embed, with 'bold' and 'italic' files to go with it, along with 'diagrams', 'tuning' and 'paper'. 'lyrics=true' leaves out the chords in the chordpro, chordsOverLyrics, html and pdf formats, and the lyrics format takes 'headings' and 'fold' to write section headings and fold repeated sections."`
+	Out           string            `short:"o" long:"out" optional:"true" optional-value:"<unset>" description:"The file to write the transposed song. If left unspecified, stdout will be used. When specified without an argument, the song title will be used as the file name with the desired format's extension."`
 }
 
 func main() {
@@ -40,12 +59,22 @@ func main() {
 		return f.CanWrite()
 	})
 
+	var book bookCommand
+	cli.AddCommand("book", "Compile songs into a songbook", "Compiles the songs in the files into a single songbook, with a title page, a table of contents and an index of the titles and first lines of the songs. The book is written in the html format unless the pdf format is given, and the other global options apply to every song.", &book)
+	cli.SubcommandsOptional = true
+
 	args, err := cli.Parse()
 	if err != nil {
 		os.Exit(1)
 	}
 
-	if err = opt.execute(args); err != nil {
+	if cli.Active != nil && cli.Active.Name == "book" {
+		err = book.execute(&opt, args)
+	} else {
+		err = opt.execute(args)
+	}
+
+	if err != nil {
 		println(err.Error())
 		os.Exit(2)
 	}
@@ -57,24 +86,16 @@ func (cmd *options) execute(args []string) error {
 		return fmt.Errorf("too many positional arguments")
 	}
 
-	var err error
-	in := os.Stdin
 	file := ""
 	if len(args) == 1 {
 		file = args[0]
-		in, err = os.Open(file)
-		if err != nil {
-			return fmt.Errorf("unable to open %q: %v", file, err)
-		}
 	}
 
-	inBytes, err := ioutil.ReadAll(in)
+	input, err := readInput(file)
 	if err != nil {
-		return fmt.Errorf("unable to read: %v", err)
+		return err
 	}
 
-	input := bytes.NewBuffer(inBytes)
-
 	readFormat, err := findReadFormat(cmd.CurrentFormat, file, input)
 	if err != nil {
 		return fmt.Errorf("unable to find input format for %q: %v", file, err)
@@ -86,71 +107,256 @@ func (cmd *options) execute(args []string) error {
 		if writeFormat, ok = format.ByName(cmd.ToFormat); !ok {
 			return fmt.Errorf("unable to find output format %q", cmd.ToFormat)
 		}
+	} else if cmd.ToKey != "" && readFormat.Name == nashvilleFormat {
+		// the numbers of a chart are the same in every key, so a chart moved to a key is
+		// written with the letter chords of that key.
+		writeFormat, _ = format.ByName(letterFormat)
 	}
 
 	if writeFormat.Writer == nil {
 		return fmt.Errorf("the input format %q is unable to be used for writing", readFormat.Name)
 	}
 
-	song, err := readFormat.Reader.Read(input)
+	configuredFormat, err := writeFormat.WithOptions(format.Options(cmd.Options))
 	if err != nil {
-		return fmt.Errorf("unable to parse %q: %v", file, err)
+		return fmt.Errorf("unable to use the options for %q: %v", writeFormat.Name, err)
 	}
+	writeFormat = configuredFormat
 
-	setSongTitleIfNecessary(file, song)
+	set, err := cmd.readSongs(readFormat, file, input)
+	if err != nil {
+		return err
+	}
 
-	if cmd.ToKey != "" {
-		fromKey := songtools.Key(cmd.CurrentKey)
+	if err = cmd.change(set); err != nil {
+		return err
+	}
 
-		if fromKey == "" && song.Key == "" {
-			return fmt.Errorf("unable to get current key")
-		} else if fromKey == "" {
-			fromKey = song.Key
-		}
+	out, err := cmd.openOut(set.Songs[0].Title, file, writeFormat)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
 
-		toKey := songtools.Key(cmd.ToKey)
+	return writeFormat.WriteSet(out, set)
+}
 
-		noteNames, interval, err := songtools.NoteNamesAndIntervalFromKeyToKey(fromKey, toKey)
+// readInput reads all of the file, or of stdin when there is no file.
+func readInput(file string) (*bytes.Buffer, error) {
+	in := os.Stdin
+	if file != "" {
+		var err error
+		in, err = os.Open(file)
 		if err != nil {
-			return fmt.Errorf("unable to get note names and interval: %v", err)
+			return nil, fmt.Errorf("unable to open %q: %v", file, err)
 		}
+		defer in.Close()
+	}
 
-		song, err = songtools.TransposeSong(song, interval, noteNames)
-		if err != nil {
-			return fmt.Errorf("unable to transpose from %q to %q: %v", fromKey, toKey, err)
+	inBytes, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read: %v", err)
+	}
+
+	return bytes.NewBuffer(inBytes), nil
+}
+
+// readSongs parses the songs in the input. A lone song without a title is named for its file.
+func (cmd *options) readSongs(f *format.Format, file string, input io.Reader) (*songtools.SongSet, error) {
+	set, err := cmd.read(f, file, input)
+	if err != nil {
+		if perr, ok := err.(*format.ParseError); ok {
+			perr.File = file
+			return nil, perr
 		}
+		return nil, fmt.Errorf("unable to parse %q: %v", file, err)
+	}
 
-		song.Key = toKey
+	if len(set.Songs) == 0 {
+		return nil, fmt.Errorf("no songs were found in %q", file)
 	}
 
-	out := os.Stdout
-	defer out.Close()
+	if len(set.Songs) == 1 {
+		setSongTitleIfNecessary(file, set.Songs[0])
+	}
+
+	return set, nil
+}
+
+// change transposes the songs and moves their capos when the options ask for it.
+func (cmd *options) change(set *songtools.SongSet) error {
+	var err error
+	if cmd.ToKey != "" {
+		for i, song := range set.Songs {
+			set.Songs[i], err = cmd.transpose(song)
+			if err != nil {
+				return err
+			}
+			refinger(song, set.Songs[i])
+		}
+	}
+
+	if cmd.Capo != "" {
+		for i, song := range set.Songs {
+			set.Songs[i], err = cmd.capo(song)
+			if err != nil {
+				return err
+			}
+			refinger(song, set.Songs[i])
+		}
+	}
+
+	return nil
+}
+
+// refinger gives a new fingering to each chord definition whose fingering couldn't be moved
+// with its chord, since it uses open strings. The new fingering is the best one for the chord
+// on the instrument the old one was for. When there isn't one, the definition is left with
+// just the chord's name, and a warning says so.
+func refinger(before, after *songtools.Song) {
+	for i, d := range after.Definitions {
+		old := before.Definitions[i]
+		if len(d.Frets) > 0 || len(old.Frets) == 0 {
+			continue
+		}
+
+		c, ok := songtools.ParseChord(d.Name)
+		t, tuned := fingering.TuningOf(old)
+		if ok && tuned {
+			if best, ok := fingering.Best(c, t, nil); ok {
+				best.Display = d.Display
+				after.Definitions[i] = best
+				continue
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "warning: the fingering of %v couldn't be moved to %v, so %v is defined without one\n", old.Name, d.Name, d.Name)
+	}
+}
 
-	if cmd.Out == "<unset>" {
-		name := song.Title
+// openOut opens the file to write to, or stdout when 'out' wasn't specified. When it was
+// specified without a name, the file is named for the title, or else the input file, with the
+// format's extension.
+func (cmd *options) openOut(title, file string, f *format.Format) (*os.File, error) {
+	name := cmd.Out
+	if name == "<unset>" {
+		name = title
 		if name == "" && file == "" {
-			return fmt.Errorf("'out' was specified, but the song does not have a title and the input was not a file")
+			return nil, fmt.Errorf("'out' was specified, but the song does not have a title and the input was not a file")
 		} else if name == "" {
 			_, file := filepath.Split(file)
 			ext := filepath.Ext(file)
 			name = strings.TrimSuffix(file, ext)
 		}
 
-		if len(writeFormat.Extensions) > 0 {
-			name += writeFormat.Extensions[0]
+		if len(f.Extensions) > 0 {
+			name += f.Extensions[0]
 		}
+	}
+
+	if name == "" {
+		return os.Stdout, nil
+	}
+
+	out, err := os.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %q: %v", name, err)
+	}
+
+	return out, nil
+}
+
+func (cmd *options) read(f *format.Format, file string, input io.Reader) (*songtools.SongSet, error) {
+	if !cmd.Lenient {
+		return f.ReadSet(input)
+	}
+
+	set, diagnostics, err := f.ReadSetLenient(input)
+	diagnostics.SetFile(file)
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d)
+	}
+
+	return set, err
+}
+
+func (cmd *options) transpose(song *songtools.Song) (*songtools.Song, error) {
+	fromKey, err := cmd.currentKey(song)
+	if err != nil {
+		return nil, err
+	}
+
+	toKey, err := songtools.ParseKey(cmd.ToKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %v", err)
+	}
+	// a key given without a mode, such as C, is in the mode of the song.
+	if _, ok := songtools.ParseSpelling(strings.TrimSpace(cmd.ToKey)); ok {
+		toKey.Mode = fromKey.Mode
+	}
 
-		cmd.Out = name
+	song, err = songtools.TransposeSong(song, fromKey, toKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to transpose from %q to %q: %v", fromKey, toKey, err)
 	}
 
-	if cmd.Out != "" {
-		out, err = os.OpenFile(cmd.Out, os.O_CREATE, 0666)
+	return song, nil
+}
+
+func (cmd *options) capo(song *songtools.Song) (*songtools.Song, error) {
+	// a transposed song is in the key it was transposed to, rather than the current key.
+	key := song.Key
+	var err error
+	if cmd.ToKey == "" || key.IsZero() {
+		key, err = cmd.currentKey(song)
 		if err != nil {
-			return fmt.Errorf("unable to open %q: %v", cmd.Out, err)
+			return nil, err
 		}
 	}
+	// the capo is placed on a copy of the song in its key, so the song itself is unchanged.
+	keyed := *song
+	keyed.Key = key
+	song = &keyed
+
+	fret := 0
+	if cmd.Capo == "auto" {
+		positions, err := songtools.SuggestCapo(song, key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to suggest a capo: %v", err)
+		}
+		fret = positions[0].Fret
+	} else {
+		fret, err = strconv.Atoi(cmd.Capo)
+		if err != nil || fret < 0 {
+			return nil, fmt.Errorf("invalid capo %q: expected a fret number or 'auto'", cmd.Capo)
+		}
+	}
+
+	return songtools.CapoSong(song, fret)
+}
+
+// currentKey is the sounding key of the song, either as given, from the song itself, or
+// detected from its chords.
+func (cmd *options) currentKey(song *songtools.Song) (songtools.Key, error) {
+	if cmd.CurrentKey != "" {
+		key, err := songtools.ParseKey(cmd.CurrentKey)
+		if err != nil {
+			return songtools.Key{}, fmt.Errorf("invalid current key: %v", err)
+		}
+		return key, nil
+	}
+
+	if !song.Key.IsZero() {
+		return song.Key, nil
+	}
 
-	return writeFormat.Writer.Write(out, song)
+	candidates := analysis.DetectKey(song)
+	if len(candidates) == 0 {
+		return songtools.Key{}, fmt.Errorf("unable to get current key")
+	}
+
+	// with a capo, the chords are shapes that sound higher than they are written.
+	return candidates[0].Key.Interval(song.Capo), nil
 }
 
 func findReadFormat(name, path string, buffer *bytes.Buffer) (*format.Format, error) {
@@ -170,7 +376,7 @@ func findFormat(name, path string, buffer *bytes.Buffer, filter func(*format.For
 
 		formats = append(formats, f)
 	} else if path != "" {
-		ext := filepath.Ext(path)
+		ext := strings.ToLower(filepath.Ext(path))
 		if ext != "" {
 			formats = format.RegisteredFormats()
 			formats = formats.Filter(func(f *format.Format) bool {
@@ -187,10 +393,38 @@ func findFormat(name, path string, buffer *bytes.Buffer, filter func(*format.For
 
 	formats = formats.Filter(filter)
 
+	if len(formats) == 1 {
+		return formats[0], nil
+	}
+
+	if name != "" {
+		return nil, fmt.Errorf("format %q cannot be used", name)
+	}
+
+	// either there was no extension or the extension is shared by multiple formats,
+	// so we'll look at the content to figure out which one it is.
+	candidates := formats
+	if len(candidates) == 0 {
+		candidates = format.RegisteredFormats().Filter(filter)
+	}
+
+	sample := buffer.Bytes()
+	if len(sample) > sampleSize {
+		sample = sample[:sampleSize]
+	}
+
+	if f, ok := candidates.Detect(sample); ok {
+		return f, nil
+	}
+
 	if len(formats) > 0 {
 		return formats[0], nil
 	}
 
+	if f, ok := format.ByName(format.Default); ok && filter(f) {
+		return f, nil
+	}
+
 	return nil, fmt.Errorf("unable to find format")
 }
 
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
+)
+
+func TestOpenOut(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songtool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing.cho")
+	if err := ioutil.WriteFile(existing, []byte("{title: A much longer song than the one replacing it}\n"), 0666); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+	}{
+		{filepath.Join(dir, "new.cho")},
+		{existing},
+	}
+
+	f := &format.Format{Name: "chordpro", Extensions: []string{".cho"}}
+	for _, test := range tests {
+		cmd := &options{Out: test.name}
+		out, err := cmd.openOut("", "", f)
+		if err != nil {
+			t.Fatalf("openOut(%q) unexpected error: %v", test.name, err)
+		}
+		if _, err := out.WriteString("{title: Short}\n"); err != nil {
+			t.Errorf("openOut(%q) can't be written: %v", test.name, err)
+		}
+		out.Close()
+
+		written, _ := ioutil.ReadFile(test.name)
+		if string(written) != "{title: Short}\n" {
+			t.Errorf("openOut(%q) wrote %q, expected %q", test.name, written, "{title: Short}\n")
+		}
+	}
+}
+
+func TestTransposeKeepsTheSongsMode(t *testing.T) {
+	tests := []struct {
+		key      string
+		toKey    string
+		expected string
+		ok       bool
+	}{
+		{"Am", "C", "Cm", true},
+		{"Am", "Cm", "Cm", true},
+		{"G", "A", "A", true},
+		{"G", "F#m", "", false},
+		{"D dorian", "E", "E dorian", true},
+	}
+
+	for _, test := range tests {
+		cmd := &options{ToKey: test.toKey}
+		song, err := cmd.transpose(&songtools.Song{Key: songtools.MustParseKey(test.key)})
+		if (err == nil) != test.ok {
+			t.Errorf("-k %v on a song in %v: expected an error: %v, but was %v", test.toKey, test.key, !test.ok, err)
+			continue
+		}
+		if err == nil && song.Key.String() != test.expected {
+			t.Errorf("-k %v on a song in %v: expected the key %q, but got %q", test.toKey, test.key, test.expected, song.Key)
+		}
+	}
+}
+
+func TestCapoLeavesTheSongUnchanged(t *testing.T) {
+	g, _ := songtools.ParseChord("G")
+	song := &songtools.Song{Nodes: []songtools.SongNode{&songtools.Section{Nodes: []songtools.SectionNode{
+		&songtools.Line{Text: "la", Chords: []*songtools.Chord{g}, ChordPositions: []int{0}},
+	}}}}
+
+	cmd := &options{CurrentKey: "A", Capo: "2"}
+	capoed, err := cmd.capo(song)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !song.Key.IsZero() || song.Capo != 0 {
+		t.Errorf("expected the song to be unchanged, but it is in %q with a capo on %d", song.Key, song.Capo)
+	}
+	if capoed.Key.String() != "A" || capoed.Capo != 2 {
+		t.Errorf("expected the new song to be in A with a capo on 2, but it is in %q with a capo on %d", capoed.Key, capoed.Capo)
+	}
+}
+
+func TestTransposeNumberChart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songtool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chart := filepath.Join(dir, "chart.nns")
+	if err := ioutil.WriteFile(chart, []byte("#title=Grace\n#key=G\n\n1    4    5/7\nAmazing grace\n"), 0666); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		toKey    string
+		toFormat string
+		expected string
+	}{
+		{"A", "", "A    D    E/G#"},
+		{"A", "chordpro", "[A]Amazi[D]ng gr[E/G#]ace"},
+		{"Bb", "chordsOverLyrics", "Bb   Eb   F/A"},
+		// without a key, the chart stays a chart.
+		{"", "", "1    4    5/7"},
+	}
+
+	for _, test := range tests {
+		out := filepath.Join(dir, "out")
+		cmd := &options{ToKey: test.toKey, ToFormat: test.toFormat, Out: out}
+		if err := cmd.execute([]string{chart}); err != nil {
+			t.Errorf("-k %q -f %q: unexpected error: %v", test.toKey, test.toFormat, err)
+			continue
+		}
+
+		written, _ := ioutil.ReadFile(out)
+		if !strings.Contains(string(written), test.expected) {
+			t.Errorf("-k %q -f %q wrote %q, expected it to contain %q", test.toKey, test.toFormat, written, test.expected)
+		}
+	}
+}
+
+func TestTransposeAndCapo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songtool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	song := filepath.Join(dir, "song.cho")
+	if err := ioutil.WriteFile(song, []byte("{title: Grace}\n[G]Amazing [C]grace [D]how sweet\n"), 0666); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := filepath.Join(dir, "out.cho")
+	cmd := &options{CurrentKey: "G", ToKey: "A", Capo: "2", Out: out}
+	if err := cmd.execute([]string{song}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the song sounds in A, played with G shapes on the 2nd fret.
+	written, _ := ioutil.ReadFile(out)
+	for _, expected := range []string{"{key:A}", "{capo:2}", "[G]Amazing [C]grace [D]how sweet"} {
+		if !strings.Contains(string(written), expected) {
+			t.Errorf("-k A --capo 2 wrote %q, expected it to contain %q", written, expected)
+		}
+	}
+}
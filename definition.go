@@ -0,0 +1,181 @@
+package songtools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ChordDefinition is the fingering a song gives for a chord on a fretted instrument.
+type ChordDefinition struct {
+	Name string
+	// BaseFret is the fret the fingering starts on, which is 1 for first position.
+	BaseFret int
+	// Frets holds the fret of each string, from the lowest string, counting from the base
+	// fret. 0 is an open string and MutedString is a string that isn't played. A definition
+	// without frets only refers to a chord by name.
+	Frets []int
+	// Fingers holds the finger used on each string, from 1 for the index finger, or 0 when
+	// no finger is used. It is optional.
+	Fingers []int
+	// Display indicates the chord's diagram is shown in the song, rather than the chord only
+	// being defined for it.
+	Display bool
+}
+
+// MutedString is the fret of a string that isn't played.
+const MutedString = -1
+
+const (
+	baseFretKeyword = "base-fret"
+	fretsKeyword    = "frets"
+	fingersKeyword  = "fingers"
+)
+
+// ParseChordDefinition parses a definition as written in ChordPro, such as
+// "Asus2 base-fret 1 frets x 0 2 2 0 0 fingers 0 0 1 2 0 0". Muted strings are written as x or N.
+func ParseChordDefinition(text string) (*ChordDefinition, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("the chord definition has no name")
+	}
+
+	d := &ChordDefinition{
+		Name:     strings.TrimSuffix(fields[0], ":"),
+		BaseFret: 1,
+	}
+
+	keyword := ""
+	for _, f := range fields[1:] {
+		switch strings.ToLower(f) {
+		case baseFretKeyword, fretsKeyword, fingersKeyword:
+			keyword = strings.ToLower(f)
+			continue
+		}
+
+		switch keyword {
+		case baseFretKeyword:
+			fret, err := strconv.Atoi(f)
+			if err != nil || fret < 1 {
+				return nil, fmt.Errorf("the base fret '%v' of %v is not a fret number", f, d.Name)
+			}
+			d.BaseFret = fret
+			keyword = ""
+		case fretsKeyword:
+			fret, ok := parseFret(f)
+			if !ok {
+				return nil, fmt.Errorf("the fret '%v' of %v is not a fret number", f, d.Name)
+			}
+			d.Frets = append(d.Frets, fret)
+		case fingersKeyword:
+			finger, ok := parseFinger(f)
+			if !ok {
+				return nil, fmt.Errorf("the finger '%v' of %v is not a finger number", f, d.Name)
+			}
+			d.Fingers = append(d.Fingers, finger)
+		default:
+			return nil, fmt.Errorf("unexpected '%v' in the definition of %v", f, d.Name)
+		}
+	}
+
+	if len(d.Fingers) > 0 && len(d.Fingers) != len(d.Frets) {
+		return nil, fmt.Errorf("%v has %d frets, but %d fingers", d.Name, len(d.Frets), len(d.Fingers))
+	}
+
+	return d, nil
+}
+
+func parseFret(text string) (int, bool) {
+	switch text {
+	case "x", "X", "N", "-1":
+		return MutedString, true
+	}
+
+	fret, err := strconv.Atoi(text)
+	if err != nil || fret < 0 {
+		return 0, false
+	}
+
+	return fret, true
+}
+
+func parseFinger(text string) (int, bool) {
+	switch text {
+	case "x", "X", "N", "-":
+		return 0, true
+	}
+
+	finger, err := strconv.Atoi(text)
+	if err != nil || finger < 0 {
+		return 0, false
+	}
+
+	return finger, true
+}
+
+// String writes the definition as ChordPro does, such as "Asus2 base-fret 1 frets x 0 2 2 0 0".
+func (d *ChordDefinition) String() string {
+	if len(d.Frets) == 0 {
+		return d.Name
+	}
+
+	parts := []string{d.Name, baseFretKeyword, strconv.Itoa(d.BaseFret), fretsKeyword}
+	for _, f := range d.Frets {
+		if f == MutedString {
+			parts = append(parts, "x")
+		} else {
+			parts = append(parts, strconv.Itoa(f))
+		}
+	}
+
+	if len(d.Fingers) > 0 {
+		parts = append(parts, fingersKeyword)
+		for _, f := range d.Fingers {
+			parts = append(parts, strconv.Itoa(f))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Movable indicates the fingering has no open strings, so it can be moved up and down the neck
+// to play the same kind of chord with another root.
+func (d *ChordDefinition) Movable() bool {
+	for _, f := range d.Frets {
+		if f == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// renamed creates a copy of the definition for the chord, moving the fingering by the number
+// of semitones between the chords' roots. The fingering is moved down the neck rather than
+// up when it can be. A fingering with open strings can't be moved, so the copy only refers to
+// the chord by name, without frets.
+func (d *ChordDefinition) renamed(from, to *Chord) *ChordDefinition {
+	semitones := int(to.Root.Interval(-int(from.Root)))
+	baseFret := d.BaseFret
+	if semitones != 0 && len(d.Frets) > 0 {
+		if !d.Movable() {
+			return &ChordDefinition{
+				Name:    to.Name,
+				Display: d.Display,
+			}
+		}
+
+		baseFret += semitones
+		if baseFret-noteCount >= 1 {
+			baseFret -= noteCount
+		}
+	}
+
+	return &ChordDefinition{
+		Name:     to.Name,
+		BaseFret: baseFret,
+		Frets:    d.Frets,
+		Fingers:  d.Fingers,
+		Display:  d.Display,
+	}
+}
@@ -0,0 +1,40 @@
+package songtools
+
+import "testing"
+
+func TestTransposeSongDefinitions(t *testing.T) {
+	tests := []struct {
+		definition string
+		expected   string
+	}{
+		{"A base-fret 1 frets x 0 2 2 2 0", "B"},
+		{"Am base-fret 5 frets 1 3 3 1 1 1", "Bm base-fret 7 frets 1 3 3 1 1 1"},
+		{"G7 base-fret 3 frets x 1 3 1 3 1", "A7 base-fret 5 frets x 1 3 1 3 1"},
+		{"E base-fret 9 frets x 1 3 3 3 1", "F# base-fret 11 frets x 1 3 3 3 1"},
+		{"A", "B"},
+		{"Intro base-fret 1 frets x 0 2 2 2 0", "Intro base-fret 1 frets x 0 2 2 2 0"},
+	}
+
+	for _, test := range tests {
+		d, err := ParseChordDefinition(test.definition)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.definition, err)
+			continue
+		}
+
+		s := &Song{Key: MustParseKey("A"), Definitions: []*ChordDefinition{d}}
+		transposed, err := TransposeSong(s, s.Key, MustParseKey("B"))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.definition, err)
+			continue
+		}
+
+		if len(transposed.Definitions) != 1 {
+			t.Errorf("%q: expected 1 definition, but got %d", test.definition, len(transposed.Definitions))
+			continue
+		}
+		if actual := transposed.Definitions[0].String(); actual != test.expected {
+			t.Errorf("%q: expected %q, but got %q", test.definition, test.expected, actual)
+		}
+	}
+}
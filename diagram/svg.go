@@ -0,0 +1,169 @@
+// Package diagram draws chord diagrams, the boxes of strings and frets that show where to put
+// the fingers to play a chord.
+package diagram
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/songtools/songtools"
+)
+
+const (
+	stringGap = 12
+	fretGap   = 14
+	marginX   = 12
+	// labelWidth is the room to the right of the box for the base fret.
+	labelWidth = 22
+	nameHeight = 16
+	// markerHeight is the room above the box for the open and muted strings.
+	markerHeight = 12
+	dotRadius    = 5
+	minFrets     = 4
+)
+
+// SVG draws the chord definition as an SVG image.
+func SVG(d *songtools.ChordDefinition) (string, error) {
+	buf := &bytes.Buffer{}
+	err := WriteSVG(buf, d)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// WriteSVG draws the chord definition as an SVG image to the writer. The box has a line for
+// each string, from the lowest string on the left, and starts at the nut unless the definition
+// has a base fret, which is labeled to the right. Muted strings are marked with an x and open
+// strings with an o above the box. Each fretted string gets a dot with the number of its
+// finger, and a finger that holds down more than one string at the same fret is drawn as a
+// barre across them.
+func WriteSVG(w io.Writer, d *songtools.ChordDefinition) error {
+	if len(d.Frets) < 2 {
+		return fmt.Errorf("unable to draw %v without the frets of its strings", d.Name)
+	}
+
+	frets := minFrets
+	for _, f := range d.Frets {
+		if f > frets {
+			frets = f
+		}
+	}
+
+	top := nameHeight + markerHeight
+	boxWidth := stringGap * (len(d.Frets) - 1)
+	boxHeight := fretGap * frets
+	width := marginX + boxWidth + labelWidth
+	height := top + boxHeight + marginX
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "<svg xmlns='http://www.w3.org/2000/svg' class='chord-diagram' width='%d' height='%d' viewBox='0 0 %d %d'>", width, height, width, height)
+	fmt.Fprintf(buf, "<text x='%d' y='%d' text-anchor='middle' font-size='12' font-weight='bold'>%v</text>", marginX+boxWidth/2, nameHeight-4, html.EscapeString(d.Name))
+
+	// the strings and frets.
+	for i := range d.Frets {
+		x := marginX + i*stringGap
+		fmt.Fprintf(buf, "<line x1='%d' y1='%d' x2='%d' y2='%d' stroke='black'/>", x, top, x, top+boxHeight)
+	}
+	for f := 0; f <= frets; f++ {
+		y := top + f*fretGap
+		stroke := 1
+		if f == 0 && d.BaseFret <= 1 {
+			stroke = 3
+		}
+		fmt.Fprintf(buf, "<line x1='%d' y1='%d' x2='%d' y2='%d' stroke='black' stroke-width='%d'/>", marginX, y, marginX+boxWidth, y, stroke)
+	}
+	if d.BaseFret > 1 {
+		fmt.Fprintf(buf, "<text x='%d' y='%d' font-size='10'>%dfr</text>", marginX+boxWidth+4, top+fretGap/2+4, d.BaseFret)
+	}
+
+	// the open and muted strings.
+	for i, f := range d.Frets {
+		x := marginX + i*stringGap
+		y := top - markerHeight/2 - 1
+		switch f {
+		case songtools.MutedString:
+			fmt.Fprintf(buf, "<path d='M%d %dl6 6m0 -6l-6 6' stroke='black'/>", x-3, y-3)
+		case 0:
+			fmt.Fprintf(buf, "<circle cx='%d' cy='%d' r='3' fill='none' stroke='black'/>", x, y)
+		}
+	}
+
+	// the barres, then the dots of the strings that aren't under a barre.
+	barred := make([]bool, len(d.Frets))
+	for _, b := range Barres(d) {
+		y := top + b.Fret*fretGap - fretGap/2
+		x1, x2 := marginX+b.First*stringGap, marginX+b.Last*stringGap
+		fmt.Fprintf(buf, "<rect x='%d' y='%d' width='%d' height='%d' rx='%d' fill='black'/>", x1-dotRadius, y-dotRadius, x2-x1+2*dotRadius, 2*dotRadius, dotRadius)
+		fmt.Fprintf(buf, "<text x='%d' y='%d' text-anchor='middle' font-size='8' fill='white'>%d</text>", (x1+x2)/2, y+3, b.Finger)
+		for i := b.First; i <= b.Last; i++ {
+			if d.Frets[i] == b.Fret {
+				barred[i] = true
+			}
+		}
+	}
+	for i, f := range d.Frets {
+		if f <= 0 || barred[i] {
+			continue
+		}
+
+		x, y := marginX+i*stringGap, top+f*fretGap-fretGap/2
+		fmt.Fprintf(buf, "<circle cx='%d' cy='%d' r='%d' fill='black'/>", x, y, dotRadius)
+		if i < len(d.Fingers) && d.Fingers[i] > 0 {
+			fmt.Fprintf(buf, "<text x='%d' y='%d' text-anchor='middle' font-size='8' fill='white'>%d</text>", x, y+3, d.Fingers[i])
+		}
+	}
+
+	buf.WriteString("</svg>")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Barre is a finger laid across several strings at the same fret. The strings are numbered
+// from the lowest string.
+type Barre struct {
+	Finger int
+	Fret   int
+	First  int
+	Last   int
+}
+
+// Barres finds the fingers of the definition that hold down more than one string at the same
+// fret.
+func Barres(d *songtools.ChordDefinition) []Barre {
+	found := []Barre{}
+	for i := 0; i < len(d.Fingers) && i < len(d.Frets); i++ {
+		finger, fret := d.Fingers[i], d.Frets[i]
+		if finger <= 0 || fret <= 0 {
+			continue
+		}
+
+		last := i
+		for j := i + 1; j < len(d.Fingers) && j < len(d.Frets); j++ {
+			if d.Fingers[j] == finger && d.Frets[j] == fret {
+				last = j
+			}
+		}
+
+		if last == i || covered(found, finger) {
+			continue
+		}
+		found = append(found, Barre{Finger: finger, Fret: fret, First: i, Last: last})
+	}
+
+	return found
+}
+
+func covered(found []Barre, finger int) bool {
+	for _, b := range found {
+		if b.Finger == finger {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,125 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+const x = songtools.MutedString
+
+func TestBarres(t *testing.T) {
+	tests := []struct {
+		name     string
+		frets    []int
+		fingers  []int
+		expected []Barre
+	}{
+		{"F", []int{1, 3, 3, 2, 1, 1}, []int{1, 3, 4, 2, 1, 1}, []Barre{{Finger: 1, Fret: 1, First: 0, Last: 5}}},
+		{"Bm", []int{x, 2, 4, 4, 3, 2}, []int{0, 1, 3, 4, 2, 1}, []Barre{{Finger: 1, Fret: 2, First: 1, Last: 5}}},
+		{"A", []int{x, 0, 2, 2, 2, 0}, []int{0, 0, 1, 1, 1, 0}, []Barre{{Finger: 1, Fret: 2, First: 2, Last: 4}}},
+		{"E7#9", []int{0, 2, 0, 1, 3, 3}, []int{0, 2, 0, 1, 3, 3}, []Barre{{Finger: 3, Fret: 3, First: 4, Last: 5}}},
+		{"C", []int{x, 3, 2, 0, 1, 0}, []int{0, 3, 2, 0, 1, 0}, nil},
+		// a finger on different frets isn't a barre.
+		{"Fmaj7", []int{x, x, 3, 2, 1, 0}, []int{0, 0, 1, 1, 1, 0}, nil},
+		{"no fingers", []int{1, 3, 3, 2, 1, 1}, nil, nil},
+	}
+
+	for _, test := range tests {
+		d := &songtools.ChordDefinition{Name: test.name, Frets: test.frets, Fingers: test.fingers}
+		if actual := Barres(d); fmt.Sprint(actual) != fmt.Sprint(test.expected) {
+			t.Errorf("Barres(%v) = %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseFret int
+		frets    []int
+		fingers  []int
+		muted    int
+		open     int
+		dots     int
+		barres   int
+	}{
+		{"C", 0, []int{x, 3, 2, 0, 1, 0}, []int{0, 3, 2, 0, 1, 0}, 1, 2, 3, 0},
+		{"D", 0, []int{x, x, 0, 2, 3, 2}, nil, 2, 1, 3, 0},
+		{"F", 0, []int{1, 3, 3, 2, 1, 1}, []int{1, 3, 4, 2, 1, 1}, 0, 0, 3, 1},
+		{"A", 0, []int{x, 0, 2, 2, 2, 0}, []int{0, 0, 1, 1, 1, 0}, 1, 2, 0, 1},
+		{"Bm", 2, []int{x, 1, 3, 3, 2, 1}, []int{0, 1, 3, 4, 2, 1}, 1, 0, 3, 1},
+	}
+
+	for _, test := range tests {
+		d := &songtools.ChordDefinition{Name: test.name, BaseFret: test.baseFret, Frets: test.frets, Fingers: test.fingers}
+		svg, err := SVG(d)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		counts := []struct {
+			what     string
+			marker   string
+			expected int
+		}{
+			{"muted strings", "<path ", test.muted},
+			{"open strings", "r='3' fill='none'", test.open},
+			{"dots", fmt.Sprintf("r='%d' fill='black'", dotRadius), test.dots},
+			{"barres", "<rect ", test.barres},
+		}
+		for _, c := range counts {
+			if actual := strings.Count(svg, c.marker); actual != c.expected {
+				t.Errorf("%v: expected %d %v, but found %d in %v", test.name, c.expected, c.what, actual, svg)
+			}
+		}
+
+		// the nut is only drawn when the box starts at the first fret.
+		nut := strings.Contains(svg, "stroke-width='3'")
+		label := strings.Contains(svg, fmt.Sprintf(">%dfr</text>", test.baseFret))
+		if nut != (test.baseFret <= 1) || label != (test.baseFret > 1) {
+			t.Errorf("%v: expected a nut: %v and a base fret label: %v, but found %v and %v", test.name, test.baseFret <= 1, test.baseFret > 1, nut, label)
+		}
+	}
+}
+
+func TestSVGBarre(t *testing.T) {
+	d := &songtools.ChordDefinition{Name: "F", Frets: []int{1, 3, 3, 2, 1, 1}, Fingers: []int{1, 3, 4, 2, 1, 1}}
+	svg, err := SVG(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the barre spans from the lowest string to the highest at the first fret, with its finger
+	// in the middle.
+	top := nameHeight + markerHeight
+	y := top + fretGap/2
+	expected := []string{
+		fmt.Sprintf("<rect x='%d' y='%d' width='%d' height='%d'", marginX-dotRadius, y-dotRadius, 5*stringGap+2*dotRadius, 2*dotRadius),
+		fmt.Sprintf("<text x='%d' y='%d' text-anchor='middle' font-size='8' fill='white'>1</text>", marginX+5*stringGap/2, y+3),
+		fmt.Sprintf("<circle cx='%d' cy='%d' r='%d' fill='black'/><text x='%d' y='%d' text-anchor='middle' font-size='8' fill='white'>3</text>",
+			marginX+stringGap, top+3*fretGap-fretGap/2, dotRadius, marginX+stringGap, top+3*fretGap-fretGap/2+3),
+	}
+	for _, e := range expected {
+		if !strings.Contains(svg, e) {
+			t.Errorf("expected the diagram to contain %q, but was %v", e, svg)
+		}
+	}
+}
+
+func TestSVGErrors(t *testing.T) {
+	if _, err := SVG(&songtools.ChordDefinition{Name: "C"}); err == nil {
+		t.Errorf("expected an error drawing a chord without frets")
+	}
+
+	svg, err := SVG(&songtools.ChordDefinition{Name: "<C>", Frets: []int{0, 0}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(svg, "&lt;C&gt;") {
+		t.Errorf("expected the name to be escaped, but was %v", svg)
+	}
+}
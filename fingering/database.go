@@ -0,0 +1,167 @@
+package fingering
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+// guitarChords are the usual fingerings of common chords in standard guitar tuning. Each
+// is the name of the chord followed by the fret of each string from the lowest string.
+var guitarChords = []string{
+	"C x 3 2 0 1 0",
+	"C7 x 3 2 3 1 0",
+	"Cmaj7 x 3 2 0 0 0",
+	"Cm x 3 5 5 4 3",
+	"Cadd9 x 3 2 0 3 0",
+	"C#m x 4 6 6 5 4",
+	"D x x 0 2 3 2",
+	"D7 x x 0 2 1 2",
+	"Dmaj7 x x 0 2 2 2",
+	"Dm x x 0 2 3 1",
+	"Dm7 x x 0 2 1 1",
+	"Dsus2 x x 0 2 3 0",
+	"Dsus4 x x 0 2 3 3",
+	"D5 x 5 7 7 x x",
+	"Eb x 6 8 8 8 6",
+	"E 0 2 2 1 0 0",
+	"E7 0 2 0 1 0 0",
+	"Emaj7 0 2 1 1 0 0",
+	"Em 0 2 2 0 0 0",
+	"Em7 0 2 0 0 0 0",
+	"Esus4 0 2 2 2 0 0",
+	"E5 0 2 2 x x x",
+	"F 1 3 3 2 1 1",
+	"Fmaj7 x x 3 2 1 0",
+	"Fm 1 3 3 1 1 1",
+	"F#m 2 4 4 2 2 2",
+	"G 3 2 0 0 0 3",
+	"G7 3 2 0 0 0 1",
+	"Gmaj7 3 2 0 0 0 2",
+	"Gm 3 5 5 3 3 3",
+	"G5 3 5 5 x x x",
+	"Ab 4 6 6 5 4 4",
+	"A x 0 2 2 2 0",
+	"A7 x 0 2 0 2 0",
+	"Amaj7 x 0 2 1 2 0",
+	"Am x 0 2 2 1 0",
+	"Am7 x 0 2 0 1 0",
+	"Asus2 x 0 2 2 0 0",
+	"Asus4 x 0 2 2 3 0",
+	"A5 x 0 2 2 x x",
+	"Bb x 1 3 3 3 1",
+	"B x 2 4 4 4 2",
+	"B7 x 2 1 2 0 2",
+	"Bm x 2 4 4 3 2",
+	"Bm7 x 2 0 2 0 2",
+}
+
+// ukuleleChords are the usual fingerings of common chords on a ukulele.
+var ukuleleChords = []string{
+	"C 0 0 0 3",
+	"C7 0 0 0 1",
+	"Cm 0 3 3 3",
+	"D 2 2 2 0",
+	"D7 2 2 2 3",
+	"Dm 2 2 1 0",
+	"E 4 4 4 2",
+	"E7 1 2 0 2",
+	"Em 0 4 3 2",
+	"F 2 0 1 0",
+	"G 0 2 3 2",
+	"G7 0 2 1 2",
+	"Gm 0 2 3 1",
+	"A 2 1 0 0",
+	"A7 0 1 0 0",
+	"Am 2 0 0 0",
+	"Bb 3 2 1 1",
+	"B 4 3 2 2",
+	"Bm 4 2 2 2",
+}
+
+// mandolinChords are the usual fingerings of common chords on a mandolin.
+var mandolinChords = []string{
+	"C 0 2 3 0",
+	"D 2 0 0 2",
+	"Dm 2 0 0 1",
+	"E 1 2 2 0",
+	"Em 0 2 2 0",
+	"G 0 0 2 3",
+	"A 2 2 4 5",
+	"Am 2 2 3 0",
+}
+
+// database holds the fingerings of common chords by the tuning they are for.
+var database = map[string][]*voicing{}
+
+func init() {
+	load(Guitar, guitarChords)
+	load(Ukulele, ukuleleChords)
+	load(Mandolin, mandolinChords)
+}
+
+func load(t *Tuning, entries []string) {
+	for _, e := range entries {
+		fields := strings.Fields(e)
+		c, ok := songtools.ParseChord(fields[0])
+		if !ok || len(fields)-1 != len(t.Strings) {
+			panic(fmt.Sprintf("the %v chord '%v' is invalid", t, e))
+		}
+
+		frets := []int{}
+		for _, f := range fields[1:] {
+			fret := songtools.MutedString
+			if f != "x" {
+				n, err := strconv.Atoi(f)
+				if err != nil {
+					panic(fmt.Sprintf("the %v chord '%v' is invalid", t, e))
+				}
+				fret = n
+			}
+			frets = append(frets, fret)
+		}
+
+		v, ok := newVoicing(c, frets, DefaultOptions.MaxFingers)
+		if !ok {
+			panic(fmt.Sprintf("the %v chord '%v' can't be fingered", t, e))
+		}
+		database[tuningKey(t)] = append(database[tuningKey(t)], v)
+	}
+}
+
+// lookup finds the fingerings of the chord in the database. A chord matches when it has the
+// same bass and notes, whatever it is named.
+func lookup(c *songtools.Chord, t *Tuning) []*voicing {
+	found := []*voicing{}
+	for _, v := range database[tuningKey(t)] {
+		if chordKey(v.chord) == chordKey(c) {
+			found = append(found, v)
+		}
+	}
+
+	return found
+}
+
+// tuningKey identifies a tuning by its notes, so a custom tuning that matches a common one
+// finds its chords.
+func tuningKey(t *Tuning) string {
+	return fmt.Sprint(t.notes())
+}
+
+// chordKey identifies a chord by its bass and the rest of its notes.
+func chordKey(c *songtools.Chord) string {
+	notes := c.Notes()
+	if len(notes) == 0 {
+		return ""
+	}
+
+	rest := append([]songtools.Note{}, notes[1:]...)
+	sort.Slice(rest, func(i, j int) bool {
+		return rest[i] < rest[j]
+	})
+
+	return fmt.Sprint(notes[0], rest)
+}
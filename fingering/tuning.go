@@ -0,0 +1,124 @@
+package fingering
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+// Tuning is the notes an instrument's strings are tuned to.
+type Tuning struct {
+	Name string
+	// Strings holds the note of each open string, from the lowest string.
+	Strings []songtools.Spelling
+}
+
+// The tunings of common instruments.
+var (
+	Guitar   = mustParseTuning("guitar", "E A D G B E")
+	Ukulele  = mustParseTuning("ukulele", "G C E A")
+	Mandolin = mustParseTuning("mandolin", "G D A E")
+)
+
+var namedTunings = []*Tuning{Guitar, Ukulele, Mandolin}
+
+// ParseTuning parses the name of a common instrument, such as guitar, or the notes of the
+// open strings from the lowest string, such as "D A D G A D" or DADGAD.
+func ParseTuning(text string) (*Tuning, error) {
+	text = strings.TrimSpace(text)
+	for _, t := range namedTunings {
+		if strings.EqualFold(t.Name, text) {
+			return t, nil
+		}
+	}
+
+	return parseNotes(text)
+}
+
+// parseNotes parses the notes of the open strings.
+func parseNotes(text string) (*Tuning, error) {
+	t := &Tuning{Name: text}
+	rest := strings.Replace(text, " ", "", -1)
+	for rest != "" {
+		n := 1
+		for n < len(rest) && strings.IndexByte("#b", rest[n]) != -1 {
+			n++
+		}
+
+		s, ok := songtools.ParseSpelling(strings.ToUpper(rest[:1]) + rest[1:n])
+		if !ok {
+			return nil, fmt.Errorf("the tuning '%v' is neither an instrument nor a list of notes", text)
+		}
+		t.Strings = append(t.Strings, s)
+		rest = rest[n:]
+	}
+
+	if len(t.Strings) < 2 {
+		return nil, fmt.Errorf("the tuning '%v' needs at least 2 strings", text)
+	}
+
+	return t, nil
+}
+
+// TuningOf finds the tuning of a common instrument that the fingering of the definition plays
+// its chord on, or indicates there isn't one.
+func TuningOf(d *songtools.ChordDefinition) (*Tuning, bool) {
+	c, ok := songtools.ParseChord(d.Name)
+	if !ok || len(d.Frets) == 0 {
+		return nil, false
+	}
+
+	notes := c.Notes()
+	for _, t := range namedTunings {
+		if len(t.Strings) == len(d.Frets) && playsNotes(d, t, notes) {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+// playsNotes indicates every string the definition plays in the tuning is one of the notes.
+func playsNotes(d *songtools.ChordDefinition, t *Tuning, notes []songtools.Note) bool {
+	open := t.notes()
+	for i, f := range d.Frets {
+		if f == songtools.MutedString {
+			continue
+		}
+
+		fret := f
+		if f > 0 && d.BaseFret > 1 {
+			fret += d.BaseFret - 1
+		}
+		if !isChordNote(open[i].Interval(fret), notes) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseTuning(name, notes string) *Tuning {
+	t, err := parseNotes(notes)
+	if err != nil {
+		panic(err)
+	}
+
+	t.Name = name
+	return t
+}
+
+func (t *Tuning) String() string {
+	return t.Name
+}
+
+// notes gets the notes of the open strings.
+func (t *Tuning) notes() []songtools.Note {
+	notes := []songtools.Note{}
+	for _, s := range t.Strings {
+		notes = append(notes, s.Note())
+	}
+
+	return notes
+}
@@ -0,0 +1,39 @@
+package fingering
+
+import (
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestTuningOf(t *testing.T) {
+	tests := []struct {
+		definition string
+		tuning     string
+	}{
+		{"A base-fret 1 frets x 0 2 2 2 0", "guitar"},
+		{"C base-fret 1 frets 0 0 0 3", "ukulele"},
+		{"G base-fret 1 frets 0 0 2 3", "mandolin"},
+		{"Bm base-fret 2 frets x 1 3 3 2 1", "guitar"},
+		{"A base-fret 1 frets x 0 2 2 2", ""},
+		{"A base-fret 1 frets 3 3 3 3 3 3", ""},
+		{"Intro base-fret 1 frets x 0 2 2 2 0", ""},
+	}
+
+	for _, test := range tests {
+		d, err := songtools.ParseChordDefinition(test.definition)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.definition, err)
+			continue
+		}
+
+		tuning, ok := TuningOf(d)
+		name := ""
+		if ok {
+			name = tuning.Name
+		}
+		if name != test.tuning {
+			t.Errorf("TuningOf(%q) = %q, expected %q", test.definition, name, test.tuning)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// Package fingering finds the ways to play chords on fretted instruments, such as the guitar,
+// ukulele and mandolin.
+package fingering
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/songtools/songtools"
+)
+
+// Options limit the fingerings that are generated.
+type Options struct {
+	// MaxSpan is the most frets a fingering may stretch across.
+	MaxSpan int
+	// MaxFingers is the most fingers a fingering may use. A barre counts as one finger.
+	MaxFingers int
+	// MaxFret is the highest fret a fingering may use.
+	MaxFret int
+}
+
+// DefaultOptions are the limits used when no options are given.
+var DefaultOptions = &Options{
+	MaxSpan:    4,
+	MaxFingers: 4,
+	MaxFret:    12,
+}
+
+// diagramFrets is the number of frets shown from the nut before a fingering needs a base fret.
+const diagramFrets = 4
+
+// voicing is a way to play a chord, with the frets counted from the nut.
+type voicing struct {
+	chord   *songtools.Chord
+	frets   []int
+	fingers []int
+	score   int
+}
+
+// Voicings returns the ways to play the chord in the tuning, best first. Fingerings from the
+// database of common chords come first, followed by generated ones. Each is a definition named
+// for the chord, with its frets counted from its base fret. Unknown chords have none.
+func Voicings(c *songtools.Chord, t *Tuning, opts *Options) []*songtools.ChordDefinition {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	if c.Unknown {
+		return nil
+	}
+
+	defs := []*songtools.ChordDefinition{}
+	seen := map[string]bool{}
+	for _, v := range append(lookup(c, t), generate(c, t, opts)...) {
+		if key := fmt.Sprint(v.frets); !seen[key] {
+			seen[key] = true
+			defs = append(defs, v.definition(c.Name))
+		}
+	}
+
+	return defs
+}
+
+// Best returns the best way to play the chord in the tuning.
+func Best(c *songtools.Chord, t *Tuning, opts *Options) (*songtools.ChordDefinition, bool) {
+	defs := Voicings(c, t, opts)
+	if len(defs) == 0 {
+		return nil, false
+	}
+
+	return defs[0], true
+}
+
+// SongDefinitions returns a fingering for each different chord in the song, in the order they
+// first appear. A definition the song gives for a chord is used when it has a fret for each
+// string of the tuning. Chords that can't be played, such as unknown chords, are left out.
+func SongDefinitions(s *songtools.Song, t *Tuning, opts *Options) []*songtools.ChordDefinition {
+	given := map[string]*songtools.ChordDefinition{}
+	for _, d := range s.Definitions {
+		if len(d.Frets) == len(t.Strings) {
+			given[d.Name] = d
+		}
+	}
+
+	defs := []*songtools.ChordDefinition{}
+	seen := map[string]bool{}
+	for _, c := range s.Chords() {
+		if seen[c.Name] {
+			continue
+		}
+		seen[c.Name] = true
+
+		if d, ok := given[c.Name]; ok {
+			defs = append(defs, d)
+		} else if d, ok := Best(c, t, opts); ok {
+			defs = append(defs, d)
+		}
+	}
+
+	return defs
+}
+
+// bassStrings is the fewest strings an instrument needs for its lowest string to play the bass
+// of a chord. Instruments with fewer strings, like the ukulele, are played too high for the
+// bass to matter.
+const bassStrings = 5
+
+// generate finds the fingerings of the chord that fit in the options. Every note of the chord
+// is played, except that the fifth may be left out of chords with more than three notes. When
+// that doesn't leave few enough notes to play, the chord's other omittable notes are left out
+// in turn, the 11th and then the 9th, and last the root on instruments that don't play the
+// bass. Only the lowest strings may be muted, and on instruments with enough strings, the
+// lowest string played is the bass of the chord.
+func generate(c *songtools.Chord, t *Tuning, opts *Options) []*voicing {
+	notes := c.Notes()
+	if len(notes) == 0 {
+		return nil
+	}
+
+	fifth := c.Root.Interval(7)
+	omittable := []songtools.Note{}
+	for _, n := range c.Omittable() {
+		if n != fifth && (n != c.Root || len(t.Strings) < bassStrings) {
+			omittable = append(omittable, n)
+		}
+	}
+
+	required := map[songtools.Note]bool{}
+	for _, n := range notes {
+		required[n] = true
+	}
+	if len(required) > 3 && fifth != notes[0] {
+		delete(required, fifth)
+	}
+
+	found := search(c, t, opts, notes, required)
+	for _, n := range omittable {
+		if len(found) > 0 || len(required) <= 3 {
+			break
+		}
+
+		delete(required, n)
+		found = search(c, t, opts, notes, required)
+	}
+
+	return found
+}
+
+// search finds the fingerings of the chord that play all the required notes, along with any
+// of the chord's other notes.
+func search(c *songtools.Chord, t *Tuning, opts *Options, notes []songtools.Note, required map[songtools.Note]bool) []*voicing {
+	bass := notes[0]
+	open := t.notes()
+	minSounding := len(open) - len(open)/3
+	checkBass := len(open) >= bassStrings
+
+	found := []*voicing{}
+	seen := map[string]bool{}
+	frets := make([]int, len(open))
+
+	// each window of frets up the neck is searched in turn, along with the open strings.
+	for low := 1; low+opts.MaxSpan-1 <= opts.MaxFret; low++ {
+		candidates := []int{0}
+		for f := low; f < low+opts.MaxSpan; f++ {
+			candidates = append(candidates, f)
+		}
+
+		var place func(str int, sounding bool)
+		place = func(str int, sounding bool) {
+			if str == len(open) {
+				key := fmt.Sprint(frets)
+				if seen[key] || !playsChord(frets, open, bass, checkBass, required, minSounding) {
+					return
+				}
+				if v, ok := newVoicing(c, append([]int{}, frets...), opts.MaxFingers); ok {
+					seen[key] = true
+					found = append(found, v)
+				}
+				return
+			}
+
+			if !sounding {
+				frets[str] = songtools.MutedString
+				place(str+1, false)
+			}
+			for _, f := range candidates {
+				if isChordNote(open[str].Interval(f), notes) {
+					frets[str] = f
+					place(str+1, true)
+				}
+			}
+		}
+		place(0, false)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].score < found[j].score
+	})
+
+	return found
+}
+
+func isChordNote(n songtools.Note, notes []songtools.Note) bool {
+	for _, cn := range notes {
+		if cn == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// playsChord indicates the frets play every required note on enough strings, and when
+// checking the bass, play the bass of the chord on the lowest string played.
+func playsChord(frets []int, open []songtools.Note, bass songtools.Note, checkBass bool, required map[songtools.Note]bool, minSounding int) bool {
+	played := map[songtools.Note]bool{}
+	sounding := 0
+	for i, f := range frets {
+		if f == songtools.MutedString {
+			continue
+		}
+
+		n := open[i].Interval(f)
+		if checkBass && sounding == 0 && n != bass {
+			return false
+		}
+		played[n] = true
+		sounding++
+	}
+
+	if sounding < minSounding {
+		return false
+	}
+	for n := range required {
+		if !played[n] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newVoicing works out the fingers for the frets, or indicates it takes too many fingers.
+// When the lowest fret is played on more than one string and nothing between them is lower,
+// a barre covers them with the first finger. The other frets get the following fingers, in
+// order up the neck.
+func newVoicing(c *songtools.Chord, frets []int, maxFingers int) (*voicing, bool) {
+	fingers := make([]int, len(frets))
+	fretted := []int{}
+	lowest := 0
+	for i, f := range frets {
+		if f > 0 {
+			fretted = append(fretted, i)
+			if lowest == 0 || f < lowest {
+				lowest = f
+			}
+		}
+	}
+
+	first, last := -1, -1
+	for _, i := range fretted {
+		if frets[i] == lowest {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	barre := first != last
+	for i := first; barre && i <= last; i++ {
+		barre = frets[i] >= lowest
+	}
+
+	sort.SliceStable(fretted, func(i, j int) bool {
+		return frets[fretted[i]] < frets[fretted[j]]
+	})
+
+	finger := 0
+	if barre {
+		finger = 1
+	}
+	for _, i := range fretted {
+		if barre && frets[i] == lowest {
+			fingers[i] = 1
+			continue
+		}
+		finger++
+		fingers[i] = finger
+	}
+	if finger > maxFingers {
+		return nil, false
+	}
+
+	v := &voicing{
+		chord:   c,
+		frets:   frets,
+		fingers: fingers,
+	}
+
+	// lower is better: first position, few fingers and no barre, with open strings ringing.
+	v.score = 2*lowest + finger
+	if barre {
+		v.score += 2
+	}
+	for _, f := range frets {
+		switch f {
+		case songtools.MutedString:
+			v.score += 2
+		case 0:
+			v.score--
+		}
+	}
+
+	return v, true
+}
+
+// definition turns the voicing into a definition of the chord. Fingerings that reach past the
+// first frets start from a base fret at their lowest fret.
+func (v *voicing) definition(name string) *songtools.ChordDefinition {
+	lowest, highest := 0, 0
+	for _, f := range v.frets {
+		if f > 0 && (lowest == 0 || f < lowest) {
+			lowest = f
+		}
+		if f > highest {
+			highest = f
+		}
+	}
+
+	d := &songtools.ChordDefinition{
+		Name:     name,
+		BaseFret: 1,
+		Frets:    v.frets,
+		Fingers:  v.fingers,
+	}
+	if highest > diagramFrets {
+		d.BaseFret = lowest
+		d.Frets = []int{}
+		for _, f := range v.frets {
+			if f > 0 {
+				f = f - lowest + 1
+			}
+			d.Frets = append(d.Frets, f)
+		}
+	}
+
+	return d
+}
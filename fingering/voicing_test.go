@@ -0,0 +1,78 @@
+package fingering
+
+import (
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestBest(t *testing.T) {
+	tests := []struct {
+		chord  string
+		tuning *Tuning
+	}{
+		{"C", Guitar},
+		{"G7", Guitar},
+		{"Am", Ukulele},
+		{"Cmaj7", Ukulele},
+		{"C9", Ukulele},
+		{"G13", Ukulele},
+		{"C7b9#11", Ukulele},
+		{"Cm11", Ukulele},
+		{"A13", Mandolin},
+		{"G13", Guitar},
+		{"C7b9#11", Guitar},
+		{"D/F#", Guitar},
+	}
+
+	for _, test := range tests {
+		c, ok := songtools.ParseChord(test.chord)
+		if !ok {
+			t.Errorf("%v: not a chord", test.chord)
+			continue
+		}
+
+		d, ok := Best(c, test.tuning, nil)
+		if !ok {
+			t.Errorf("%v on the %v: expected a fingering, but found none", test.chord, test.tuning)
+			continue
+		}
+		if len(d.Frets) != len(test.tuning.Strings) {
+			t.Errorf("%v on the %v: expected %d frets, but got %v", test.chord, test.tuning, len(test.tuning.Strings), d)
+		}
+		if !playsNotes(d, test.tuning, c.Notes()) {
+			t.Errorf("%v on the %v: %v plays notes that aren't in the chord", test.chord, test.tuning, d)
+		}
+	}
+}
+
+func TestOmittable(t *testing.T) {
+	tests := []struct {
+		chord    string
+		expected string
+	}{
+		{"C", "G C"},
+		{"C7", "G C"},
+		{"Cadd9", "G C"},
+		{"C9", "G D C"},
+		{"C11", "G F D C"},
+		{"G13", "D A G"},
+		{"C7b9#11", "G F# C# C"},
+		{"Cdim7", "C"},
+		{"C/G", "C"},
+	}
+
+	for _, test := range tests {
+		c, _ := songtools.ParseChord(test.chord)
+		actual := ""
+		for i, n := range c.Omittable() {
+			if i > 0 {
+				actual += " "
+			}
+			actual += n.String()
+		}
+		if actual != test.expected {
+			t.Errorf("%v: expected %q, but got %q", test.chord, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package chordpro
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+// Detect scores how likely the sample is to be a chordpro song. Lines containing
+// directives, such as {title:...}, and lines containing inline chords, such as [C],
+// count towards the score.
+func Detect(sample []byte) float64 {
+	total := 0
+	hits := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(sample))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		total++
+		if isDirectiveLine(line) || hasInlineChord(line) {
+			hits++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(total)
+}
+
+func isDirectiveLine(line string) bool {
+	if !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}") {
+		return false
+	}
+
+	d, err := parseDirective(line[1 : len(line)-1])
+	if err != nil {
+		return false
+	}
+
+	return d.Name != "" && !strings.Contains(d.Name, " ")
+}
+
+func hasInlineChord(line string) bool {
+	for {
+		start := strings.Index(line, "[")
+		if start == -1 {
+			return false
+		}
+
+		end := strings.Index(line[start:], "]")
+		if end == -1 {
+			return false
+		}
+
+		if _, ok := songtools.ParseChord(line[start+1 : start+end]); ok {
+			return true
+		}
+
+		line = line[start+end+1:]
+	}
+}
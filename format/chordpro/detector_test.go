@@ -0,0 +1,29 @@
+package chordpro
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		sample   string
+		expected float64
+	}{
+		{"{title: Grace}\n{key: G}\n\nA[G]mazing [C]grace\nhow sweet the sound\n", 0.75},
+		{"{soc}\n[G]Amazing grace\n{eoc}\n", 1},
+		{"{start_of_verse label=\"Verse 2\"}\n", 1},
+		// a chord in brackets on its own is an inline chord, but a section header isn't.
+		{"[G]\nAmazing grace\n", 0.5},
+		{"[Chorus]\n[G]Amazing grace\n", 0.5},
+		{"[Chorus]\n", 0},
+		{"[not a chord] [G\nAmazing grace\n", 0},
+		{"{}\n{: value}\n", 0},
+		{"#title=Grace\n#key=G\n\nG      C\nAmazing grace\n", 0},
+		{"Amazing grace\n\n\n", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		if actual := Detect([]byte(test.sample)); actual != test.expected {
+			t.Errorf("Detect(%q) = %v, expected %v", test.sample, actual, test.expected)
+		}
+	}
+}
@@ -13,32 +13,74 @@ func init() {
 		Name:       "chordpro",
 		Reader:     rw,
 		Writer:     rw,
+		Detector:   rw,
 		Extensions: []string{".cho", ".chordpro", ".chopro"},
 	}
 
 	format.Register(f)
 }
 
-type cpReaderWriter struct{}
+type cpReaderWriter struct {
+	opts WriteOptions
+}
 
 func (cprw *cpReaderWriter) Read(r io.Reader) (*songtools.Song, error) {
 	return ParseSong(r)
 }
 
+func (cprw *cpReaderWriter) ReadSet(r io.Reader) (*songtools.SongSet, error) {
+	return ParseSongSet(r)
+}
+
+func (cprw *cpReaderWriter) ReadSetLenient(r io.Reader) (*songtools.SongSet, format.Diagnostics, error) {
+	return ParseSongSetLenient(r)
+}
+
+func (cprw *cpReaderWriter) Detect(sample []byte) float64 {
+	return Detect(sample)
+}
+
 func (cprw *cpReaderWriter) Write(w io.Writer, s *songtools.Song) error {
-	return WriteSong(w, s)
+	return WriteSongWithOptions(w, s, &cprw.opts)
+}
+
+func (cprw *cpReaderWriter) WriteSet(w io.Writer, s *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, s, &cprw.opts)
+}
+
+func (cprw *cpReaderWriter) WithOptions(opts format.Options) (format.Writer, error) {
+	if err := opts.Check(lyricsOption); err != nil {
+		return nil, err
+	}
+
+	lyrics, err := opts.Bool(lyricsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cpReaderWriter{
+		opts: WriteOptions{
+			Lyrics: lyrics,
+		},
+	}, nil
 }
 
 const (
-	titleDirectiveName         = "title"
-	subtitleDirectiveName      = "subtitle"
-	keyDirectiveName           = "key"
-	authorDirectiveName        = "author"
-	startOfSectionPrefix       = "start_of_"
-	endOfSectionPrefix         = "end_of_"
-	startOfChorusDirectiveName = "start_of_chorus"
-	endOfChorusDirectiveName   = "end_of_chorus"
-	startOfBridgeDirectiveName = "start_of_bridge"
-	endOfBridgeDirectiveName   = "end_of_bridge"
-	commentDirectiveName       = "comment"
+	titleDirectiveName       = "title"
+	subtitleDirectiveName    = "subtitle"
+	keyDirectiveName         = "key"
+	authorDirectiveName      = "author"
+	capoDirectiveName        = "capo"
+	defineDirectiveName      = "define"
+	chordDirectiveName       = "chord"
+	startOfSectionPrefix     = "start_of_"
+	endOfSectionPrefix       = "end_of_"
+	startOfTabDirectiveName  = "start_of_tab"
+	endOfTabDirectiveName    = "end_of_tab"
+	startOfGridDirectiveName = "start_of_grid"
+	endOfGridDirectiveName   = "end_of_grid"
+	commentDirectiveName     = "comment"
+	newSongDirectiveName     = "new_song"
+
+	lyricsOption = "lyrics"
 )
@@ -3,13 +3,35 @@ package chordpro
 import (
 	"fmt"
 	"io"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
 )
 
-// ParseSong the src to create a songtools.Song.
+// ParseSong the src to create a songtools.Song. It is an error for the src
+// to contain more than one song.
 func ParseSong(src io.Reader) (*songtools.Song, error) {
+	set, err := ParseSongSet(src)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(set.Songs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return set.Songs[0], nil
+	default:
+		return nil, fmt.Errorf("expected a single song, but found %d", len(set.Songs))
+	}
+}
+
+// ParseSongSet parses the src to create a songtools.SongSet. Songs are
+// separated with the new_song directive.
+func ParseSongSet(src io.Reader) (*songtools.SongSet, error) {
 	scanner, err := newScanner(src)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a scanner: %v", err)
@@ -22,30 +44,53 @@ func ParseSong(src io.Reader) (*songtools.Song, error) {
 	return parser.parse()
 }
 
+// ParseSongSetLenient parses the src to create a songtools.SongSet, continuing past any
+// problems. Chords that cannot be understood are kept as unknown chords and unterminated
+// chords and directives are kept as text. Every problem is reported as a diagnostic.
+func ParseSongSetLenient(src io.Reader) (*songtools.SongSet, format.Diagnostics, error) {
+	scanner, err := newScanner(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create a scanner: %v", err)
+	}
+
+	parser := &parser{
+		scanner: scanner,
+		lenient: true,
+	}
+
+	set, err := parser.parse()
+	return set, parser.diagnostics, err
+}
+
 // Parser produces songs from text.
 type parser struct {
 
 	// current token and text
 	scanner *scanner
+
+	lenient     bool
+	diagnostics format.Diagnostics
 }
 
-func (p *parser) parse() (*songtools.Song, error) {
+func (p *parser) parse() (*songtools.SongSet, error) {
 	token, _, err := p.scanner.la(0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to consume initial token: %v", err)
+	if err != nil && !p.lenient {
+		return nil, err
 	}
 	if token == eofToken {
-		return nil, nil
+		return &songtools.SongSet{}, nil
 	}
 
-	return p.parseSong()
+	return p.parseSongSet()
 }
 
-func (p *parser) parseSong() (*songtools.Song, error) {
+func (p *parser) parseSongSet() (*songtools.SongSet, error) {
 
+	set := &songtools.SongSet{}
 	song := &songtools.Song{}
+	set.Songs = append(set.Songs, song)
 
-	token, text, err := p.scanner.next()
+	token, text, err := p.next()
 	if err != nil {
 		return nil, err
 	}
@@ -60,33 +105,30 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 			comment := &songtools.Comment{
 				Text:   text,
 				Hidden: true,
+				Span:   p.scanner.span(),
 			}
 			if section != nil {
 				section.Nodes = append(section.Nodes, comment)
+				p.extend(section.Span)
 			} else {
 				song.Nodes = append(song.Nodes, comment)
 			}
 		case directiveToken:
 			d, err := parseDirective(text)
 			if err != nil {
-				return nil, err
+				return nil, p.scanner.errorf(p.scanner.start, "%v", err)
 			}
+			d.Span = p.scanner.span()
 
 			switch d.Name {
-			case startOfChorusDirectiveName:
-				section = &songtools.Section{
-					Kind: songtools.SectionKind("Chorus"),
+			case newSongDirectiveName:
+				if song.Title != "" || len(song.Nodes) > 0 {
+					song = &songtools.Song{}
+					set.Songs = append(set.Songs, song)
 				}
-				song.Nodes = append(song.Nodes, section)
-			case endOfChorusDirectiveName:
-				section = nil
-			case startOfBridgeDirectiveName:
-				section = &songtools.Section{
-					Kind: songtools.SectionKind("Bridge"),
-				}
-				song.Nodes = append(song.Nodes, section)
-			case endOfBridgeDirectiveName:
 				section = nil
+				line = nil
+				numNewLines = 0
 			case commentDirectiveName:
 
 				if section == nil {
@@ -109,6 +151,7 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 						} else if nextToken == chordToken || nextToken == textToken {
 							section = &songtools.Section{
 								Kind: songtools.SectionKind(d.Value),
+								Span: p.scanner.span(),
 							}
 							song.Nodes = append(song.Nodes, section)
 							break
@@ -120,11 +163,13 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 					comment := &songtools.Comment{
 						Text:   d.Value,
 						Hidden: false,
+						Span:   d.Span,
 					}
 					if section == nil {
 						song.Nodes = append(song.Nodes, comment)
 					} else {
 						section.Nodes = append(section.Nodes, comment)
+						p.extend(section.Span)
 					}
 				}
 			case titleDirectiveName:
@@ -134,16 +179,80 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 			case authorDirectiveName:
 				song.Authors = append(song.Authors, d.Value)
 			case keyDirectiveName:
-				song.Key = songtools.Key(d.Value)
+				key, err := songtools.ParseKey(d.Value)
+				if err != nil {
+					p.warn(p.scanner.errorf(p.scanner.start, "%v", err))
+					p.keep(song, section, d)
+					line = nil
+					numNewLines = 0
+					break
+				}
+				if len(song.Chords()) == 0 {
+					song.Key = key
+					break
+				}
+
+				// a key after the song has chords is a key change.
+				change := &songtools.KeyChange{
+					Key:  key,
+					Span: d.Span,
+				}
+				if section != nil {
+					section.Nodes = append(section.Nodes, change)
+					p.extend(section.Span)
+				} else {
+					song.Nodes = append(song.Nodes, change)
+				}
+				line = nil
+				numNewLines = 0
+			case capoDirectiveName:
+				capo, err := strconv.Atoi(strings.TrimSpace(d.Value))
+				if err != nil || capo < 0 {
+					p.warn(p.scanner.errorf(p.scanner.start, "The capo '%v' is not a fret number.", strings.TrimSpace(d.Value)))
+					p.keep(song, section, d)
+					break
+				}
+				song.Capo = capo
+			case defineDirectiveName, chordDirectiveName:
+				def, err := songtools.ParseChordDefinition(d.Value)
+				if err != nil {
+					p.warn(p.scanner.errorf(p.scanner.start, "%v", err))
+					p.keep(song, section, d)
+					break
+				}
+				def.Display = d.Name == chordDirectiveName
+				song.Definitions = append(song.Definitions, def)
 			default:
-				// choruses and bridges have end tags, which means we can just wait until those show up
+				if strings.HasPrefix(d.Name, startOfSectionPrefix) {
+					section = &songtools.Section{
+						Kind:      environmentKind(strings.TrimPrefix(d.Name, startOfSectionPrefix)),
+						Label:     parseLabel(d.Name, d.Value),
+						Delimited: true,
+						Span:      p.scanner.span(),
+					}
+					song.Nodes = append(song.Nodes, section)
+					line = nil
+					numNewLines = 0
+					break
+				}
+				if strings.HasPrefix(d.Name, endOfSectionPrefix) {
+					if section != nil {
+						p.extend(section.Span)
+					}
+					section = nil
+					line = nil
+					break
+				}
+
+				// environments have end tags, which means we can just wait until those show up
 				// and not have to guess at the end of a section.
-				if section != nil && numNewLines == 2 && section.Kind != "Chorus" && section.Kind != "Bridge" {
+				if section != nil && numNewLines == 2 && !section.Delimited {
 					section = nil
 				}
 
 				if section != nil {
 					section.Nodes = append(section.Nodes, d)
+					p.extend(section.Span)
 				} else {
 					song.Nodes = append(song.Nodes, d)
 				}
@@ -152,41 +261,99 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 			}
 		case chordToken:
 			if section == nil {
-				section = &songtools.Section{}
+				section = &songtools.Section{
+					Span: p.scanner.span(),
+				}
 				song.Nodes = append(song.Nodes, section)
 			}
 
 			if line == nil {
-				line = &songtools.Line{}
+				line = &songtools.Line{
+					Span: p.scanner.span(),
+				}
 				section.Nodes = append(section.Nodes, line)
 			}
 
 			chord, ok := songtools.ParseChord(text)
 			if !ok {
-				return nil, fmt.Errorf("The text '%v' is not a chord.", text)
+				err = p.report(format.SeverityWarning, p.scanner.errorf(p.scanner.start+1, "The text '%v' is not a chord.", text))
+				if err != nil {
+					return nil, err
+				}
+				chord = songtools.UnknownChord(text)
 			}
 
+			p.extend(line.Span)
+			p.extend(section.Span)
+
 			line.Chords = append(line.Chords, chord)
 			line.ChordPositions = append(line.ChordPositions, len(line.Text))
 
 		case textToken:
+			if section != nil && section.Delimited && section.Kind == "Grid" && line == nil {
+				if row, ok := songtools.ParseGridRow(text); ok {
+					// consecutive rows make up a single grid.
+					var grid *songtools.Grid
+					if n := len(section.Nodes); n > 0 {
+						grid, _ = section.Nodes[n-1].(*songtools.Grid)
+					}
+					if grid == nil {
+						grid = &songtools.Grid{
+							Span: p.scanner.span(),
+						}
+						section.Nodes = append(section.Nodes, grid)
+					}
+					grid.Rows = append(grid.Rows, row)
+					p.extend(grid.Span)
+					p.extend(section.Span)
+					numNewLines = 0
+					break
+				}
+			}
+
 			if section == nil {
-				section = &songtools.Section{}
+				section = &songtools.Section{
+					Span: p.scanner.span(),
+				}
 				song.Nodes = append(song.Nodes, section)
 			}
 
 			if line == nil {
-				line = &songtools.Line{}
+				line = &songtools.Line{
+					Span: p.scanner.span(),
+				}
 				section.Nodes = append(section.Nodes, line)
 			}
 
 			line.Text += text
+			p.extend(line.Span)
+			p.extend(section.Span)
 
+			numNewLines = 0
+		case tabToken:
+			if text == "" {
+				break
+			}
+
+			if section == nil {
+				section = &songtools.Section{
+					Kind: songtools.SectionKind("Tab"),
+					Span: p.scanner.span(),
+				}
+				song.Nodes = append(song.Nodes, section)
+			}
+
+			section.Nodes = append(section.Nodes, &songtools.Tab{
+				Lines: strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n"),
+				Span:  p.scanner.span(),
+			})
+			p.extend(section.Span)
+			line = nil
 			numNewLines = 0
 		case newLineToken:
 			line = nil
 			numNewLines++
-			if section != nil && numNewLines == 2 && section.Kind != "Chorus" && section.Kind != "Bridge" {
+			if section != nil && numNewLines == 2 && !section.Delimited {
 				section = nil
 				numNewLines = 0
 			}
@@ -194,13 +361,65 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 			break
 		}
 
-		token, text, err = p.scanner.next()
+		token, text, err = p.next()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return song, nil
+	return set, nil
+}
+
+// Next returns the next token. When parsing leniently, problems found by
+// the scanner are reported and the best-effort token is returned.
+func (p *parser) next() (token, string, error) {
+	token, text, err := p.scanner.next()
+	if err != nil {
+		err = p.report(format.SeverityError, err)
+	}
+
+	return token, text, err
+}
+
+// Keep adds the directive as it was written, to the section when there is one, so that a
+// directive that couldn't be understood isn't lost.
+func (p *parser) keep(song *songtools.Song, section *songtools.Section, d *songtools.Directive) {
+	if section != nil {
+		section.Nodes = append(section.Nodes, d)
+		p.extend(section.Span)
+	} else {
+		song.Nodes = append(song.Nodes, d)
+	}
+}
+
+// Warn records the problem as a warning when parsing leniently. It is for content that is
+// kept as it was written, so it never stops a strict parse.
+func (p *parser) warn(err error) {
+	if p.lenient {
+		p.report(format.SeverityWarning, err)
+	}
+}
+
+// Report records the problem as a diagnostic when parsing leniently. Otherwise, the
+// problem is returned as an error.
+func (p *parser) report(severity format.Severity, err error) error {
+	perr, ok := err.(*format.ParseError)
+	if !p.lenient || !ok {
+		return err
+	}
+
+	p.diagnostics = append(p.diagnostics, &format.Diagnostic{
+		ParseError: *perr,
+		Severity:   severity,
+	})
+	return nil
+}
+
+// Extend moves the end of the span to the end of the current token.
+func (p *parser) extend(span *songtools.Span) {
+	if span != nil {
+		span.End = p.scanner.span().End
+	}
 }
 
 func parseDirective(text string) (*songtools.Directive, error) {
@@ -210,7 +429,15 @@ func parseDirective(text string) (*songtools.Directive, error) {
 		return nil, fmt.Errorf("directives must either have no value or have a value separated by a ':': %v", text)
 	}
 
-	name := strings.ToLower(parts[0])
+	name, value := parts[0], ""
+	if len(parts) > 1 {
+		value = parts[1]
+	} else if idx := strings.IndexAny(name, " \t"); idx != -1 {
+		// attributes may follow the name, as in {start_of_verse label="Verse 2"}.
+		name, value = name[:idx], strings.TrimSpace(name[idx+1:])
+	}
+
+	name = strings.ToLower(name)
 	switch name {
 	case "t":
 		name = titleDirectiveName
@@ -228,11 +455,20 @@ func parseDirective(text string) (*songtools.Directive, error) {
 		name = "start_of_bridge"
 	case "eob":
 		name = "end_of_bridge"
-	}
-
-	value := ""
-	if len(parts) > 1 {
-		value = parts[1]
+	case "sov":
+		name = "start_of_verse"
+	case "eov":
+		name = "end_of_verse"
+	case "sot":
+		name = startOfTabDirectiveName
+	case "eot":
+		name = endOfTabDirectiveName
+	case "sog":
+		name = startOfGridDirectiveName
+	case "eog":
+		name = endOfGridDirectiveName
+	case "ns":
+		name = newSongDirectiveName
 	}
 
 	return &songtools.Directive{
@@ -240,3 +476,75 @@ func parseDirective(text string) (*songtools.Directive, error) {
 		Value: value,
 	}, nil
 }
+
+// environmentKind turns the name of an environment, such as pre_chorus, into the kind of
+// section it starts, such as Pre Chorus.
+func environmentKind(name string) songtools.SectionKind {
+	words := strings.Split(name, "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+
+	return songtools.SectionKind(strings.Join(words, " "))
+}
+
+var (
+	// attribute matches an attribute of a start directive, such as label="Verse 2", with its
+	// value in double quotes, single quotes or none at all.
+	attribute = regexp.MustCompile(`([A-Za-z_][\w-]*)\s*=\s*("(?:[^"\\]|\\.)*"|'[^']*'|[^\s"']+)`)
+	// gridShape matches the shape of a grid, such as 1+4x2+4, which was given without a name
+	// before there were attributes.
+	gridShape = regexp.MustCompile(`^\d+(?:\+\d+)?(?:x\d+)?(?:\+\d+)?$`)
+)
+
+// parseLabel gets the label of an environment from the value of its start directive. The
+// value is either the label itself or a list of attributes, such as label="Verse 2". Only the
+// label attribute is used. The others, such as the shape of a grid, are ignored, since the
+// layout of a section comes from its own lines. A grid's shape without a name is not a label.
+func parseLabel(name, value string) string {
+	value = strings.TrimSpace(value)
+	attributes, ok := parseAttributes(value)
+	if !ok {
+		if name == startOfGridDirectiveName && gridShape.MatchString(value) {
+			return ""
+		}
+
+		return value
+	}
+
+	return attributes["label"]
+}
+
+// parseAttributes gets the attributes in the value of a directive, or indicates the value is
+// not a list of attributes.
+func parseAttributes(value string) (map[string]string, bool) {
+	matches := attribute.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return nil, false
+	}
+
+	attributes := map[string]string{}
+	last := 0
+	for _, m := range matches {
+		if strings.TrimSpace(value[last:m[0]]) != "" {
+			return nil, false
+		}
+
+		attributeValue := value[m[4]:m[5]]
+		if unquoted, err := strconv.Unquote(attributeValue); err == nil {
+			attributeValue = unquoted
+		} else {
+			attributeValue = strings.Trim(attributeValue, "'\"")
+		}
+		attributes[strings.ToLower(value[m[2]:m[3]])] = attributeValue
+		last = m[1]
+	}
+
+	if strings.TrimSpace(value[last:]) != "" {
+		return nil, false
+	}
+
+	return attributes, true
+}
@@ -0,0 +1,287 @@
+package chordpro
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestParseSongSetLenientKeepsUnknownKeys(t *testing.T) {
+	tests := []string{
+		"{title: One}\n{key: H#}\n[C]Hello\n",
+		"{title: One}\n{key: C}\n[C]Hello\n{key: H#}\n[C]Bye\n",
+	}
+
+	for _, src := range tests {
+		set, diags, err := ParseSongSetLenient(strings.NewReader(src))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", src, err)
+			continue
+		}
+		if len(diags) != 1 {
+			t.Errorf("%q: expected 1 diagnostic, but got %d", src, len(diags))
+		}
+
+		var b bytes.Buffer
+		if err := WriteSongSet(&b, set); err != nil {
+			t.Errorf("%q: unexpected error: %v", src, err)
+			continue
+		}
+		if !strings.Contains(b.String(), "{key:H#}") && !strings.Contains(b.String(), "{key: H#}") {
+			t.Errorf("%q: expected the key to be written, but got %q", src, b.String())
+		}
+	}
+}
+
+func TestParseSongKeepsUnknownKeys(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"{title: One}\n{key: G (capo 2)}\n[C]Hello\n", "{key: G (capo 2)}"},
+		{"{title: One}\n{key: C}\n[C]Hello\n{key: H#}\n[C]Bye\n", "{key: H#}"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+
+		var b bytes.Buffer
+		if err := WriteSong(&b, s); err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+		if !strings.Contains(b.String(), test.expected) {
+			t.Errorf("%q: expected %q to be written, but got %q", test.text, test.expected, b.String())
+		}
+	}
+}
+
+func TestParseSongKeepsUnreadableDirectives(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"{title: One}\n{capo: x}\n[C]Hello\n", "{capo: x}"},
+		{"{title: One}\n{define: C keys 0 4 7}\n[C]Hello\n", "{define: C keys 0 4 7}"},
+		{"{title: One}\n{define: C7 copy C}\n[C7]Hello\n", "{define: C7 copy C}"},
+		{"{title: One}\n{chord: C display \"C major\"}\n[C]Hello\n", "{chord: C display \"C major\"}"},
+		{"{title: One}\n{capo: -1}\n[C]Hello\n", "{capo: -1}"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+
+		var b bytes.Buffer
+		if err := WriteSong(&b, s); err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+		if !strings.Contains(b.String(), test.expected) {
+			t.Errorf("%q: expected %q to be written, but got %q", test.text, test.expected, b.String())
+		}
+	}
+}
+
+func TestParseSongKeepsKeyChanges(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"{key: C}\n[C]Hello [G]there\n{key: D}\n[D]Bye\n", "C C D"},
+		{"{key: C}\n{start_of_chorus}\n[C]Hello\n{key: D}\n[D]Bye\n{end_of_chorus}\n", "C D"},
+		// a key before any chords is the song's key rather than a change.
+		{"{key: C}\n{key: D}\n[D]Hello\n", "D"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+
+		keys := []string{}
+		for _, kc := range s.KeyedChords() {
+			keys = append(keys, kc.Key.String())
+		}
+		if actual := strings.Join(keys, " "); actual != test.expected {
+			t.Errorf("%q: expected the chords to be in %q, but got %q", test.text, test.expected, actual)
+		}
+	}
+}
+
+func TestParseSongKeepsKeyChangesInTheSection(t *testing.T) {
+	src := "[G]One\n{key: A}\n[A]Two\n"
+	s, err := ParseSong(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(s.Nodes) != 1 {
+		t.Fatalf("expected 1 section, but got %d nodes", len(s.Nodes))
+	}
+	section := s.Nodes[0].(*songtools.Section)
+	if len(section.Nodes) != 3 {
+		t.Fatalf("expected 2 lines and a key change, but got %d nodes", len(section.Nodes))
+	}
+	if change, ok := section.Nodes[1].(*songtools.KeyChange); !ok || change.Key.String() != "A" {
+		t.Errorf("expected a key change to A between the lines, but got %#v", section.Nodes[1])
+	}
+
+	var b bytes.Buffer
+	if err := WriteSong(&b, s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "[G]One\n{key:A}\n[A]Two\n"; !strings.HasPrefix(b.String(), expected) {
+		t.Errorf("expected %q to be written, but got %q", expected, b.String())
+	}
+}
+
+func TestParseLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"start_of_verse", "", ""},
+		{"start_of_verse", "Verse 1", "Verse 1"},
+		{"start_of_verse", " Verse 1 ", "Verse 1"},
+		{"start_of_verse", `label="Verse 1"`, "Verse 1"},
+		{"start_of_verse", `label='Verse 1'`, "Verse 1"},
+		{"start_of_verse", `label=Intro`, "Intro"},
+		{"start_of_verse", `label="Verse 1" class="x"`, "Verse 1"},
+		{"start_of_verse", `class="x" label="Verse \"1\""`, `Verse "1"`},
+		{"start_of_verse", `class="x"`, ""},
+		{"start_of_verse", `1 + 1 = 2`, "1 + 1 = 2"},
+		{"start_of_grid", `shape="1+4x2+4"`, ""},
+		{"start_of_grid", `label="Intro" shape="1+4x2+4"`, "Intro"},
+		{"start_of_grid", `1+4x2+4`, ""},
+		{"start_of_grid", `4x4`, ""},
+		{"start_of_grid", `Intro`, "Intro"},
+	}
+
+	for _, test := range tests {
+		if actual := parseLabel(test.name, test.value); actual != test.expected {
+			t.Errorf("parseLabel(%q, %q) = %q, expected %q", test.name, test.value, actual, test.expected)
+		}
+	}
+}
+
+func TestParseSongSectionLabels(t *testing.T) {
+	src := "{start_of_verse: label=\"Verse 1\" class=\"x\"}\n[C]Hello\n{end_of_verse}\n{start_of_grid shape=\"1+4x2+4\"}\n| C . . . |\n{end_of_grid}\n"
+	s, err := ParseSong(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"Verse 1", ""}
+	if len(s.Nodes) != len(expected) {
+		t.Fatalf("expected %d sections, but got %d", len(expected), len(s.Nodes))
+	}
+	for i, n := range s.Nodes {
+		if label := n.(*songtools.Section).Label; label != expected[i] {
+			t.Errorf("expected section %d to have the label %q, but got %q", i+1, expected[i], label)
+		}
+	}
+}
+
+func TestParseSongLyrics(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Hal-[C]le-lu-[G]jah", "Hallelujah"},
+		{"Lord - [G]you are my strength", "Lord - you are my strength"},
+		{"A - [C]ma - [G]zing grace", "A - ma - zing grace"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text + "\n"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		lyrics := songtools.LyricsOnly(s)
+		l := lyrics.Nodes[0].(*songtools.Section).Nodes[0].(*songtools.Line)
+		if l.Text != test.expected {
+			t.Errorf("lyrics of %q = %q, expected %q", test.text, l.Text, test.expected)
+		}
+	}
+}
+
+func TestParseSongTabs(t *testing.T) {
+	tests := []struct {
+		text     string
+		label    string
+		expected []string
+	}{
+		{"{start_of_tab}\ne|--0--|\nB|--1--|\n{end_of_tab}\n", "", []string{"e|--0--|", "B|--1--|"}},
+		{"{sot}\ne|--0--|\n  B|--1--|  \n{eot}\n", "", []string{"e|--0--|", "  B|--1--|  "}},
+		{"{start_of_tab: label=\"Riff\"}\ne|--0--|\n{end_of_tab}\n", "Riff", []string{"e|--0--|"}},
+		// brackets, braces and hashes in tab aren't chords, directives or comments.
+		{"{sot}\ne|--[0]--|\n# {x} |--2--|\n{eot}\n", "", []string{"e|--[0]--|", "# {x} |--2--|"}},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+
+		section := s.Nodes[0].(*songtools.Section)
+		if section.Kind != "Tab" || section.Label != test.label {
+			t.Errorf("%q: expected a tab section labeled %q, but got %q labeled %q", test.text, test.label, section.Kind, section.Label)
+		}
+		if len(section.Nodes) != 1 {
+			t.Errorf("%q: expected 1 tab, but got %d nodes", test.text, len(section.Nodes))
+			continue
+		}
+		tab, ok := section.Nodes[0].(*songtools.Tab)
+		if !ok {
+			t.Errorf("%q: expected a tab, but got %T", test.text, section.Nodes[0])
+			continue
+		}
+		if strings.Join(tab.Lines, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%q: expected the tab %q, but got %q", test.text, test.expected, tab.Lines)
+		}
+	}
+}
+
+func TestParseSongSetSplitsSongs(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"{title:One}\n[G]la\n", []string{"One"}},
+		{"{title:One}\n[G]la\n{new_song}\n{title:Two}\n[C]la\n", []string{"One", "Two"}},
+		{"{title:One}\n{ns}\n{t:Two}\n{ns}\n{title:Three}\n", []string{"One", "Two", "Three"}},
+		// a song without anything in it is left out.
+		{"{title:One}\n{new_song}\n{new_song}\n{title:Three}\n", []string{"One", "Three"}},
+	}
+
+	for _, test := range tests {
+		set, err := ParseSongSet(strings.NewReader(test.text))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		titles := []string{}
+		for _, s := range set.Songs {
+			titles = append(titles, s.Title)
+		}
+		if strings.Join(titles, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("ParseSongSet(%q) found %q, expected %q", test.text, titles, test.expected)
+		}
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
 )
 
 type token int
@@ -15,6 +19,7 @@ const (
 	commentToken
 	directiveToken
 	chordToken
+	tabToken
 )
 
 func (t token) String() string {
@@ -31,6 +36,8 @@ func (t token) String() string {
 		return "<directive>"
 	case chordToken:
 		return "<chordToken>"
+	case tabToken:
+		return "<tab>"
 	default:
 		return "<unknown>"
 	}
@@ -38,14 +45,23 @@ func (t token) String() string {
 
 // Scanner produces tokens from text.
 type scanner struct {
-	src string
-	pos int
+	src    string
+	source *format.Source
+	pos    int
+
+	// start and end of the most recently returned token
+	start int
+	end   int
+
+	// tab indicates the scanner is inside a tab environment, where lines are not tokenized.
+	tab bool
 
 	peeks []peek
 }
 
 type peek struct {
 	pos   int
+	end   int
 	token token
 	text  string
 	err   error
@@ -58,7 +74,7 @@ func newScanner(src io.Reader) (*scanner, error) {
 		return nil, fmt.Errorf("unable to create a new scanner: %v", err)
 	}
 
-	s := &scanner{src: string(b), pos: 0}
+	s := &scanner{src: string(b), source: format.NewSource(string(b)), pos: 0}
 	return s, nil
 }
 
@@ -67,7 +83,7 @@ func (s *scanner) la(count int) (token, string, error) {
 	for len(s.peeks) <= count {
 		nextPos := s.pos
 		nextToken, nextText, nextErr := s._internalNext()
-		s.peeks = append(s.peeks, peek{nextPos, nextToken, nextText, nextErr})
+		s.peeks = append(s.peeks, peek{nextPos, s.pos, nextToken, nextText, nextErr})
 	}
 
 	peek := s.peeks[count]
@@ -80,13 +96,31 @@ func (s *scanner) next() (token, string, error) {
 	if len(s.peeks) > 0 {
 		peek := s.peeks[0]
 		s.peeks = s.peeks[1:]
+		s.start, s.end = peek.pos, peek.end
 		return peek.token, peek.text, peek.err
 	}
 
-	return s._internalNext()
+	s.start = s.pos
+	token, text, err := s._internalNext()
+	s.end = s.pos
+	return token, text, err
+}
+
+// Span returns the span of the most recently returned token.
+func (s *scanner) span() *songtools.Span {
+	return s.source.Span(s.start, s.end)
+}
+
+// Errorf creates a ParseError at the offset.
+func (s *scanner) errorf(offset int, f string, args ...interface{}) error {
+	return s.source.Error(offset, fmt.Sprintf(f, args...))
 }
 
 func (s *scanner) _internalNext() (token, string, error) {
+	if s.tab && s.pos < len(s.src) && (s.pos == 0 || s.src[s.pos-1] == '\n') {
+		return s.scanTab()
+	}
+
 	if s.pos < len(s.src) {
 		switch s.src[s.pos] {
 		case '\r':
@@ -108,18 +142,20 @@ func (s *scanner) _internalNext() (token, string, error) {
 }
 
 func (s *scanner) scanChord() (token, string, error) {
+	open := s.pos
 	start := s.pos + 1
-	for s.pos < len(s.src) {
+	for s.pos+1 < len(s.src) {
 		s.pos++
-		if s.src[s.pos] == ']' {
+		switch s.src[s.pos] {
+		case ']':
 			s.pos++
 			return chordToken, s.src[start : s.pos-1], nil
+		case '\r', '\n':
+			return s.scanUnterminated(open, "Expected ']', but found the end of the line")
 		}
 	}
-	if s.pos == len(s.src) {
-		return eofToken, "", fmt.Errorf("Expected ']', but found Eof")
-	}
-	return eofToken, "", nil
+
+	return s.scanUnterminated(open, "Expected ']', but found Eof")
 }
 
 func (s *scanner) scanComment() (token, string, error) {
@@ -135,18 +171,69 @@ func (s *scanner) scanComment() (token, string, error) {
 }
 
 func (s *scanner) scanDirective() (token, string, error) {
+	open := s.pos
 	start := s.pos + 1
-	for s.pos < len(s.src) {
+	for s.pos+1 < len(s.src) {
 		s.pos++
-		if s.src[s.pos] == '}' {
+		switch s.src[s.pos] {
+		case '}':
 			s.pos++
-			return directiveToken, s.src[start : s.pos-1], nil
+			text := s.src[start : s.pos-1]
+			if d, err := parseDirective(text); err == nil && d.Name == startOfTabDirectiveName {
+				s.tab = true
+			}
+			return directiveToken, text, nil
+		case '\r', '\n':
+			return s.scanUnterminated(open, "Expected '}', but found the end of the line")
+		}
+	}
+
+	return s.scanUnterminated(open, "Expected '}', but found Eof")
+}
+
+// ScanUnterminated rescans everything from the open bracket to the end of the line as text
+// and returns it along with an error so that a lenient parser can continue.
+func (s *scanner) scanUnterminated(open int, msg string) (token, string, error) {
+	s.pos = open
+	for s.pos < len(s.src) {
+		if s.src[s.pos] == '\r' || s.src[s.pos] == '\n' {
+			break
+		}
+		s.pos++
+	}
+
+	return textToken, s.src[open:s.pos], s.errorf(open, "%v", msg)
+}
+
+// ScanTab scans the lines of a tab environment verbatim, up to the line that ends it.
+func (s *scanner) scanTab() (token, string, error) {
+	s.tab = false
+	start := s.pos
+	for s.pos < len(s.src) {
+		end := strings.IndexByte(s.src[s.pos:], '\n')
+		if end == -1 {
+			end = len(s.src)
+		} else {
+			end += s.pos + 1
+		}
+
+		if isEndOfTab(s.src[s.pos:end]) {
+			return tabToken, strings.TrimRight(s.src[start:s.pos], "\r\n"), nil
 		}
+		s.pos = end
 	}
-	if s.pos == len(s.src) {
-		return eofToken, "", fmt.Errorf("Expected '}', but found Eof")
+
+	return tabToken, strings.TrimRight(s.src[start:s.pos], "\r\n"), s.errorf(start, "Expected '{%v}', but found Eof", endOfTabDirectiveName)
+}
+
+func isEndOfTab(line string) bool {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}") {
+		return false
 	}
-	return eofToken, "", nil
+
+	d, err := parseDirective(line[1 : len(line)-1])
+	return err == nil && d.Name == endOfTabDirectiveName
 }
 
 func (s *scanner) scanNewLine() (token, string, error) {
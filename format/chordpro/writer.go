@@ -3,13 +3,53 @@ package chordpro
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/songtools/songtools"
 )
 
+// WriteOptions control how a song is written.
+type WriteOptions struct {
+	// Lyrics writes just the words of the song, without its chords.
+	Lyrics bool
+}
+
+// WriteSongSet writes all the songs in the set to the writer, separated
+// by the new_song directive.
+func WriteSongSet(w io.Writer, set *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, set, &WriteOptions{})
+}
+
+// WriteSongSetWithOptions writes all the songs in the set to the writer using the options.
+func WriteSongSetWithOptions(w io.Writer, set *songtools.SongSet, opts *WriteOptions) error {
+	for i, s := range set.Songs {
+		if i > 0 {
+			err := writeDirective(w, newSongDirectiveName, "")
+			if err != nil {
+				return err
+			}
+		}
+
+		err := WriteSongWithOptions(w, s, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // WriteSong writes a single song to the writer.
 func WriteSong(w io.Writer, s *songtools.Song) error {
+	return WriteSongWithOptions(w, s, &WriteOptions{})
+}
+
+// WriteSongWithOptions writes a single song to the writer using the options.
+func WriteSongWithOptions(w io.Writer, s *songtools.Song, opts *WriteOptions) error {
+	if opts.Lyrics {
+		s = songtools.LyricsOnly(s)
+	}
 
 	if s.Title != "" {
 		err := writeDirective(w, titleDirectiveName, s.Title)
@@ -29,8 +69,25 @@ func WriteSong(w io.Writer, s *songtools.Song) error {
 			return err
 		}
 	}
-	if s.Key != "" {
-		err := writeDirective(w, keyDirectiveName, string(s.Key))
+	if !s.Key.IsZero() {
+		err := writeDirective(w, keyDirectiveName, s.Key.String())
+		if err != nil {
+			return err
+		}
+	}
+	if s.Capo > 0 {
+		err := writeDirective(w, capoDirectiveName, strconv.Itoa(s.Capo))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, d := range s.Definitions {
+		name := defineDirectiveName
+		if d.Display {
+			name = chordDirectiveName
+		}
+		err := writeDirective(w, name, d.String())
 		if err != nil {
 			return err
 		}
@@ -52,30 +109,39 @@ func writeSongNode(w io.Writer, n songtools.SongNode) error {
 		return writeComment(w, typedN)
 	case *songtools.Directive:
 		return writeDirective(w, typedN.Name, typedN.Value)
+	case *songtools.KeyChange:
+		return writeDirective(w, keyDirectiveName, typedN.Key.String())
 	case *songtools.Section:
-		if typedN.Kind != "" {
-			switch typedN.Kind {
-			case "Chorus":
-				writeDirective(w, startOfChorusDirectiveName, "")
-			case "Bridge":
-				writeDirective(w, startOfBridgeDirectiveName, "")
-			default:
-				writeDirective(w, commentDirectiveName, string(typedN.Kind))
+		// choruses and bridges are always environments, even when they were read from another format.
+		environment := typedN.Kind != "" && (typedN.Delimited || typedN.Kind == "Chorus" || typedN.Kind == "Bridge")
+		if environment {
+			err := writeDirective(w, startOfSectionPrefix+environmentName(typedN.Kind), typedN.Label)
+			if err != nil {
+				return err
+			}
+		} else if typedN.Kind != "" {
+			err := writeDirective(w, commentDirectiveName, typedN.Heading())
+			if err != nil {
+				return err
 			}
 		}
 
+		var inEnvironment songtools.SectionKind
+		if environment {
+			inEnvironment = typedN.Kind
+		}
 		for _, sn := range typedN.Nodes {
-			err := writeSectionNode(w, sn)
+			err := writeSectionNode(w, sn, inEnvironment)
 			if err != nil {
 				return err
 			}
 		}
 
-		switch typedN.Kind {
-		case "Chorus":
-			writeDirective(w, endOfChorusDirectiveName, "")
-		case "Bridge":
-			writeDirective(w, endOfBridgeDirectiveName, "")
+		if environment {
+			err := writeDirective(w, endOfSectionPrefix+environmentName(typedN.Kind), "")
+			if err != nil {
+				return err
+			}
 		}
 
 		_, err := fmt.Fprintln(w)
@@ -89,12 +155,20 @@ func writeSongNode(w io.Writer, n songtools.SongNode) error {
 	return nil
 }
 
-func writeSectionNode(w io.Writer, n songtools.SectionNode) error {
+// writeSectionNode writes the node of a section. The environment is the kind of environment
+// the section was written as, if any.
+func writeSectionNode(w io.Writer, n songtools.SectionNode, environment songtools.SectionKind) error {
 	switch typedN := n.(type) {
 	case *songtools.Comment:
 		return writeComment(w, typedN)
 	case *songtools.Directive:
 		return writeDirective(w, typedN.Name, typedN.Value)
+	case *songtools.KeyChange:
+		return writeDirective(w, keyDirectiveName, typedN.Key.String())
+	case *songtools.Tab:
+		return writeTab(w, typedN, environment == "Tab")
+	case *songtools.Grid:
+		return writeGrid(w, typedN, environment == "Grid")
 	case *songtools.Line:
 		return writeLine(w, typedN)
 	default:
@@ -102,6 +176,60 @@ func writeSectionNode(w io.Writer, n songtools.SectionNode) error {
 	}
 }
 
+// environmentName is the name of the environment for a kind of section, such as pre_chorus
+// for Pre Chorus.
+func environmentName(kind songtools.SectionKind) string {
+	return strings.ToLower(strings.Replace(string(kind), " ", "_", -1))
+}
+
+// writeTab writes the lines of the tab as they are. Outside of a tab environment, the
+// tab gets one of its own.
+func writeTab(w io.Writer, t *songtools.Tab, inTab bool) error {
+	if !inTab {
+		err := writeDirective(w, startOfTabDirectiveName, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range t.Lines {
+		_, err := fmt.Fprintln(w, l)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !inTab {
+		return writeDirective(w, endOfTabDirectiveName, "")
+	}
+
+	return nil
+}
+
+// writeGrid writes the rows of the grid with their bars lined up. Outside of a grid
+// environment, the grid gets one of its own.
+func writeGrid(w io.Writer, g *songtools.Grid, inGrid bool) error {
+	if !inGrid {
+		err := writeDirective(w, startOfGridDirectiveName, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range g.Lines() {
+		_, err := fmt.Fprintln(w, l)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !inGrid {
+		return writeDirective(w, endOfGridDirectiveName, "")
+	}
+
+	return nil
+}
+
 func writeComment(w io.Writer, c *songtools.Comment) error {
 	if c.Hidden {
 		_, err := fmt.Fprintln(w, fmt.Sprintf("#%v", c.Text))
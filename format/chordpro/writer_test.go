@@ -0,0 +1,74 @@
+package chordpro
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestWriteSongTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		song     *songtools.Song
+		expected string
+	}{
+		{
+			name: "tab environment",
+			song: &songtools.Song{Nodes: []songtools.SongNode{
+				&songtools.Section{Kind: "Tab", Nodes: []songtools.SectionNode{
+					&songtools.Tab{Lines: []string{"e|--[0]--|", "  B|--1--|  "}},
+				}},
+			}},
+			expected: "{start_of_tab}\ne|--[0]--|\n  B|--1--|  \n{end_of_tab}\n",
+		},
+		{
+			name: "tab in a verse",
+			song: &songtools.Song{Nodes: []songtools.SongNode{
+				&songtools.Section{Kind: "Verse", Nodes: []songtools.SectionNode{
+					&songtools.Tab{Lines: []string{"e|--0--|"}},
+				}},
+			}},
+			expected: "{start_of_tab}\ne|--0--|\n{end_of_tab}\n",
+		},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := WriteSong(&b, test.song); err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !strings.Contains(b.String(), test.expected) {
+			t.Errorf("%v: expected %q to be written, but got %q", test.name, test.expected, b.String())
+		}
+
+		// the tab reads back as it was written.
+		s, err := ParseSong(&b)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		expected := test.song.Nodes[0].(*songtools.Section).Nodes[0].(*songtools.Tab).Lines
+		actual := findTab(s)
+		if actual == nil || strings.Join(actual.Lines, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%v: expected the tab %q to read back, but got %v", test.name, expected, actual)
+		}
+	}
+}
+
+// findTab finds the first tab in the song.
+func findTab(s *songtools.Song) *songtools.Tab {
+	for _, n := range s.Nodes {
+		if section, ok := n.(*songtools.Section); ok {
+			for _, sn := range section.Nodes {
+				if tab, ok := sn.(*songtools.Tab); ok {
+					return tab
+				}
+			}
+		}
+	}
+
+	return nil
+}
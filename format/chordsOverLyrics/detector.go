@@ -0,0 +1,61 @@
+package chordsOverLyrics
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+// Detect scores how likely the sample is to be a chordsOverLyrics song. Lines containing
+// directives, such as #key=G, section headers, such as [Chorus], and lines made up
+// entirely of chords count towards the score.
+func Detect(sample []byte) float64 {
+	total := 0
+	hits := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(sample))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		total++
+		if isDirectiveLine(line) || isSectionHeaderLine(line) {
+			hits++
+		} else if _, _, isChordLine := parseTextForChords(line, songtools.ParseChord, false); isChordLine {
+			hits++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(total)
+}
+
+func isDirectiveLine(line string) bool {
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+
+	_, err := parseDirective(line[1:])
+	return err == nil
+}
+
+func isSectionHeaderLine(line string) bool {
+	if !strings.HasPrefix(line, "[") {
+		return false
+	}
+
+	end := strings.Index(line, "]")
+	if end == -1 {
+		return false
+	}
+
+	_, _, isChordLine := parseTextForChords(line[1:end], songtools.ParseChord, false)
+	return !isChordLine
+}
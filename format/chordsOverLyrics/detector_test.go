@@ -0,0 +1,30 @@
+package chordsOverLyrics
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		sample   string
+		expected float64
+	}{
+		{"#title=Grace\n#key=G\n\n[Verse 1]\nG      C\nAmazing grace\n", 0.8},
+		{"A      E\nAmazing grace\n", 0.5},
+		// words that are chords make up a line of chords, but not in a sentence.
+		{"Am I wrong\n", 0},
+		{"A E\n", 1},
+		// a section header names a section, so a chord in brackets isn't one.
+		{"[Chorus]\n", 1},
+		{"[G]\nAmazing grace\n", 0},
+		{"[G]Amazing [C]grace\n", 0},
+		{"# a comment\n#key=G\n", 0.5},
+		{"{title: Grace}\n", 0},
+		{"1    4    5\nAmazing grace\n", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		if actual := Detect([]byte(test.sample)); actual != test.expected {
+			t.Errorf("Detect(%q) = %v, expected %v", test.sample, actual, test.expected)
+		}
+	}
+}
@@ -13,20 +13,62 @@ func init() {
 		Name:       "chordsOverLyrics",
 		Reader:     rw,
 		Writer:     rw,
+		Detector:   rw,
 		Extensions: []string{".txt"},
 	}
 
 	format.Register(f)
 }
 
-type plainReaderWriter struct{}
+type plainReaderWriter struct {
+	opts WriteOptions
+}
 
 func (prw *plainReaderWriter) Read(r io.Reader) (*songtools.Song, error) {
 	return ParseSong(r)
 }
 
+func (prw *plainReaderWriter) ReadSet(r io.Reader) (*songtools.SongSet, error) {
+	return ParseSongSet(r)
+}
+
+func (prw *plainReaderWriter) ReadSetLenient(r io.Reader) (*songtools.SongSet, format.Diagnostics, error) {
+	return ParseSongSetLenient(r)
+}
+
+func (prw *plainReaderWriter) Detect(sample []byte) float64 {
+	return Detect(sample)
+}
+
 func (prw *plainReaderWriter) Write(w io.Writer, s *songtools.Song) error {
-	return WriteSong(w, s)
+	return WriteSongWithOptions(w, s, &prw.opts)
+}
+
+func (prw *plainReaderWriter) WriteSet(w io.Writer, s *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, s, &prw.opts)
+}
+
+func (prw *plainReaderWriter) WithOptions(opts format.Options) (format.Writer, error) {
+	if err := opts.Check(numeralsOption, lyricsOption); err != nil {
+		return nil, err
+	}
+
+	numerals, err := opts.Bool(numeralsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	lyrics, err := opts.Bool(lyricsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plainReaderWriter{
+		opts: WriteOptions{
+			Numerals: numerals,
+			Lyrics:   lyrics,
+		},
+	}, nil
 }
 
 const (
@@ -34,4 +76,10 @@ const (
 	subtitleDirectiveName = "subtitle"
 	keyDirectiveName      = "key"
 	authorDirectiveName   = "author"
+	capoDirectiveName     = "capo"
+	defineDirectiveName   = "define"
+	chordDirectiveName    = "chord"
+
+	numeralsOption = "numerals"
+	lyricsOption   = "lyrics"
 )
@@ -3,49 +3,120 @@ package chordsOverLyrics
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
 )
 
-// ParseSong the src to create a songtools.Song.
+// ParseSong the src to create a songtools.Song. It is an error for the src
+// to contain more than one song.
 func ParseSong(src io.Reader) (*songtools.Song, error) {
+	set, err := ParseSongSet(src)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(set.Songs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return set.Songs[0], nil
+	default:
+		return nil, fmt.Errorf("expected a single song, but found %d", len(set.Songs))
+	}
+}
+
+// ParseSongSet parses the src to create a songtools.SongSet. A title directive
+// following the content of a song begins the next song, along with the block of
+// directives just before it.
+func ParseSongSet(src io.Reader) (*songtools.SongSet, error) {
 	scanner, err := newScanner(src)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a scanner: %v", err)
 	}
 
 	parser := &parser{
-		scanner: scanner,
+		scanner:    scanner,
+		parseChord: songtools.ParseChord,
 	}
 
 	return parser.parse()
 }
 
+// ParseSongSetLenient parses the src to create a songtools.SongSet, continuing past any
+// problems. Lines made up mostly of chords are kept as chord lines, with the names that
+// cannot be understood kept as unknown chords. Every problem is reported as a diagnostic.
+func ParseSongSetLenient(src io.Reader) (*songtools.SongSet, format.Diagnostics, error) {
+	return ParseSongSetWithOptions(src, &ParseOptions{Lenient: true})
+}
+
+// ParseOptions control how a song is parsed.
+type ParseOptions struct {
+	// Lenient continues parsing past problems and reports them as diagnostics.
+	Lenient bool
+	// ParseChord recognizes the chords in a line. When nil, songtools.ParseChord is used.
+	ParseChord func(string) (*songtools.Chord, bool)
+}
+
+// ParseSongSetWithOptions parses the src to create a songtools.SongSet using the options.
+// Diagnostics are only reported when parsing leniently.
+func ParseSongSetWithOptions(src io.Reader, opts *ParseOptions) (*songtools.SongSet, format.Diagnostics, error) {
+	scanner, err := newScanner(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create a scanner: %v", err)
+	}
+
+	parser := &parser{
+		scanner:    scanner,
+		lenient:    opts.Lenient,
+		parseChord: opts.ParseChord,
+	}
+
+	if parser.parseChord == nil {
+		parser.parseChord = songtools.ParseChord
+	}
+
+	set, err := parser.parse()
+	if err != nil {
+		return nil, parser.diagnostics, err
+	}
+
+	return set, parser.diagnostics, nil
+}
+
 // Parser produces songs from text.
 type parser struct {
 
 	// current token and text
 	scanner *scanner
+
+	lenient     bool
+	diagnostics format.Diagnostics
+	parseChord  func(string) (*songtools.Chord, bool)
 }
 
-func (p *parser) parse() (*songtools.Song, error) {
+func (p *parser) parse() (*songtools.SongSet, error) {
 	token, _, err := p.scanner.peek()
-	if err != nil {
-		return nil, fmt.Errorf("failed to consume initial token: %v", err)
+	if err != nil && !p.lenient {
+		return nil, err
 	}
 	if token == eofToken {
-		return nil, nil
+		return &songtools.SongSet{}, nil
 	}
 
-	return p.parseSong()
+	return p.parseSongSet()
 }
 
-func (p *parser) parseSong() (*songtools.Song, error) {
+func (p *parser) parseSongSet() (*songtools.SongSet, error) {
 
+	set := &songtools.SongSet{}
 	song := &songtools.Song{}
+	set.Songs = append(set.Songs, song)
 
-	token, text, err := p.scanner.next()
+	token, text, err := p.next()
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +124,8 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 	var section *songtools.Section
 	var line *songtools.Line
 	numNewLines := 0
+	// header indicates the directives of the next song's header are being read, before its title.
+	header := false
 
 	for token != eofToken {
 		switch token {
@@ -60,24 +133,78 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 			comment := &songtools.Comment{
 				Text:   text,
 				Hidden: false,
+				Span:   p.scanner.span(),
 			}
 			if section != nil {
 				section.Nodes = append(section.Nodes, comment)
+				p.extend(section.Span)
 			} else {
 				song.Nodes = append(song.Nodes, comment)
 			}
 		case directiveToken:
 			d, err := parseDirective(text)
 			if err != nil {
-				return nil, err
+				err = p.report(format.SeverityError, p.scanner.errorf(p.scanner.start, "%v", err))
+				if err != nil {
+					return nil, err
+				}
+				d = &songtools.Directive{
+					Name: strings.ToLower(text),
+				}
+			}
+			d.Span = p.scanner.span()
+
+			hasContent := song.Title != "" || len(song.Nodes) > 0
+			if d.Name == titleDirectiveName && hasContent && !header {
+				// a title after the content of a song is the start of the next song.
+				song = &songtools.Song{}
+				set.Songs = append(set.Songs, song)
+				section = nil
+			} else if d.Name != titleDirectiveName && !header && numNewLines != 1 && p.titleFollows() {
+				// a block of directives ending in a title is the header of the song with the title.
+				if hasContent {
+					song = &songtools.Song{}
+					set.Songs = append(set.Songs, song)
+					section = nil
+				}
+				header = true
+			}
+			if d.Name == titleDirectiveName {
+				header = false
 			}
 
 			if numNewLines == 2 {
 				section = nil
 			}
 
-			if section != nil {
+			if d.Name == keyDirectiveName && len(song.Chords()) > 0 {
+				// a key after the song has chords is a key change.
+				key, err := songtools.ParseKey(d.Value)
+				if err != nil {
+					p.warn(p.scanner.errorf(p.scanner.start, "%v", err))
+
+					// the key is kept as it was written, so it isn't lost.
+					if section != nil {
+						section.Nodes = append(section.Nodes, d)
+						p.extend(section.Span)
+					} else {
+						song.Nodes = append(song.Nodes, d)
+					}
+				} else {
+					change := &songtools.KeyChange{
+						Key:  key,
+						Span: d.Span,
+					}
+					if section != nil {
+						section.Nodes = append(section.Nodes, change)
+						p.extend(section.Span)
+					} else {
+						song.Nodes = append(song.Nodes, change)
+					}
+				}
+			} else if section != nil {
 				section.Nodes = append(section.Nodes, d)
+				p.extend(section.Span)
 			} else {
 				switch d.Name {
 				case titleDirectiveName:
@@ -87,7 +214,36 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 				case authorDirectiveName:
 					song.Authors = append(song.Authors, d.Value)
 				case keyDirectiveName:
-					song.Key = songtools.Key(d.Value)
+					key, err := songtools.ParseKey(d.Value)
+					if err != nil {
+						p.warn(p.scanner.errorf(p.scanner.start, "%v", err))
+
+						// the key is kept as it was written, so it isn't lost.
+						song.Nodes = append(song.Nodes, d)
+						break
+					}
+					song.Key = key
+				case capoDirectiveName:
+					capo, err := strconv.Atoi(strings.TrimSpace(d.Value))
+					if err != nil || capo < 0 {
+						p.warn(p.scanner.errorf(p.scanner.start, "The capo '%v' is not a fret number.", strings.TrimSpace(d.Value)))
+
+						// the capo is kept as it was written, so it isn't lost.
+						song.Nodes = append(song.Nodes, d)
+						break
+					}
+					song.Capo = capo
+				case defineDirectiveName, chordDirectiveName:
+					def, err := songtools.ParseChordDefinition(d.Value)
+					if err != nil {
+						p.warn(p.scanner.errorf(p.scanner.start, "%v", err))
+
+						// the definition is kept as it was written, so it isn't lost.
+						song.Nodes = append(song.Nodes, d)
+						break
+					}
+					def.Display = d.Name == chordDirectiveName
+					song.Definitions = append(song.Definitions, def)
 				default:
 					song.Nodes = append(song.Nodes, d)
 				}
@@ -98,6 +254,7 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 		case sectionToken:
 			section = &songtools.Section{
 				Kind: songtools.SectionKind(text),
+				Span: p.scanner.span(),
 			}
 
 			song.Nodes = append(song.Nodes, section)
@@ -109,23 +266,78 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 				directive := &songtools.Comment{
 					Text:   text,
 					Hidden: false,
+					Span:   p.scanner.span(),
 				}
 				section.Nodes = append(section.Nodes, directive)
+				p.extend(section.Span)
 				break
 			}
 
 			if section == nil {
-				section = &songtools.Section{}
+				section = &songtools.Section{
+					Span: p.scanner.span(),
+				}
 				song.Nodes = append(song.Nodes, section)
 			}
 
-			chords, positions, isChordLine := parseTextForChords(text)
+			if isTabLine(text) {
+				// consecutive staves make up a single block of tab.
+				var tab *songtools.Tab
+				if n := len(section.Nodes); n > 0 && numNewLines <= 1 {
+					tab, _ = section.Nodes[n-1].(*songtools.Tab)
+				}
+				if tab == nil {
+					tab = &songtools.Tab{
+						Span: p.scanner.span(),
+					}
+					section.Nodes = append(section.Nodes, tab)
+				}
+				tab.Lines = append(tab.Lines, text)
+				p.extend(tab.Span)
+				p.extend(section.Span)
+				line = nil
+				numNewLines = 0
+				break
+			}
+
+			if row, ok := songtools.ParseGridRowWithChords(text, p.parseChord); ok {
+				// consecutive rows make up a single grid.
+				var grid *songtools.Grid
+				if n := len(section.Nodes); n > 0 && numNewLines <= 1 {
+					grid, _ = section.Nodes[n-1].(*songtools.Grid)
+				}
+				if grid == nil {
+					grid = &songtools.Grid{
+						Span: p.scanner.span(),
+					}
+					section.Nodes = append(section.Nodes, grid)
+				}
+				grid.Rows = append(grid.Rows, row)
+				p.extend(grid.Span)
+				p.extend(section.Span)
+				line = nil
+				numNewLines = 0
+				break
+			}
+
+			chords, positions, isChordLine := parseTextForChords(text, p.parseChord, p.lenient)
+			for i, c := range chords {
+				if c.Unknown {
+					err = p.report(format.SeverityWarning, p.scanner.errorf(p.scanner.start+positions[i], "The text '%v' is not a chord.", c.Name))
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+
 			if !isChordLine {
 				if line != nil && line.Text == "" {
 					line.Text = text
+					p.extend(line.Span)
 				} else {
 					line = &songtools.Line{
 						Text: text,
+						Span: p.scanner.span(),
 					}
 					section.Nodes = append(section.Nodes, line)
 				}
@@ -134,9 +346,12 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 				line = &songtools.Line{
 					Chords:         chords,
 					ChordPositions: positions,
+					ChordsAbove:    true,
+					Span:           p.scanner.span(),
 				}
 				section.Nodes = append(section.Nodes, line)
 			}
+			p.extend(section.Span)
 			numNewLines = 0
 		case newLineToken:
 			numNewLines++
@@ -148,13 +363,86 @@ func (p *parser) parseSong() (*songtools.Song, error) {
 			break
 		}
 
-		token, text, err = p.scanner.next()
+		token, text, err = p.next()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return song, nil
+	return set, nil
+}
+
+// TitleFollows indicates the directives on the lines after the current one end with a title,
+// without a blank line or anything else before it.
+func (p *parser) titleFollows() bool {
+	s := *p.scanner
+	numNewLines := 0
+	for {
+		token, text, err := s.next()
+		if err != nil {
+			return false
+		}
+
+		switch token {
+		case newLineToken:
+			numNewLines++
+			if numNewLines > 1 {
+				return false
+			}
+		case directiveToken:
+			d, err := parseDirective(text)
+			if err != nil {
+				return false
+			}
+			if d.Name == titleDirectiveName {
+				return true
+			}
+			numNewLines = 0
+		default:
+			return false
+		}
+	}
+}
+
+// Next returns the next token. When parsing leniently, problems found by
+// the scanner are reported and the best-effort token is returned.
+func (p *parser) next() (token, string, error) {
+	token, text, err := p.scanner.next()
+	if err != nil {
+		err = p.report(format.SeverityError, err)
+	}
+
+	return token, text, err
+}
+
+// Warn records the problem as a warning when parsing leniently. It is for content that is
+// kept as it was written, so it never stops a strict parse.
+func (p *parser) warn(err error) {
+	if p.lenient {
+		p.report(format.SeverityWarning, err)
+	}
+}
+
+// Report records the problem as a diagnostic when parsing leniently. Otherwise, the
+// problem is returned as an error.
+func (p *parser) report(severity format.Severity, err error) error {
+	perr, ok := err.(*format.ParseError)
+	if !p.lenient || !ok {
+		return err
+	}
+
+	p.diagnostics = append(p.diagnostics, &format.Diagnostic{
+		ParseError: *perr,
+		Severity:   severity,
+	})
+	return nil
+}
+
+// Extend moves the end of the span to the end of the current token.
+func (p *parser) extend(span *songtools.Span) {
+	if span != nil {
+		span.End = p.scanner.span().End
+	}
 }
 
 func parseDirective(text string) (*songtools.Directive, error) {
@@ -183,11 +471,15 @@ func parseDirective(text string) (*songtools.Directive, error) {
 }
 
 // ParseTextForChords parses a line of text for chords and their positions. It returns true if
-// this was just a line of chords and false if it contains text other than chords.
-func parseTextForChords(text string) ([]*songtools.Chord, []int, bool) {
+// this was just a line of chords and false if it contains text other than chords. When lenient,
+// a line made up mostly of chords is still a line of chords and the names that aren't chords
+// are returned as unknown chords.
+func parseTextForChords(text string, parseChord func(string) (*songtools.Chord, bool), lenient bool) ([]*songtools.Chord, []int, bool) {
 
 	chords := []*songtools.Chord{}
 	positions := []int{}
+	unknown := 0
+	words := 0
 
 	i := 0
 	for i < len(text) {
@@ -199,22 +491,87 @@ func parseTextForChords(text string) ([]*songtools.Chord, []int, bool) {
 			break
 		}
 
-		name := ""
 		pos := i
 		for i < len(text) && text[i] != ' ' {
-			name += string(text[i])
 			i++
 		}
+		name := text[pos:i]
 
-		chord, ok := songtools.ParseChord(name)
+		chord, ok := parseChord(name)
 		if !ok {
-			// we aren't a chord line
-			return nil, nil, false
+			if !lenient || !looksLikeChord(name) {
+				// we aren't a chord line
+				return nil, nil, false
+			}
+
+			chord = songtools.UnknownChord(name)
+			unknown++
+		} else if isWord(name, chord) {
+			words++
 		}
 
 		chords = append(chords, chord)
 		positions = append(positions, pos)
 	}
 
+	if unknown > 0 && unknown*2 >= len(chords) {
+		// too many of these aren't chords, so this is most likely lyrics.
+		return nil, nil, false
+	}
+
+	if words > 0 && words+unknown == len(chords) {
+		// every chord is also a word, like Go and Do, so this is most likely lyrics.
+		return nil, nil, false
+	}
+
 	return chords, positions, len(chords) > 0
 }
+
+// IsWord indicates whether the chord is also a word, since it is written with the o for
+// diminished, such as Go and Do. Only a line with other chords makes it a chord.
+func isWord(name string, chord *songtools.Chord) bool {
+	return chord.Quality == songtools.Diminished && !strings.Contains(name, "dim") && !strings.Contains(name, "°")
+}
+
+// LooksLikeChord indicates whether a name that isn't a chord is shaped like one,
+// which would make it more likely to be a mistake than a lyric.
+func looksLikeChord(name string) bool {
+	if len(name) > 8 {
+		return false
+	}
+
+	return name[0] >= 'A' && name[0] <= 'H'
+}
+
+// tabStaffChars are the characters found on a staff of tab, other than the name of the string.
+const tabStaffChars = "-|:0123456789hpbrxv/\\~()<>^*. "
+
+// isTabLine indicates whether the line is a staff of tab, such as e|---0---2---|. A staff
+// starts with the name of a string or a bar, and is mostly made up of dashes and frets.
+func isTabLine(text string) bool {
+	text = strings.TrimSpace(text)
+	rest := text
+	if len(rest) > 0 && strings.IndexByte("ABCDEFGabcdefg", rest[0]) != -1 {
+		rest = rest[1:]
+		if len(rest) > 0 && (rest[0] == '#' || rest[0] == 'b') {
+			rest = rest[1:]
+		}
+	}
+
+	if rest == "" || (rest[0] != '|' && rest[0] != '-' && rest[0] != ':') {
+		return false
+	}
+
+	if strings.Count(rest, "-") < 4 {
+		return false
+	}
+
+	staff := 0
+	for _, r := range rest {
+		if strings.ContainsRune(tabStaffChars, r) {
+			staff++
+		}
+	}
+
+	return staff*10 >= utf8.RuneCountInString(rest)*9
+}
@@ -0,0 +1,300 @@
+package chordsOverLyrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestParseSongSetSplitsSongs(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		titles []string
+		keys   []string
+	}{
+		{
+			name:   "title",
+			src:    "#title=One\n#key=C\n\nC\nHello\n\n#title=Two\n#key=D\n\nD\nBye\n",
+			titles: []string{"One", "Two"},
+			keys:   []string{"C", "D"},
+		},
+		{
+			name:   "directives before the title",
+			src:    "#title=One\n#key=C\n\nC\nHello\n\n#key=D\n#title=Two\n\nD\nBye\n",
+			titles: []string{"One", "Two"},
+			keys:   []string{"C", "D"},
+		},
+		{
+			name:   "several directives before the title",
+			src:    "#title=One\n#key=C\n\nC\nHello\n\n#key=D\n#capo=2\n#title=Two\n\nD\nBye\n",
+			titles: []string{"One", "Two"},
+			keys:   []string{"C", "D"},
+		},
+		{
+			name:   "directives before the first title",
+			src:    "#copyright=1900\n#title=One\n\nC\nHello\n",
+			titles: []string{"One"},
+			keys:   []string{""},
+		},
+		{
+			name:   "key change without a title",
+			src:    "#title=One\n#key=C\n\nC\nHello\n\n#key=D\n\nD\nBye\n",
+			titles: []string{"One"},
+			keys:   []string{"C"},
+		},
+		{
+			name:   "title after a blank line",
+			src:    "#title=One\n\nC\nHello\n\n#key=D\n\n#title=Two\n\nD\nBye\n",
+			titles: []string{"One", "Two"},
+			keys:   []string{"", ""},
+		},
+	}
+
+	for _, test := range tests {
+		set, err := ParseSongSet(strings.NewReader(test.src))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if len(set.Songs) != len(test.titles) {
+			t.Errorf("%v: expected %d songs, but got %d", test.name, len(test.titles), len(set.Songs))
+			continue
+		}
+
+		for i, s := range set.Songs {
+			if s.Title != test.titles[i] {
+				t.Errorf("%v: expected song %d to have the title %q, but got %q", test.name, i+1, test.titles[i], s.Title)
+			}
+			if s.Key.String() != test.keys[i] {
+				t.Errorf("%v: expected song %d to be in %q, but got %q", test.name, i+1, test.keys[i], s.Key.String())
+			}
+		}
+	}
+}
+
+func TestParseSongSetKeepsKeyChanges(t *testing.T) {
+	src := "#title=One\n#key=C\n\nC\nHello\n\n#key=D\n\nD\nBye\n"
+	set, err := ParseSongSet(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := 0
+	for _, n := range set.Songs[0].Nodes {
+		if _, ok := n.(*songtools.KeyChange); ok {
+			changes++
+		}
+	}
+	if changes != 1 {
+		t.Errorf("expected 1 key change, but got %d", changes)
+	}
+}
+
+func TestParseTextForChords(t *testing.T) {
+	tests := []struct {
+		text        string
+		isChordLine bool
+	}{
+		{"C      G", true},
+		{"  Am7  D/F#  G", true},
+		{"Hello there", false},
+		{"Go Do", false},
+		{"Go", false},
+		{"C  Go  Dm", true},
+		{"Co7  G", true},
+		{"Bdim", true},
+		{"B°7", true},
+		{"A", true},
+	}
+
+	for _, test := range tests {
+		_, _, isChordLine := parseTextForChords(test.text, songtools.ParseChord, false)
+		if isChordLine != test.isChordLine {
+			t.Errorf("parseTextForChords(%q) = %v, expected %v", test.text, isChordLine, test.isChordLine)
+		}
+	}
+}
+
+func TestIsTabLine(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"e|---0---2---|", true},
+		{"B|---1---3---|", true},
+		{"  G|-0-0-0-0-|  ", true},
+		{"D#|--2h4--5p2--|", true},
+		{"Eb|--3/5--5\\3--|", true},
+		{"|---0---2---|", true},
+		{"--0--2--3--", true},
+		{"e:--12--x--(7)--|", true},
+		{"C      G", false},
+		{"Hello there", false},
+		{"e|--0-|", false},
+		{"G|-- to the chorus --|", false},
+		{"-- Repeat --", false},
+		{"A - ma - zing grace", false},
+		{"| C . . . | G . . . |", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if actual := isTabLine(test.text); actual != test.expected {
+			t.Errorf("isTabLine(%q) = %v, expected %v", test.text, actual, test.expected)
+		}
+	}
+}
+
+func TestParseSongTabs(t *testing.T) {
+	src := "[Intro]\ne|---0---2---|\nB|---1---3---|\n\nC   G\nHello there\n"
+	s, err := ParseSong(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	section := s.Nodes[0].(*songtools.Section)
+	if len(section.Nodes) != 2 {
+		t.Fatalf("expected a tab and a line, but got %d nodes", len(section.Nodes))
+	}
+	tab, ok := section.Nodes[0].(*songtools.Tab)
+	if !ok {
+		t.Fatalf("expected a tab, but got %T", section.Nodes[0])
+	}
+	if expected := "e|---0---2---|\nB|---1---3---|"; strings.Join(tab.Lines, "\n") != expected {
+		t.Errorf("expected the tab %q, but got %q", expected, tab.Lines)
+	}
+	if l, ok := section.Nodes[1].(*songtools.Line); !ok || len(l.Chords) != 2 {
+		t.Errorf("expected a line with 2 chords after the tab, but got %#v", section.Nodes[1])
+	}
+}
+
+func TestParseSongWithWordsLikeChords(t *testing.T) {
+	s, err := ParseSong(strings.NewReader("Go Do\nsomething\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	section := s.Nodes[0].(*songtools.Section)
+	if len(section.Nodes) != 2 {
+		t.Fatalf("expected 2 lines, but got %d", len(section.Nodes))
+	}
+	for i, text := range []string{"Go Do", "something"} {
+		l := section.Nodes[i].(*songtools.Line)
+		if l.Text != text || len(l.Chords) != 0 {
+			t.Errorf("expected line %d to be %q without chords, but got %q with %d chords", i+1, text, l.Text, len(l.Chords))
+		}
+	}
+}
+
+func TestParseSongJoinsSpacedSyllables(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		// the syllables were spaced out to line up with the chords above them.
+		{"G     C     D\nA - ma - zing grace\n", "Amazing grace"},
+		{"Em7  G\nJe - sus\n", "Jesus"},
+		// a dash between two words is kept.
+		{"       G\nLord - you are my strength\n", "Lord - you are my strength"},
+		{"D      G\nLord - you are my strength\n", "Lord - you are my strength"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		lyrics := songtools.LyricsOnly(s)
+		l := lyrics.Nodes[0].(*songtools.Section).Nodes[0].(*songtools.Line)
+		if l.Text != test.expected {
+			t.Errorf("lyrics of %q = %q, expected %q", test.text, l.Text, test.expected)
+		}
+	}
+}
+
+func TestParseSongKeepsUnknownKeys(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"#title=One\n#key=G (capo 2)\n\nC\nHello\n", "#key=G (capo 2)"},
+		{"#title=One\n#key=C\n\nC\nHello\n\n#key=H#\n\nC\nBye\n", "#key=H#"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+
+		var b bytes.Buffer
+		if err := WriteSong(&b, s); err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+		if !strings.Contains(b.String(), test.expected) {
+			t.Errorf("%q: expected %q to be written, but got %q", test.text, test.expected, b.String())
+		}
+	}
+}
+
+func TestParseSongKeepsUnreadableDirectives(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"#title=One\n#capo=x\n\nC\nHello\n", "#capo=x"},
+		{"#title=One\n#define=C keys 0 4 7\n\nC\nHello\n", "#define=C keys 0 4 7"},
+		{"#title=One\n#chord=C7 copy C\n\nC7\nHello\n", "#chord=C7 copy C"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.text))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+
+		var b bytes.Buffer
+		if err := WriteSong(&b, s); err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+		if !strings.Contains(b.String(), test.expected) {
+			t.Errorf("%q: expected %q to be written, but got %q", test.text, test.expected, b.String())
+		}
+	}
+}
+
+func TestParseSongSetLenientKeepsUnknownKeys(t *testing.T) {
+	tests := []string{
+		"#title=One\n#key=H#\n\nC\nHello\n",
+		"#title=One\n#key=C\n\nC\nHello\n\n#key=H#\n\nC\nBye\n",
+	}
+
+	for _, src := range tests {
+		set, diags, err := ParseSongSetLenient(strings.NewReader(src))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", src, err)
+			continue
+		}
+		if len(diags) != 1 {
+			t.Errorf("%q: expected 1 diagnostic, but got %d", src, len(diags))
+		}
+
+		var b bytes.Buffer
+		if err := WriteSongSet(&b, set); err != nil {
+			t.Errorf("%q: unexpected error: %v", src, err)
+			continue
+		}
+		if !strings.Contains(b.String(), "#key=H#") {
+			t.Errorf("%q: expected the key to be written, but got %q", src, b.String())
+		}
+	}
+}
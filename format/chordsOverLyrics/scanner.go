@@ -5,6 +5,9 @@ import (
 	"io"
 	"io/ioutil"
 	"strings"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
 )
 
 type token int
@@ -39,11 +42,17 @@ func (t token) String() string {
 
 // Scanner produces tokens from text.
 type scanner struct {
-	src string
-	pos int
+	src    string
+	source *format.Source
+	pos    int
+
+	// start and end of the most recently returned token
+	start int
+	end   int
 
 	isPeeked  bool
 	peekPos   int
+	peekEnd   int
 	peekToken token
 	peekText  string
 	peekErr   error
@@ -56,7 +65,7 @@ func newScanner(src io.Reader) (*scanner, error) {
 		return nil, fmt.Errorf("unable to create a new scanner: %v", err)
 	}
 
-	s := &scanner{src: string(b), pos: 0}
+	s := &scanner{src: string(b), source: format.NewSource(string(b)), pos: 0}
 	return s, nil
 }
 
@@ -64,8 +73,8 @@ func newScanner(src io.Reader) (*scanner, error) {
 func (s *scanner) peek() (token, string, error) {
 	if !s.isPeeked {
 		s.isPeeked = true
-		s.peekPos = s.pos
 		s.peekToken, s.peekText, s.peekErr = s.next()
+		s.peekPos, s.peekEnd = s.start, s.end
 	}
 
 	return s.peekToken, s.peekText, s.peekErr
@@ -75,9 +84,27 @@ func (s *scanner) peek() (token, string, error) {
 func (s *scanner) next() (token, string, error) {
 	if s.isPeeked {
 		s.isPeeked = false
+		s.start, s.end = s.peekPos, s.peekEnd
 		return s.peekToken, s.peekText, s.peekErr
 	}
 
+	s.start = s.pos
+	token, text, err := s.scan()
+	s.end = s.pos
+	return token, text, err
+}
+
+// Span returns the span of the most recently returned token.
+func (s *scanner) span() *songtools.Span {
+	return s.source.Span(s.start, s.end)
+}
+
+// Errorf creates a ParseError at the offset.
+func (s *scanner) errorf(offset int, f string, args ...interface{}) error {
+	return s.source.Error(offset, fmt.Sprintf(f, args...))
+}
+
+func (s *scanner) scan() (token, string, error) {
 	if s.pos < len(s.src) {
 		switch s.src[s.pos] {
 		case '\r':
@@ -142,18 +169,34 @@ func (s *scanner) scanNewLine() (token, string, error) {
 }
 
 func (s *scanner) scanSectionHeader() (token, string, error) {
+	open := s.pos
 	start := s.pos + 1
-	for s.pos < len(s.src) {
+	for s.pos+1 < len(s.src) {
 		s.pos++
-		if s.src[s.pos] == ']' {
+		switch s.src[s.pos] {
+		case ']':
 			s.pos++
 			return sectionToken, s.src[start : s.pos-1], nil
+		case '\r', '\n':
+			return s.scanUnterminated(open, "Expected ']', but found the end of the line")
 		}
 	}
-	if s.pos == len(s.src) {
-		return eofToken, "", fmt.Errorf("Expected ']', but found Eof")
+
+	return s.scanUnterminated(open, "Expected ']', but found Eof")
+}
+
+// ScanUnterminated rescans everything from the open bracket to the end of the line as text
+// and returns it along with an error so that a lenient parser can continue.
+func (s *scanner) scanUnterminated(open int, msg string) (token, string, error) {
+	s.pos = open
+	for s.pos < len(s.src) {
+		if s.src[s.pos] == '\r' || s.src[s.pos] == '\n' {
+			break
+		}
+		s.pos++
 	}
-	return eofToken, "", nil
+
+	return textToken, s.src[open:s.pos], s.errorf(open, "%v", msg)
 }
 
 func (s *scanner) scanText() (token, string, error) {
@@ -0,0 +1,38 @@
+package chordsOverLyrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanSectionHeader(t *testing.T) {
+	tests := []struct {
+		src      string
+		token    token
+		text     string
+		expected string
+	}{
+		{"[Verse 1]\nla la\n", sectionToken, "Verse 1", ""},
+		{"[Verse\nla la\n[Chorus]\n", textToken, "[Verse", "Expected ']', but found the end of the line"},
+		{"[Verse\r\nla ]\n", textToken, "[Verse", "Expected ']', but found the end of the line"},
+		{"[Verse", textToken, "[Verse", "Expected ']', but found Eof"},
+	}
+
+	for _, test := range tests {
+		s, err := newScanner(strings.NewReader(test.src))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		token, text, err := s.scan()
+		if token != test.token || text != test.text {
+			t.Errorf("scan(%q) = %v %q, expected %v %q", test.src, token, text, test.token, test.text)
+		}
+		switch {
+		case test.expected == "" && err != nil:
+			t.Errorf("scan(%q) unexpected error: %v", test.src, err)
+		case test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)):
+			t.Errorf("scan(%q) expected the error %q, but was %v", test.src, test.expected, err)
+		}
+	}
+}
@@ -3,13 +3,78 @@ package chordsOverLyrics
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/analysis"
 )
 
+// WriteOptions control how a song is written.
+type WriteOptions struct {
+	// Numerals writes the Roman numeral of each chord, in the song's key, above the chords.
+	Numerals bool
+	// Lyrics writes just the words of the song, without its chords.
+	Lyrics bool
+}
+
+// songWriter writes a single song.
+type songWriter struct {
+	opts *WriteOptions
+	song *songtools.Song
+	// key is the key in effect at the node being written.
+	key songtools.Key
+}
+
+// WriteSongSet writes all the songs in the set to the writer. Each song after
+// the first begins with a title directive so the songs can be told apart.
+func WriteSongSet(w io.Writer, set *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, set, &WriteOptions{})
+}
+
+// WriteSongSetWithOptions writes all the songs in the set to the writer using the options.
+func WriteSongSetWithOptions(w io.Writer, set *songtools.SongSet, opts *WriteOptions) error {
+	for i, s := range set.Songs {
+		if i > 0 {
+			_, err := fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+
+			if s.Title == "" {
+				_, err := fmt.Fprintln(w, "#"+titleDirectiveName+"=")
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err := WriteSongWithOptions(w, s, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // WriteSong writes a single song to the writer.
 func WriteSong(w io.Writer, s *songtools.Song) error {
+	return WriteSongWithOptions(w, s, &WriteOptions{})
+}
+
+// WriteSongWithOptions writes a single song to the writer using the options.
+func WriteSongWithOptions(w io.Writer, s *songtools.Song, opts *WriteOptions) error {
+	if opts.Lyrics {
+		s = songtools.LyricsOnly(s)
+	}
+
+	sw := &songWriter{
+		opts: opts,
+		song: s,
+		key:  s.Key,
+	}
 
 	if s.Title != "" {
 		_, err := fmt.Fprintln(w, "#"+titleDirectiveName+"="+s.Title)
@@ -29,15 +94,31 @@ func WriteSong(w io.Writer, s *songtools.Song) error {
 			return err
 		}
 	}
-	if s.Key != "" {
-		_, err := fmt.Fprintln(w, "#"+keyDirectiveName+"="+string(s.Key))
+	if !s.Key.IsZero() {
+		_, err := fmt.Fprintln(w, "#"+keyDirectiveName+"="+s.Key.String())
+		if err != nil {
+			return err
+		}
+	}
+	if s.Capo > 0 {
+		_, err := fmt.Fprintln(w, "#"+capoDirectiveName+"="+strconv.Itoa(s.Capo))
+		if err != nil {
+			return err
+		}
+	}
+	for _, d := range s.Definitions {
+		name := defineDirectiveName
+		if d.Display {
+			name = chordDirectiveName
+		}
+		_, err := fmt.Fprintln(w, "#"+name+"="+d.String())
 		if err != nil {
 			return err
 		}
 	}
 
 	for _, n := range s.Nodes {
-		err := writeSongNode(w, n)
+		err := sw.writeSongNode(w, n)
 		if err != nil {
 			return err
 		}
@@ -46,15 +127,17 @@ func WriteSong(w io.Writer, s *songtools.Song) error {
 	return nil
 }
 
-func writeSongNode(w io.Writer, n songtools.SongNode) error {
+func (sw *songWriter) writeSongNode(w io.Writer, n songtools.SongNode) error {
 	switch typedN := n.(type) {
 	case *songtools.Comment:
 		return writeComment(w, typedN)
 	case *songtools.Directive:
 		return writeDirective(w, typedN)
+	case *songtools.KeyChange:
+		return sw.writeKeyChange(w, typedN)
 	case *songtools.Section:
 		if typedN.Kind != "" {
-			_, err := fmt.Fprint(w, fmt.Sprintf("[%v]", typedN.Kind))
+			_, err := fmt.Fprint(w, fmt.Sprintf("[%v]", typedN.Heading()))
 			if err != nil {
 				return err
 			}
@@ -85,7 +168,7 @@ func writeSongNode(w io.Writer, n songtools.SongNode) error {
 					}
 				}
 			}
-			err := writeSectionNode(w, sn, anyChords)
+			err := sw.writeSectionNode(w, sn, anyChords)
 			if err != nil {
 				return err
 			}
@@ -102,19 +185,47 @@ func writeSongNode(w io.Writer, n songtools.SongNode) error {
 	return nil
 }
 
-func writeSectionNode(w io.Writer, n songtools.SectionNode, blankLineForNoChords bool) error {
+func (sw *songWriter) writeSectionNode(w io.Writer, n songtools.SectionNode, blankLineForNoChords bool) error {
 	switch typedN := n.(type) {
 	case *songtools.Comment:
 		return writeComment(w, typedN)
 	case *songtools.Directive:
 		return writeDirective(w, typedN)
+	case *songtools.KeyChange:
+		return sw.writeKeyChange(w, typedN)
+	case *songtools.Tab:
+		return writeTab(w, typedN)
+	case *songtools.Grid:
+		return writeGrid(w, typedN)
 	case *songtools.Line:
-		return writeLine(w, typedN, blankLineForNoChords)
+		return sw.writeLine(w, typedN, blankLineForNoChords)
 	default:
 		panic("Unknown node")
 	}
 }
 
+func writeTab(w io.Writer, t *songtools.Tab) error {
+	for _, l := range t.Lines {
+		_, err := fmt.Fprintln(w, l)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeGrid(w io.Writer, g *songtools.Grid) error {
+	for _, l := range g.Lines() {
+		_, err := fmt.Fprintln(w, l)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func writeComment(w io.Writer, c *songtools.Comment) error {
 	_, err := fmt.Fprintln(w, fmt.Sprintf("{%v}", c.Text))
 	return err
@@ -125,7 +236,21 @@ func writeDirective(w io.Writer, d *songtools.Directive) error {
 	return err
 }
 
-func writeLine(w io.Writer, l *songtools.Line, blankLineForNoChords bool) error {
+// writeKeyChange writes the key change, and the numerals that follow are in the new key.
+func (sw *songWriter) writeKeyChange(w io.Writer, k *songtools.KeyChange) error {
+	sw.key = k.Key
+	_, err := fmt.Fprintln(w, "#"+keyDirectiveName+"="+k.Key.String())
+	return err
+}
+
+func (sw *songWriter) writeLine(w io.Writer, l *songtools.Line, blankLineForNoChords bool) error {
+	if l.Chords != nil && sw.opts.Numerals {
+		err := sw.writeNumerals(w, l)
+		if err != nil {
+			return err
+		}
+	}
+
 	if l.Chords != nil {
 		pos := 0
 		for i := 0; i < len(l.Chords); i++ {
@@ -158,3 +283,24 @@ func writeLine(w io.Writer, l *songtools.Line, blankLineForNoChords bool) error
 	_, err := fmt.Fprintln(w, l.Text)
 	return err
 }
+
+// writeNumerals writes the Roman numerals of the line's chords, lined up with the chords.
+func (sw *songWriter) writeNumerals(w io.Writer, l *songtools.Line) error {
+	buf := ""
+	for i, c := range l.Chords {
+		numeral := "?"
+		if n, ok := analysis.AnalyzeChord(c, sw.key); ok {
+			numeral = n.String()
+		}
+
+		if pad := l.ChordPositions[i] - utf8.RuneCountInString(buf); pad > 0 {
+			buf += strings.Repeat(" ", pad)
+		} else if i > 0 {
+			buf += " "
+		}
+		buf += numeral
+	}
+
+	_, err := fmt.Fprintln(w, buf)
+	return err
+}
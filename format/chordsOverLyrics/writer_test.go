@@ -0,0 +1,39 @@
+package chordsOverLyrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestWriteSongTabs(t *testing.T) {
+	lines := []string{"e|---0---2---|", "B|---1---3---|", "  G|-0-0-0-0-|  "}
+	s := &songtools.Song{Nodes: []songtools.SongNode{
+		&songtools.Section{Kind: "Intro", Nodes: []songtools.SectionNode{
+			&songtools.Tab{Lines: lines},
+		}},
+	}}
+
+	var b bytes.Buffer
+	if err := WriteSong(&b, s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := strings.Join(lines, "\n") + "\n"; !strings.Contains(b.String(), expected) {
+		t.Errorf("expected %q to be written, but got %q", expected, b.String())
+	}
+
+	// the tab reads back as it was written.
+	read, err := ParseSong(&b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tab, ok := read.Nodes[0].(*songtools.Section).Nodes[0].(*songtools.Tab)
+	if !ok {
+		t.Fatalf("expected a tab, but got %T", read.Nodes[0].(*songtools.Section).Nodes[0])
+	}
+	if strings.Join(tab.Lines, "\n") != strings.Join(lines, "\n") {
+		t.Errorf("expected the tab %q to read back, but got %q", lines, tab.Lines)
+	}
+}
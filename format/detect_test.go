@@ -0,0 +1,40 @@
+package format_test
+
+import (
+	"testing"
+
+	"github.com/songtools/songtools/format"
+	_ "github.com/songtools/songtools/format/chordpro"
+	_ "github.com/songtools/songtools/format/chordsOverLyrics"
+	_ "github.com/songtools/songtools/format/nashville"
+)
+
+func TestFormatsDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   string
+		expected string
+	}{
+		{"chordpro", "{title: Grace}\n{key: G}\n\nA[G]mazing [C]grace\n", "chordpro"},
+		{"chordsOverLyrics", "#title=Grace\n#key=G\n\n[Verse 1]\nG      C\nAmazing grace\n", "chordsOverLyrics"},
+		{"nashville", "#title=Grace\n#key=G\n\n[Verse 1]\n1      4\nAmazing grace\n", "nashville"},
+		// a chord in brackets is chordpro, but a word in brackets is a chordsOverLyrics header.
+		{"bracketed chord", "[G]\nAmazing grace\n", "chordpro"},
+		{"bracketed word", "[Chorus]\nG      C\nAmazing grace\n", "chordsOverLyrics"},
+		{"header in chordpro", "{title: Grace}\n[Chorus]\n[G]Amazing grace\n", "chordpro"},
+		{"numbers", "1    4    5\nAmazing grace\n", "nashville"},
+		{"lyrics", "Am I wrong\nto think of you\n", ""},
+		{"empty", "", ""},
+	}
+
+	for _, test := range tests {
+		f, ok := format.RegisteredFormats().Detect([]byte(test.sample))
+		name := ""
+		if ok {
+			name = f.Name
+		}
+		if name != test.expected {
+			t.Errorf("%v: detected %q, expected %q", test.name, name, test.expected)
+		}
+	}
+}
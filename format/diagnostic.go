@@ -0,0 +1,71 @@
+package format
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/songtools/songtools"
+)
+
+// Severity indicates how serious a Diagnostic is.
+type Severity int
+
+const (
+	// SeverityError is a problem that caused some of the song to be misunderstood.
+	SeverityError Severity = iota
+	// SeverityWarning is a problem that was recovered from without losing content.
+	SeverityWarning
+	// SeverityInfo is something worth noting that isn't a problem.
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a problem found while leniently parsing a song.
+type Diagnostic struct {
+	ParseError
+	Severity Severity
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%v: %v", d.Severity, d.ParseError.Error())
+}
+
+// Diagnostics is a slice of Diagnostics.
+type Diagnostics []*Diagnostic
+
+// HasErrors indicates whether any of the diagnostics are errors.
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetFile sets the file of all the diagnostics.
+func (ds Diagnostics) SetFile(file string) {
+	for _, d := range ds {
+		d.File = file
+	}
+}
+
+// LenientReader represents the ability to read a SongSet while recovering from problems.
+// Rather than stopping at the first problem, all the problems are returned as Diagnostics
+// alongside the best-effort SongSet.
+type LenientReader interface {
+	ReadSetLenient(io.Reader) (*songtools.SongSet, Diagnostics, error)
+}
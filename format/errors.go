@@ -0,0 +1,102 @@
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+// ParseError is a problem found at a specific location while parsing a song.
+type ParseError struct {
+	File    string
+	Line    int
+	Column  int
+	Excerpt string
+	Msg     string
+}
+
+func (e *ParseError) Error() string {
+	location := fmt.Sprintf("%d:%d", e.Line, e.Column)
+	if e.File != "" {
+		location = e.File + ":" + location
+	}
+
+	if e.Excerpt == "" {
+		return fmt.Sprintf("%v: %v", location, e.Msg)
+	}
+
+	return fmt.Sprintf("%v: %v\n\t%v\n\t%v^", location, e.Msg, e.Excerpt, caretIndent(e.Excerpt, e.Column))
+}
+
+// Source is the text of a song being parsed along with the offsets its lines start at, so the
+// position of an offset is found without counting the lines before it each time.
+type Source struct {
+	Text       string
+	lineStarts []int
+}
+
+// NewSource creates a Source for the text.
+func NewSource(text string) *Source {
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	return &Source{Text: text, lineStarts: lineStarts}
+}
+
+// Position computes the Position of the offset in the source.
+func (s *Source) Position(offset int) songtools.Position {
+	if offset > len(s.Text) {
+		offset = len(s.Text)
+	}
+
+	line := sort.SearchInts(s.lineStarts, offset+1)
+	return songtools.Position{
+		Offset: offset,
+		Line:   line,
+		Column: offset - s.lineStarts[line-1] + 1,
+	}
+}
+
+// Span computes the Span between the start and end offsets in the source.
+func (s *Source) Span(start, end int) *songtools.Span {
+	return &songtools.Span{
+		Start: s.Position(start),
+		End:   s.Position(end),
+	}
+}
+
+// Error creates a ParseError for the offset in the source.
+func (s *Source) Error(offset int, msg string) *ParseError {
+	pos := s.Position(offset)
+	start := s.lineStarts[pos.Line-1]
+	excerpt := s.Text[start:]
+	if end := strings.IndexAny(excerpt, "\r\n"); end != -1 {
+		excerpt = excerpt[:end]
+	}
+
+	return &ParseError{
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Excerpt: excerpt,
+		Msg:     msg,
+	}
+}
+
+func caretIndent(excerpt string, column int) string {
+	indent := ""
+	for i := 0; i < column-1 && i < len(excerpt); i++ {
+		if excerpt[i] == '\t' {
+			indent += "\t"
+		} else {
+			indent += " "
+		}
+	}
+
+	return indent
+}
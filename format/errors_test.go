@@ -0,0 +1,37 @@
+package format
+
+import "testing"
+
+func TestSourcePosition(t *testing.T) {
+	src := NewSource("ab\ncd\r\n\nef")
+	tests := []struct {
+		offset int
+		line   int
+		column int
+	}{
+		{0, 1, 1},
+		{2, 1, 3},
+		{3, 2, 1},
+		{5, 2, 3},
+		{6, 2, 4},
+		{7, 3, 1},
+		{8, 4, 1},
+		{10, 4, 3},
+		{20, 4, 3},
+	}
+
+	for _, test := range tests {
+		pos := src.Position(test.offset)
+		if pos.Line != test.line || pos.Column != test.column {
+			t.Errorf("Position(%d) = %d:%d, expected %d:%d", test.offset, pos.Line, pos.Column, test.line, test.column)
+		}
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	err := NewSource("one\r\ntwo [G\nthree").Error(9, "Expected ']'")
+	expected := "2:5: Expected ']'\n\ttwo [G\n\t    ^"
+	if err.Error() != expected {
+		t.Errorf("Error() = %q, expected %q", err.Error(), expected)
+	}
+}
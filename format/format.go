@@ -1,6 +1,7 @@
 package format
 
 import (
+	"fmt"
 	"io"
 	"strings"
 
@@ -12,16 +13,41 @@ const (
 	Default = "chordsOverLyrics"
 )
 
-// Reader represents the ability to read a SongSet.
+// Reader represents the ability to read a Song.
 type Reader interface {
 	Read(io.Reader) (*songtools.Song, error)
 }
 
-// Writer represents the ability to write a SongSet.
+// Writer represents the ability to write a Song.
 type Writer interface {
 	Write(io.Writer, *songtools.Song) error
 }
 
+// SetReader represents the ability to read a SongSet. A Reader may also implement
+// SetReader when its format is able to hold more than one song.
+type SetReader interface {
+	ReadSet(io.Reader) (*songtools.SongSet, error)
+}
+
+// SetWriter represents the ability to write a SongSet. A Writer may also implement
+// SetWriter when its format is able to hold more than one song.
+type SetWriter interface {
+	WriteSet(io.Writer, *songtools.SongSet) error
+}
+
+// BookWriter represents the ability to write a Book. A Writer may also implement BookWriter
+// when its format is able to lay out a songbook, with its title page, contents and index.
+type BookWriter interface {
+	WriteBook(io.Writer, *songtools.Book) error
+}
+
+// Detector represents the ability to recognize a format from a sample of its content.
+type Detector interface {
+	// Detect returns a score between 0 and 1 indicating how confident the detector
+	// is that the sample is written in its format.
+	Detect(sample []byte) float64
+}
+
 // Formats is a slice of Formats.
 type Formats []*Format
 
@@ -37,6 +63,26 @@ func (fs Formats) Filter(filter func(*Format) bool) Formats {
 	return formats
 }
 
+// Detect scores the sample against every format that can detect and returns the
+// format with the highest score. When no format recognizes the sample, false is returned.
+func (fs Formats) Detect(sample []byte) (*Format, bool) {
+	var best *Format
+	bestScore := 0.0
+	for _, f := range fs {
+		if !f.CanDetect() {
+			continue
+		}
+
+		score := f.Detector.Detect(sample)
+		if score > bestScore {
+			best = f
+			bestScore = score
+		}
+	}
+
+	return best, best != nil
+}
+
 var registeredFormats = Formats{}
 
 // Format represents a named ability to read and write a SongSet.
@@ -44,6 +90,7 @@ type Format struct {
 	Name       string
 	Reader     Reader
 	Writer     Writer
+	Detector   Detector
 	Extensions []string
 }
 
@@ -52,11 +99,80 @@ func (f *Format) CanRead() bool {
 	return f.Reader != nil
 }
 
+// CanDetect indicates whether the format can be discovered from the content of a song.
+func (f *Format) CanDetect() bool {
+	return f.Detector != nil
+}
+
 // CanWrite indicates whether the format can be used to write a song.
 func (f *Format) CanWrite() bool {
 	return f.Writer != nil
 }
 
+// CanWriteBook indicates whether the format can be used to write a songbook.
+func (f *Format) CanWriteBook() bool {
+	_, ok := f.Writer.(BookWriter)
+	return ok
+}
+
+// ReadSet reads a SongSet. When the format's reader only understands a single song,
+// the set will contain just that song.
+func (f *Format) ReadSet(r io.Reader) (*songtools.SongSet, error) {
+	if sr, ok := f.Reader.(SetReader); ok {
+		return sr.ReadSet(r)
+	}
+
+	song, err := f.Reader.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &songtools.SongSet{}
+	if song != nil {
+		set.Songs = append(set.Songs, song)
+	}
+
+	return set, nil
+}
+
+// ReadSetLenient reads a SongSet, recovering from problems when the format's reader supports it.
+// When it doesn't, the first problem is returned as an error.
+func (f *Format) ReadSetLenient(r io.Reader) (*songtools.SongSet, Diagnostics, error) {
+	if lr, ok := f.Reader.(LenientReader); ok {
+		return lr.ReadSetLenient(r)
+	}
+
+	set, err := f.ReadSet(r)
+	return set, nil, err
+}
+
+// WriteSet writes a SongSet. When the format's writer only understands a single song,
+// the songs will be written one after another.
+func (f *Format) WriteSet(w io.Writer, set *songtools.SongSet) error {
+	if sw, ok := f.Writer.(SetWriter); ok {
+		return sw.WriteSet(w, set)
+	}
+
+	for _, s := range set.Songs {
+		err := f.Writer.Write(w, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteBook writes a Book. Only formats whose writer understands books can write one.
+func (f *Format) WriteBook(w io.Writer, b *songtools.Book) error {
+	bw, ok := f.Writer.(BookWriter)
+	if !ok {
+		return fmt.Errorf("the format %q is unable to write a book", f.Name)
+	}
+
+	return bw.WriteBook(w, b)
+}
+
 // RegisteredFormats returns all the registered formats.
 func RegisteredFormats() Formats {
 	return registeredFormats
@@ -0,0 +1,66 @@
+package html
+
+// bookStyle is the style of a songbook. The title page, contents and index are each on pages
+// of their own, and every page but the title page is numbered at the bottom. The contents and
+// index give the number of each song, which is printed with its title, and the page it starts
+// on when printed. Browsers that can't count pages leave the page out, and the number stands
+// in for it.
+const bookStyle = `
+        .book-title-page {
+            text-align: center;
+            margin: 30vh 24px 0;
+            page-break-after: always;
+        }
+
+        .book-title {
+            font-size: 40px;
+            border-bottom-style: none;
+        }
+
+        .book-subtitle {
+            font-size: 20px;
+        }
+
+        .book-contents, .book-index {
+            margin: 24px;
+            page-break-after: always;
+        }
+
+        .book-index {
+            page-break-before: always;
+        }
+
+        .book-contents a, .book-index a {
+            color: inherit;
+            text-decoration: none;
+        }
+
+        .book-contents ol {
+            list-style: none;
+            padding-left: 0;
+        }
+
+        .book-page::before {
+            content: ", p. " target-counter(attr(href), page);
+        }
+
+        .book-index-first-line {
+            font-style: italic;
+        }
+
+        .song-number::after {
+            content: ".";
+        }
+
+        @page {
+            @bottom-center {
+                content: counter(page);
+            }
+        }
+
+        @page :first {
+            @bottom-center {
+                content: none;
+            }
+        }
+`
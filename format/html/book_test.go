@@ -0,0 +1,53 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestWriteBookNumbers(t *testing.T) {
+	b := &songtools.Book{
+		Title: "Hymns",
+		Songs: []*songtools.Song{{Title: "Amazing Grace"}, {Title: "Be Thou My Vision"}},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteBook(buf, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	// the pages of each song are numbered along with the rest of the book.
+	for i := range b.Songs {
+		rule := fmt.Sprintf("@page song-%d {", i)
+		start := strings.Index(out, rule)
+		if start == -1 {
+			t.Errorf("expected the book to contain %q", rule)
+			continue
+		}
+		if end := strings.Index(out[start:], "}\n        }"); !strings.Contains(out[start:start+end], "counter(page)") {
+			t.Errorf("expected the pages of song %d to be numbered, but they were %q", i, out[start:start+end])
+		}
+	}
+	if !strings.Contains(out, "@page :first") {
+		t.Errorf("expected the title page not to be numbered")
+	}
+
+	expected := []string{
+		`<li><span class='song-number'>1</span> <a href='#song-0'>Amazing Grace</a><a class='book-page' href='#song-0'></a></li>`,
+		`<li><span class='song-number'>2</span> <a href='#song-1'>Be Thou My Vision</a><a class='book-page' href='#song-1'></a></li>`,
+		`<h1 class='song-title'><span class='song-number'>2</span> Be Thou My Vision</h1>`,
+		`<a href='#song-1'>Be Thou My Vision</a> <span class='book-index-number'>2</span><a class='book-page' href='#song-1'></a>`,
+		// the page each song starts on is counted when the book is printed.
+		`content: ", p. " target-counter(attr(href), page);`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected the book to contain %q", e)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package html
 
 import (
+	"fmt"
 	"io"
+	"io/ioutil"
+	"strconv"
 
 	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/fingering"
 	"github.com/songtools/songtools/format"
 )
 
@@ -18,8 +22,135 @@ func init() {
 	format.Register(f)
 }
 
-type htmlWriter struct{}
+type htmlWriter struct {
+	opts WriteOptions
+}
 
 func (hw *htmlWriter) Write(w io.Writer, s *songtools.Song) error {
-	return WriteSong(w, s)
+	return WriteSongWithOptions(w, s, &hw.opts)
+}
+
+func (hw *htmlWriter) WriteSet(w io.Writer, s *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, s, &hw.opts)
+}
+
+func (hw *htmlWriter) WriteBook(w io.Writer, b *songtools.Book) error {
+	return WriteBookWithOptions(w, b, &hw.opts)
+}
+
+func (hw *htmlWriter) WithOptions(opts format.Options) (format.Writer, error) {
+	if err := opts.Check(numeralsOption, diagramsOption, tuningOption, themeOption, templateOption, cssOption, printOption, columnsOption, fitOption, paperOption, lyricsOption); err != nil {
+		return nil, err
+	}
+
+	numerals, err := opts.Bool(numeralsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	diagrams, err := opts.Bool(diagramsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	var tuning *fingering.Tuning
+	if name, ok := opts[tuningOption]; ok {
+		tuning, err = fingering.ParseTuning(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	theme := opts[themeOption]
+	if _, err := themeStyle(theme); err != nil {
+		return nil, err
+	}
+
+	stylesheet, err := readOptionFile(opts, cssOption)
+	if err != nil {
+		return nil, err
+	}
+
+	paged, err := opts.Bool(printOption)
+	if err != nil {
+		return nil, err
+	}
+
+	fit, err := opts.Bool(fitOption)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := 1
+	if value, ok := opts[columnsOption]; ok {
+		columns, err = strconv.Atoi(value)
+		if err != nil || columns < 1 {
+			return nil, fmt.Errorf("the option %q must be a number of columns, but was %q", columnsOption, value)
+		}
+	}
+
+	lyrics, err := opts.Bool(lyricsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	paper := opts[paperOption]
+	if _, ok := papers[paper]; paper != "" && !ok {
+		return nil, fmt.Errorf("the option %q must be letter or a4, but was %q", paperOption, paper)
+	}
+
+	wo := WriteOptions{
+		Numerals:   numerals,
+		Diagrams:   diagrams,
+		Tuning:     tuning,
+		Theme:      theme,
+		Stylesheet: stylesheet,
+		// the layout of the printed page is only used when printing.
+		Print:   paged || fit || columns > 1 || paper != "",
+		Columns: columns,
+		Fit:     fit,
+		Paper:   paper,
+		Lyrics:  lyrics,
+	}
+
+	wo.Template, err = readOptionFile(opts, templateOption)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseTemplate(&wo); err != nil {
+		return nil, fmt.Errorf("the template %q is invalid: %v", opts[templateOption], err)
+	}
+
+	return &htmlWriter{
+		opts: wo,
+	}, nil
+}
+
+// readOptionFile reads the file named by the option, if it was given.
+func readOptionFile(opts format.Options, name string) (string, error) {
+	path, ok := opts[name]
+	if !ok {
+		return "", nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the %v file: %v", name, err)
+	}
+
+	return string(b), nil
 }
+
+const (
+	numeralsOption = "numerals"
+	diagramsOption = "diagrams"
+	tuningOption   = "tuning"
+	themeOption    = "theme"
+	templateOption = "template"
+	cssOption      = "css"
+	printOption    = "print"
+	columnsOption  = "columns"
+	fitOption      = "fit"
+	paperOption    = "paper"
+	lyricsOption   = "lyrics"
+)
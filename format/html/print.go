@@ -0,0 +1,174 @@
+package html
+
+import (
+	"fmt"
+
+	"github.com/songtools/songtools"
+)
+
+// paperSize is the width and height of a sheet of paper, in millimeters.
+type paperSize struct {
+	width  float64
+	height float64
+}
+
+// papers are the sizes of paper that can be printed on.
+var papers = map[string]paperSize{
+	"letter": {215.9, 279.4},
+	"a4":     {210, 297},
+}
+
+const (
+	defaultPaper = "letter"
+	// pageMargin is the margin, in millimeters, around each printed page. The running headers
+	// are printed in the top margin.
+	pageMargin = 15
+)
+
+// printStyle is the style of a printed page. Each song starts on a new page and sections,
+// along with the blocks in them, are kept on one page or column. The font sizes are relative
+// to the song's font size, which is scaled to fit the song on a page.
+const printStyle = `
+        .song {
+            margin: 0;
+            font-size: calc(11pt * var(--song-scale, 1));
+        }
+
+        .song * {
+            font-size: inherit;
+        }
+
+        .song h1 {
+            font-size: 2em;
+        }
+
+        .song h2 {
+            font-size: 1.1em;
+        }
+
+        section, .song-line-group, .song-grid, .song-tab, .song-diagram {
+            break-inside: avoid;
+            page-break-inside: avoid;
+        }
+`
+
+// writePrintStyle writes the paged media css for the songs. Each song is printed on pages of
+// its own, named for the song, with a running header of its title and key and the page number
+// at the bottom. Songs are as wide on the screen as on the paper, so they are laid out the same
+// way in both.
+func writePrintStyle(songs []*songtools.Song, opts *WriteOptions) (string, error) {
+	paper := opts.Paper
+	if paper == "" {
+		paper = defaultPaper
+	}
+	size, ok := papers[paper]
+	if !ok {
+		return "", fmt.Errorf("unknown paper %q, the papers are letter and a4", paper)
+	}
+
+	buf := printStyle
+	buf += fmt.Sprintf(`
+        @page {
+            size: %v;
+            margin: %dmm;
+        }
+
+        .song {
+            width: %.1fmm;
+        }
+`, paper, pageMargin, size.width-2*pageMargin)
+
+	if opts.Columns > 1 {
+		buf += fmt.Sprintf(`
+        .song-content {
+            column-count: %d;
+            column-gap: 8mm;
+        }
+`, opts.Columns)
+	}
+
+	if opts.Fit {
+		// a song the script has fit is exactly as tall as a page, so its columns fill the page.
+		// Without the script, or when a song doesn't fit, it flows onto more pages instead.
+		buf += fmt.Sprintf(`
+        .song.song-fit {
+            height: %.1fmm;
+            overflow: hidden;
+            display: flex;
+            flex-direction: column;
+        }
+
+        .song-fit .song-content {
+            flex: 1;
+            min-height: 0;
+            column-fill: auto;
+        }
+`, size.height-2*pageMargin-1)
+	}
+
+	for i, s := range songs {
+		key := ""
+		if !s.Key.IsZero() {
+			key = "Key: " + s.Key.String()
+			if s.Capo > 0 {
+				key += fmt.Sprintf(", Capo: %d", s.Capo)
+			}
+		}
+
+		buf += fmt.Sprintf(`
+        @page song-%d {
+            @top-left {
+                content: %v;
+            }
+            @top-right {
+                content: %v;
+            }
+            @bottom-center {
+                content: counter(page);
+            }
+        }
+
+        #song-%d {
+            page: song-%d;
+        }
+`, i, cssString(s.Title), cssString(key), i, i)
+	}
+
+	return buf, nil
+}
+
+// fitScript shrinks the font of each song that overflows its page, down to half its size. Only
+// a song that fits keeps the height of a page. One that still doesn't fit goes back to its
+// full size and flows onto more pages, so none of it is cut off.
+const fitScript = `
+    document.querySelectorAll('.song').forEach(function(song) {
+        var content = song.querySelector('.song-content') || song;
+        var overflows = function() {
+            return song.scrollHeight > song.clientHeight || content.scrollWidth > content.clientWidth;
+        };
+        song.classList.add('song-fit');
+        for (var scale = 0.95; scale >= 0.5 && overflows(); scale -= 0.05) {
+            song.style.setProperty('--song-scale', scale);
+        }
+        if (overflows()) {
+            song.classList.remove('song-fit');
+            song.style.removeProperty('--song-scale');
+        }
+    });
+`
+
+// cssString quotes the text as a css string. Everything other than letters, digits and spaces
+// is escaped, so the text can't end the string or the style element.
+func cssString(text string) string {
+	buf := "\""
+	for _, r := range text {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == ' ':
+			buf += string(r)
+		default:
+			buf += fmt.Sprintf("\\%x ", r)
+		}
+	}
+
+	return buf + "\""
+}
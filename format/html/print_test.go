@@ -0,0 +1,140 @@
+package html
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+// cssRules gets the declarations of each rule in the style by its selector, with the
+// whitespace in both collapsed. Nested rules, such as the margin boxes of a page, are
+// given by their own selector.
+func cssRules(style string) map[string][]string {
+	collapse := func(text string) string {
+		return strings.Join(strings.Fields(text), " ")
+	}
+
+	rules := map[string][]string{}
+	for _, r := range regexp.MustCompile(`([^{};]+)\{([^{}]*)\}`).FindAllStringSubmatch(style, -1) {
+		selector := collapse(r[1])
+		rules[selector] = append(rules[selector], collapse(r[2]))
+	}
+
+	return rules
+}
+
+func TestWritePrintStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     WriteOptions
+		expected map[string]string
+		missing  []string
+	}{
+		{
+			name: "letter",
+			opts: WriteOptions{},
+			expected: map[string]string{
+				"@page": "size: letter; margin: 15mm;",
+				".song": "width: 185.9mm;",
+			},
+			missing: []string{".song-content", ".song.song-fit", ".song-fit .song-content"},
+		},
+		{
+			name: "a4 in columns",
+			opts: WriteOptions{Paper: "a4", Columns: 3},
+			expected: map[string]string{
+				"@page":         "size: a4; margin: 15mm;",
+				".song":         "width: 180.0mm;",
+				".song-content": "column-count: 3; column-gap: 8mm;",
+			},
+			missing: []string{".song.song-fit"},
+		},
+		{
+			name:    "one column",
+			opts:    WriteOptions{Columns: 1},
+			missing: []string{".song-content"},
+		},
+		{
+			name: "fit on letter",
+			opts: WriteOptions{Fit: true, Columns: 2},
+			expected: map[string]string{
+				".song-content":           "column-count: 2; column-gap: 8mm;",
+				".song.song-fit":          "height: 248.4mm; overflow: hidden; display: flex; flex-direction: column;",
+				".song-fit .song-content": "flex: 1; min-height: 0; column-fill: auto;",
+			},
+		},
+		{
+			name: "fit on a4",
+			opts: WriteOptions{Fit: true, Paper: "a4"},
+			expected: map[string]string{
+				".song.song-fit": "height: 266.0mm; overflow: hidden; display: flex; flex-direction: column;",
+			},
+			missing: []string{".song-content"},
+		},
+	}
+
+	for _, test := range tests {
+		style, err := writePrintStyle([]*songtools.Song{{Title: "One"}}, &test.opts)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		rules := cssRules(style)
+
+		for selector, declarations := range test.expected {
+			found := false
+			for _, d := range rules[selector] {
+				found = found || d == declarations
+			}
+			if !found {
+				t.Errorf("%v: expected %v { %v }, but found %q", test.name, selector, declarations, rules[selector])
+			}
+		}
+		for _, selector := range test.missing {
+			if _, ok := rules[selector]; ok {
+				t.Errorf("%v: expected no rule for %v, but found %q", test.name, selector, rules[selector])
+			}
+		}
+
+		// only songs the script has fit may be cut to the height of a page.
+		for selector, declarations := range rules {
+			for _, d := range declarations {
+				if (strings.Contains(d, "overflow: hidden") || strings.HasPrefix(d, "height:")) && !strings.Contains(selector, "song-fit") {
+					t.Errorf("%v: expected %v { %v } to only apply to songs that fit", test.name, selector, d)
+				}
+			}
+		}
+	}
+
+	if _, err := writePrintStyle(nil, &WriteOptions{Paper: "legal"}); err == nil {
+		t.Errorf("expected an error for an unknown paper")
+	}
+}
+
+func TestWritePrintStyleSongPages(t *testing.T) {
+	songs := []*songtools.Song{
+		{Title: "Amazing Grace", Key: songtools.MustParseKey("G"), Capo: 2},
+		{Title: "Untitled"},
+	}
+
+	style, err := writePrintStyle(songs, &WriteOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rules := cssRules(style)
+
+	expected := map[string][]string{
+		"#song-0":        {"page: song-0;"},
+		"#song-1":        {"page: song-1;"},
+		"@top-left":      {`content: "Amazing Grace";`, `content: "Untitled";`},
+		"@top-right":     {`content: "Key\3a G\2c Capo\3a 2";`, `content: "";`},
+		"@bottom-center": {"content: counter(page);", "content: counter(page);"},
+	}
+	for selector, declarations := range expected {
+		if strings.Join(rules[selector], "|") != strings.Join(declarations, "|") {
+			t.Errorf("expected %v to be %q, but was %q", selector, declarations, rules[selector])
+		}
+	}
+}
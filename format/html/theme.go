@@ -0,0 +1,129 @@
+package html
+
+import (
+	"fmt"
+	"sort"
+)
+
+// themes are the built in styles, which are added to the base style.
+var themes = map[string]string{
+	"": "",
+
+	"print": `
+        @page {
+            margin: 15mm;
+        }
+
+        .song {
+            margin: 0;
+        }
+
+        section, .song-line-group, .song-grid, .song-tab {
+            page-break-inside: avoid;
+        }
+`,
+
+	"dark": `
+        body {
+            background-color: #121212;
+            color: #e0e0e0;
+        }
+
+        h1 {
+            border-bottom-color: #e0e0e0;
+        }
+
+        .song-chord-line {
+            color: #ffb74d;
+        }
+
+        .song-numeral-line {
+            color: #90caf9;
+        }
+
+        .chord-diagram {
+            filter: invert(1);
+        }
+`,
+
+	"large-print": `
+        * {
+            font-size: 20px;
+        }
+
+        h1, h1 * {
+            font-size: 32px;
+        }
+
+        h2 {
+            font-size: 22px;
+        }
+
+        .song-grid-beat {
+            min-width: 4em;
+        }
+`,
+
+	"projector": `
+        body {
+            background-color: black;
+            color: white;
+            text-align: center;
+        }
+
+        * {
+            font-family: sans-serif;
+            font-size: 36px;
+        }
+
+        h1 {
+            border-bottom-style: none;
+        }
+
+        h1, h1 * {
+            font-size: 48px;
+        }
+
+        h2 {
+            font-size: 28px;
+            text-decoration: none;
+            color: #bdbdbd;
+        }
+
+        .song + .song {
+            page-break-before: auto;
+        }
+
+        .song-line-group {
+            white-space: normal;
+        }
+
+        header ul, .song-key, .song-capo, .song-key-change, .song-diagrams, .song-chord-line,
+        .song-numeral-line, .song-tab, .song-grid {
+            display: none;
+        }
+`,
+}
+
+// ThemeNames returns the names of the built in themes.
+func ThemeNames() []string {
+	names := []string{}
+	for name := range themes {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// themeStyle gets the style of the named theme.
+func themeStyle(name string) (string, error) {
+	style, ok := themes[name]
+	if !ok {
+		return "", fmt.Errorf("unknown theme %q, the themes are %v", name, ThemeNames())
+	}
+
+	return baseStyle + style, nil
+}
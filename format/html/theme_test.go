@@ -0,0 +1,131 @@
+package html
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
+)
+
+func themeSong() *songtools.Song {
+	return &songtools.Song{Title: "Grace", Nodes: []songtools.SongNode{
+		&songtools.Section{Kind: "Verse", Nodes: []songtools.SectionNode{&songtools.Line{Text: "Amazing grace"}}},
+		&songtools.Section{Kind: "Chorus", Nodes: []songtools.SectionNode{&songtools.Line{Text: "How sweet"}}},
+	}}
+}
+
+// writeWithOptions writes the song with the writer the options configure.
+func writeWithOptions(opts format.Options) (string, error) {
+	w, err := (&htmlWriter{}).WithOptions(opts)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := w.Write(buf, themeSong()); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func TestThemes(t *testing.T) {
+	names := ThemeNames()
+	if !sort.StringsAreSorted(names) || len(names) != len(themes)-1 {
+		t.Errorf("ThemeNames() = %v, expected the sorted names of the themes", names)
+	}
+
+	for _, name := range append(names, "") {
+		out, err := writeWithOptions(format.Options{themeOption: name})
+		if err != nil {
+			t.Errorf("theme %q: unexpected error: %v", name, err)
+			continue
+		}
+
+		// every theme adds to the base style.
+		if !strings.Contains(out, baseStyle+themes[name]) {
+			t.Errorf("theme %q: expected the style to be the base style and the theme's", name)
+		}
+	}
+
+	_, err := writeWithOptions(format.Options{themeOption: "neon"})
+	if err == nil || !strings.Contains(err.Error(), `unknown theme "neon"`) {
+		t.Errorf("expected an error for an unknown theme, but was %v", err)
+	}
+}
+
+func TestTemplateAndCSSOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "html")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return path
+	}
+	css := file("song.css", ".song-chorus { color: red; }")
+	tmpl := file("song.html", `{{define "song"}}<style>{{.Style}}</style>{{range .Songs}}<h1>{{.Title}}</h1>{{range $i, $s := Sections .}}<div data-section='{{$i}}'>{{$s}}</div>{{end}}{{end}}{{end}}`)
+	invalid := file("invalid.html", `{{define "song"}}{{range .Songs}}{{end}}`)
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name     string
+		opts     format.Options
+		expected []string
+		err      string
+	}{
+		{
+			name:     "css after the theme",
+			opts:     format.Options{themeOption: "dark", cssOption: css},
+			expected: []string{themes["dark"] + ".song-chorus { color: red; }"},
+		},
+		{
+			name: "template",
+			opts: format.Options{templateOption: tmpl},
+			expected: []string{
+				"<h1>Grace</h1>",
+				"<div data-section='1'><section class='song-chorus'>",
+			},
+		},
+		{
+			name:     "template and css",
+			opts:     format.Options{templateOption: tmpl, cssOption: css},
+			expected: []string{".song-chorus { color: red; }</style><h1>Grace</h1>"},
+		},
+		{name: "missing css", opts: format.Options{cssOption: missing}, err: "unable to read the css file"},
+		{name: "missing template", opts: format.Options{templateOption: missing}, err: "unable to read the template file"},
+		{name: "invalid template", opts: format.Options{templateOption: invalid}, err: "is invalid"},
+		{name: "unknown option", opts: format.Options{"colour": "red"}, err: "colour"},
+	}
+
+	for _, test := range tests {
+		out, err := writeWithOptions(test.opts)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: expected an error about %q, but was %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		for _, e := range test.expected {
+			if !strings.Contains(out, e) {
+				t.Errorf("%v: expected the page to contain %q, but was %q", test.name, e, out)
+			}
+		}
+	}
+}
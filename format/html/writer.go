@@ -1,14 +1,21 @@
 package html
 
 import (
+	"bytes"
+	"html/template"
 	"io"
+	"strconv"
 	"strings"
-	"text/template"
+	"unicode/utf8"
 
 	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/analysis"
+	"github.com/songtools/songtools/diagram"
+	"github.com/songtools/songtools/fingering"
 )
 
 const (
+	// songTemplate is the page of songs. It is executed with a page.
 	songTemplate = `<!DOCTYPE html>
 <html>
 <head>
@@ -16,13 +23,88 @@ const (
     <meta charset="utf-8">
     <meta name="viewport" content="width=device-width, intial-scale=1.0">
     <style>
-        * {
+{{.Style}}    </style>
+</head>
+<body>
+{{with .Book}}
+<div class='book-title-page'>
+    <h1 class='book-title'>{{.Title}}</h1>
+    {{with .Subtitle}}
+    <div class='book-subtitle'>{{.}}</div>
+    {{end}}
+</div>
+<nav class='book-contents'>
+    <h2>Contents</h2>
+    <ol>
+    {{range $i, $song := .Songs}}
+        <li><span class='song-number'>{{Number $i}}</span> <a href='#song-{{$i}}'>{{.Title}}</a><a class='book-page' href='#song-{{$i}}'></a></li>
+    {{end}}
+    </ol>
+</nav>
+{{end}}
+{{range $i, $song := .Songs}}
+<div class='song' id='song-{{$i}}'>
+    <header>
+    {{if $.Book}}
+        <h1 class='song-title'><span class='song-number'>{{Number $i}}</span> {{.Title}}</h1>
+    {{else if .Title}}
+        <h1 class='song-title'>{{.Title}}</h1>
+    {{end}}
+    {{if .Subtitles}}
+        <ul class='song-subtitles'>
+        {{range .Subtitles}}
+            <li>{{.}}</li>
+        {{end}}
+        </ul>
+    {{end}}
+    {{if .Authors}}
+        <ul class='song-authors'>
+        {{range .Authors}}
+            <li>{{.}}</li>
+        {{end}}
+        </ul>
+    {{end}}
+    {{if not .Key.IsZero}}
+        <div class='song-key'>{{.Key}}</div>
+    {{end}}
+    {{if .Capo}}
+        <div class='song-capo'>{{.Capo}}</div>
+    {{end}}
+    {{with Diagrams .}}
+        <div class='song-diagrams'>{{.}}</div>
+    {{end}}
+    </header>
+    <div class='song-content'>
+        {{Content .}}
+    </div>
+</div>
+{{end}}
+{{with .Book}}
+<nav class='book-index'>
+    <h2>Index</h2>
+    {{range .Index}}
+    <div class='book-index-entry{{if .FirstLine}} book-index-first-line{{end}}'><a href='#song-{{.Song}}'>{{.Text}}</a> <span class='book-index-number'>{{Number .Song}}</span><a class='book-page' href='#song-{{.Song}}'></a></div>
+    {{end}}
+</nav>
+{{end}}
+{{with .Script}}
+<script>{{.}}</script>
+{{end}}
+</body>
+</html>`
+
+	// baseStyle is the style of every theme.
+	baseStyle = `        * {
             font-family: monospace;
         }
         
         .song {
             margin: 24px;
         }
+
+        .song + .song {
+            page-break-before: always;
+        }
         
         h1 {
             font-size: 26px;
@@ -71,6 +153,53 @@ const (
             content: "Key: "
         }
         
+        .song-capo::before {
+            content: "Capo: "
+        }
+        
+        .song-diagrams {
+            display: flex;
+            flex-wrap: wrap;
+            margin: 8px 0;
+        }
+        
+        .song-diagram {
+            margin-right: 8px;
+        }
+        
+        .song-tab {
+            font-family: monospace;
+            margin: 0;
+        }
+        
+        .song-grid {
+            border-collapse: collapse;
+            margin: 4px 0;
+        }
+        
+        .song-grid td {
+            padding: 0 4px;
+            white-space: pre;
+        }
+        
+        .song-grid-beat {
+            display: inline-block;
+            min-width: 3em;
+            font-weight: bold;
+        }
+        
+        .song-grid-comment {
+            font-style: italic;
+        }
+        
+        .song-key-change {
+            font-weight: bold;
+        }
+        
+        .song-key-change::before {
+            content: "Key: "
+        }
+        
         .song-comment {
             font-style: italic;
             font-weight: bold;
@@ -84,139 +213,495 @@ const (
         .song-chord-line {
             font-weight: bold;
         }
-    </style>
-</head>
-<body class='song'>
-    <header>
-    {{if .Title}}
-        <h1 class='song-title'>{{.Title}}</h1>
-    {{end}}
-    {{if .Subtitles}}
-        <ul class='song-subtitles'>
-        {{range .Subtitles}}
-            <li>{{.}}</li>
-        {{end}}
-        </ul>
-    {{end}}
-    {{if .Authors}}
-        <ul class='song-authors'>
-        {{range .Authors}}
-            <li>{{.}}</li>
-        {{end}}
-        </ul>
-    {{end}}
-    {{if .Key}}
-        <div class='song-key'>{{.Key}}</div>
-    {{end}}
-    </header>
-    <div class='song-content'>
-        {{Content .}}
-    </div>
-</body>
-</html>`
+
+        .song-numeral-line {
+            font-style: italic;
+        }
+`
 )
 
+// WriteOptions control how a song is written.
+type WriteOptions struct {
+	// Numerals writes the Roman numeral of each chord, in the song's key, above the chords.
+	Numerals bool
+	// Diagrams writes a legend with a chord diagram for each different chord in the song.
+	Diagrams bool
+	// Tuning is the instrument the diagrams are for. It is the guitar when not given.
+	Tuning *fingering.Tuning
+	// Theme is the name of one of the built in styles, such as dark or projector. It is the
+	// plain style when not given.
+	Theme string
+	// Stylesheet is css added after the theme's style, so it can override it.
+	Stylesheet string
+	// Template is the text of an html/template to write the page with instead of the built in
+	// one. It is executed with the page, which has the Title, Songs, Style and Script of the page,
+	// along with the Book when writing a songbook. Each song should have an id of song- followed
+	// by its index for printing. The template can use the functions Content, which writes the
+	// nodes of a song, Sections, which writes each node of a song separately, Diagrams, which
+	// writes the chord diagrams of a song when they are turned on, and Number, which turns the
+	// index of a song into its number.
+	Template string
+	// Print writes css for printing the page. Sections aren't split across pages and each
+	// page has a running header with the song's title and key.
+	Print bool
+	// Columns is the number of columns to print each song in.
+	Columns int
+	// Fit shrinks the text of each song that doesn't fit on a single printed page. A song that
+	// won't fit at half its size is printed at its full size on more pages.
+	Fit bool
+	// Paper is the size of the printed page, letter or a4. It is letter when not given.
+	Paper string
+	// Lyrics writes just the words of the songs, without their chords.
+	Lyrics bool
+}
+
+// page is the data given to the template.
+type page struct {
+	Title string
+	Songs []*songtools.Song
+	// Style is the css of the theme along with the stylesheet.
+	Style template.CSS
+	// Script is the javascript run once the page has loaded.
+	Script template.JS
+	// Book is the songbook being written, if any. It adds a title page, contents and index.
+	Book *songtools.Book
+}
+
+// songWriter writes a single song.
+type songWriter struct {
+	opts *WriteOptions
+	song *songtools.Song
+	// key is the key in effect at the node being written.
+	key songtools.Key
+}
+
 // WriteSong writes a single song to the writer.
 func WriteSong(w io.Writer, s *songtools.Song) error {
+	return WriteSongWithOptions(w, s, &WriteOptions{})
+}
+
+// WriteSongWithOptions writes a single song to the writer using the options.
+func WriteSongWithOptions(w io.Writer, s *songtools.Song, opts *WriteOptions) error {
+	return writePage(w, &page{
+		Title: s.Title,
+		Songs: []*songtools.Song{s},
+	}, opts)
+}
+
+// WriteSongSet writes all the songs in the set to a single page.
+func WriteSongSet(w io.Writer, set *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, set, &WriteOptions{})
+}
+
+// WriteSongSetWithOptions writes all the songs in the set to a single page using the options.
+func WriteSongSetWithOptions(w io.Writer, set *songtools.SongSet, opts *WriteOptions) error {
+	titles := []string{}
+	for _, s := range set.Songs {
+		if s.Title != "" {
+			titles = append(titles, s.Title)
+		}
+	}
+
+	return writePage(w, &page{
+		Title: strings.Join(titles, " / "),
+		Songs: set.Songs,
+	}, opts)
+}
+
+// WriteBook writes the songbook to a single page.
+func WriteBook(w io.Writer, b *songtools.Book) error {
+	return WriteBookWithOptions(w, b, &WriteOptions{})
+}
+
+// WriteBookWithOptions writes the songbook to a single page using the options. A book is
+// always laid out for printing, with each song starting on a new page.
+func WriteBookWithOptions(w io.Writer, b *songtools.Book, opts *WriteOptions) error {
+	printed := *opts
+	printed.Print = true
+
+	return writePage(w, &page{
+		Title: b.Title,
+		Songs: b.Songs,
+		Book:  b,
+	}, &printed)
+}
+
+func writePage(w io.Writer, p *page, opts *WriteOptions) error {
+	if opts.Lyrics {
+		songs := []*songtools.Song{}
+		for _, s := range p.Songs {
+			songs = append(songs, songtools.LyricsOnly(s))
+		}
+		p.Songs = songs
+
+		if p.Book != nil {
+			book := *p.Book
+			book.Songs = songs
+			p.Book = &book
+		}
+	}
+
+	t, err := parseTemplate(opts)
+	if err != nil {
+		return err
+	}
+
+	style, err := themeStyle(opts.Theme)
+	if err != nil {
+		return err
+	}
+	if opts.Print {
+		paged, err := writePrintStyle(p.Songs, opts)
+		if err != nil {
+			return err
+		}
+		style += paged
+
+		if opts.Fit {
+			p.Script = template.JS(fitScript)
+		}
+	}
+	if p.Book != nil {
+		style += bookStyle
+	}
+	p.Style = template.CSS(style + opts.Stylesheet)
+
+	return t.ExecuteTemplate(w, "song", p)
+}
+
+// parseTemplate parses the template given in the options, or the built in one.
+func parseTemplate(opts *WriteOptions) (*template.Template, error) {
+	text := songTemplate
+	if opts.Template != "" {
+		text = opts.Template
+	}
+
 	funcs := make(map[string]interface{})
-	funcs["Content"] = writeContent
-	t := template.Must(template.New("song").Funcs(funcs).Parse(songTemplate))
+	// the content is markup written by the content templates, which escape the text of the song.
+	funcs["Content"] = func(s *songtools.Song) (template.HTML, error) {
+		sw := &songWriter{
+			opts: opts,
+			song: s,
+			key:  s.Key,
+		}
+		return sw.writeContent()
+	}
+	funcs["Sections"] = func(s *songtools.Song) ([]template.HTML, error) {
+		sw := &songWriter{
+			opts: opts,
+			song: s,
+			key:  s.Key,
+		}
+		sections := []template.HTML{}
+		for _, n := range s.Nodes {
+			section, err := sw.writeSongNode(n)
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, section)
+		}
+		return sections, nil
+	}
+	funcs["Number"] = func(i int) int {
+		return i + 1
+	}
+	funcs["Diagrams"] = func(s *songtools.Song) (template.HTML, error) {
+		if !opts.Diagrams {
+			return "", nil
+		}
+		return writeDiagrams(s, opts.Tuning)
+	}
+
+	return template.New("song").Funcs(funcs).Parse(text)
+}
+
+// contentTemplates write the nodes of a song. Each is given plain text, which it escapes.
+var contentTemplates = template.Must(template.New("content").Parse(`
+{{- define "diagrams"}}{{range .}}<div class='song-diagram'>{{.}}</div>{{end}}{{end -}}
+{{- define "section"}}<section class='song-{{.Class}}'>{{with .Heading}}<h2 class='song-section-kind'>{{.}}</h2>{{end}}{{range .Nodes}}{{.}}{{end}}</section>{{end -}}
+{{- define "comment"}}<div class='song-comment'>{{.}}</div>{{end -}}
+{{- define "keyChange"}}<div class='song-key-change'>{{.}}</div>{{end -}}
+{{- define "tab"}}<pre class='song-tab'>{{.}}</pre>{{end -}}
+{{- define "grid"}}<table class='song-grid'>{{range .Rows}}<tr>{{if $.Labels}}<td class='song-grid-label'>{{.Label}}</td>{{end}}{{range .Bars}}<td class='song-grid-barline'>{{.Barline}}</td><td class='song-grid-bar'>{{range .Beats}}<span class='song-grid-beat'>{{.}}</span>{{end}}</td>{{end}}<td class='song-grid-barline'>{{.End}}</td>{{with .Comment}}<td class='song-grid-comment'>{{.}}</td>{{end}}</tr>{{end}}</table>{{end -}}
+{{- define "line"}}<div class='song-line-group'>{{with .Numerals}}<div class='song-numeral-line'>{{range .}}{{.Pad}}<span class='song-numeral'>{{.Text}}</span>{{end}}</div>{{end}}{{if .Chords}}<div class='song-chord-line'>{{range .Chords}}{{.Pad}}<span class='song-chord'>{{.Text}}</span>{{end}}</div>{{else if .Blank}}<div class='song-chord-line'> </div>{{end}}<div class='song-lyric-line'>{{.Text}}</div></div>{{end -}}
+`))
+
+// executeContent executes the named content template with the data.
+func executeContent(name string, data interface{}) (template.HTML, error) {
+	buf := &bytes.Buffer{}
+	if err := contentTemplates.ExecuteTemplate(buf, name, data); err != nil {
+		return "", err
+	}
+
+	// the template has escaped the data, so its output is safe.
+	return template.HTML(buf.String()), nil
+}
+
+// writeDiagrams writes a chord diagram for each different chord in the song, using the song's
+// own definitions where it has them.
+func writeDiagrams(s *songtools.Song, t *fingering.Tuning) (template.HTML, error) {
+	if t == nil {
+		t = fingering.Guitar
+	}
 
-	return t.ExecuteTemplate(w, "song", s)
+	svgs := []template.HTML{}
+	for _, d := range fingering.SongDefinitions(s, t, nil) {
+		svg, err := diagram.SVG(d)
+		if err != nil {
+			continue
+		}
+		// the svg is markup drawn by the diagram package rather than text from the song.
+		svgs = append(svgs, template.HTML(svg))
+	}
+
+	return executeContent("diagrams", svgs)
 }
 
-func writeContent(s *songtools.Song) string {
-	buf := ""
-	for _, n := range s.Nodes {
-		buf += writeSongNode(n)
+func (sw *songWriter) writeContent() (template.HTML, error) {
+	var buf template.HTML
+	for _, n := range sw.song.Nodes {
+		node, err := sw.writeSongNode(n)
+		if err != nil {
+			return "", err
+		}
+		buf += node
 	}
-	return buf
+
+	return buf, nil
 }
 
-func writeSongNode(n songtools.SongNode) string {
-	buf := ""
+func (sw *songWriter) writeSongNode(n songtools.SongNode) (template.HTML, error) {
 	switch typedN := n.(type) {
 	case *songtools.Comment:
-		buf += writeComment(typedN)
+		return writeComment(typedN)
+	case *songtools.KeyChange:
+		return sw.writeKeyChange(typedN)
 	case *songtools.Section:
+		return sw.writeSection(typedN)
+	default:
+		return "", nil
+	}
+}
 
-		anyChords := len(typedN.Chords()) > 0
-		for i, sn := range typedN.Nodes {
-			if i == 0 {
-				if typedN.Kind != "" {
-					// only use the first word for the css class
-					kind := strings.ToLower(strings.Split(string(typedN.Kind), " ")[0])
-					buf += "<section class='song-" + kind + "'>"
-
-					kind = string(typedN.Kind)
-					cont := false
-					if c, ok := sn.(*songtools.Comment); ok {
-						kind += " " + c.Text
-						cont = true
-					}
-
-					buf += "<h2 class='song-section-kind'>" + kind + "</h2>"
-					if cont {
-						continue
-					}
-				} else {
-					buf += "<section class='song-verse'>"
-				}
+// sectionData is the data of the section template.
+type sectionData struct {
+	Class   string
+	Heading string
+	Nodes   []template.HTML
+}
+
+// writeSection writes the section with a heading for its kind. A comment at the start of the
+// section is written as part of the heading.
+func (sw *songWriter) writeSection(s *songtools.Section) (template.HTML, error) {
+	data := &sectionData{
+		Class: "verse",
+	}
+
+	nodes := s.Nodes
+	if s.Kind != "" {
+		data.Class = sectionClass(s.Kind)
+		data.Heading = s.Heading()
+		if len(nodes) > 0 {
+			if c, ok := nodes[0].(*songtools.Comment); ok {
+				data.Heading += " " + c.Text
+				nodes = nodes[1:]
 			}
+		}
+	}
 
-			buf += writeSectionNode(sn, anyChords)
+	anyChords := len(s.Chords()) > 0
+	for _, sn := range nodes {
+		node, err := sw.writeSectionNode(sn, anyChords)
+		if err != nil {
+			return "", err
 		}
+		data.Nodes = append(data.Nodes, node)
+	}
+
+	return executeContent("section", data)
+}
+
+// sectionClass turns the kind of section into a name for its css class. Only the first word is
+// used and anything other than letters, digits, dashes and underscores is left out, so Pre Chorus
+// is pre. Kinds with nothing left are just a section.
+func sectionClass(kind songtools.SectionKind) string {
+	class := ""
+	if words := strings.Fields(string(kind)); len(words) > 0 {
+		for _, r := range strings.ToLower(words[0]) {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+				class += string(r)
+			}
+		}
+	}
 
-		buf += "</section>"
+	if class == "" {
+		return "section"
 	}
 
-	return buf
+	return class
 }
 
-func writeSectionNode(n songtools.SectionNode, blankLineForNoChords bool) string {
+func (sw *songWriter) writeSectionNode(n songtools.SectionNode, blankLineForNoChords bool) (template.HTML, error) {
 	switch typedN := n.(type) {
 	case *songtools.Comment:
 		return writeComment(typedN)
+	case *songtools.KeyChange:
+		return sw.writeKeyChange(typedN)
+	case *songtools.Tab:
+		return writeTab(typedN)
+	case *songtools.Grid:
+		return writeGrid(typedN)
 	case *songtools.Line:
-		return writeLine(typedN, blankLineForNoChords)
+		return sw.writeLine(typedN, blankLineForNoChords)
 	default:
-		return ""
+		return "", nil
 	}
 }
 
-func writeComment(c *songtools.Comment) string {
-	buf := ""
-	if !c.Hidden {
-		buf += "<div class='song-comment'>"
-		buf += c.Text
-		buf += "</div>"
-	}
-	return buf
+// writeTab writes the tab as a preformatted block.
+func writeTab(t *songtools.Tab) (template.HTML, error) {
+	return executeContent("tab", strings.Join(t.Lines, "\n"))
+}
+
+// gridData is the data of the grid template.
+type gridData struct {
+	// Labels indicates whether any of the rows have a label, in which case each row has a
+	// column for it.
+	Labels bool
+	Rows   []*gridRowData
+}
+
+type gridRowData struct {
+	Label   string
+	Bars    []*gridBarData
+	End     string
+	Comment string
+}
+
+type gridBarData struct {
+	Barline string
+	Beats   []string
 }
 
-func writeLine(l *songtools.Line, blankLineForNoChords bool) string {
-	buf := "<div class='song-line-group'>"
-	if l.Chords != nil {
-		buf += "<div class='song-chord-line'>"
-		pos := 0
-		for i := 0; i < len(l.Chords); i++ {
-			diff := l.ChordPositions[i] - pos
-			if i > 0 && diff <= 0 {
-				diff = l.ChordPositions[i] - l.ChordPositions[i-1]
+// writeGrid writes the grid as a table with a column for each barline and bar, so the bars
+// of each row line up.
+func writeGrid(g *songtools.Grid) (template.HTML, error) {
+	data := &gridData{}
+	for _, r := range g.Rows {
+		data.Labels = data.Labels || r.Label != ""
+
+		row := &gridRowData{
+			Label:   r.Label,
+			End:     r.End.String(),
+			Comment: r.Comment,
+		}
+		for _, b := range r.Bars {
+			bar := &gridBarData{
+				Barline: b.Barline.String(),
+			}
+			if b.Volta > 0 {
+				bar.Barline += strconv.Itoa(b.Volta)
+			}
+			if b.Simile {
+				bar.Beats = append(bar.Beats, "%")
+			}
+			for i, c := range b.Beats {
+				beat := "."
+				if b.Slash(i) {
+					beat = "/"
+				} else if c != nil {
+					beat = c.Name
+				}
+				bar.Beats = append(bar.Beats, beat)
 			}
-			buf += strings.Repeat(" ", diff)
-			buf += "<span class='song-chord'>"
-			buf += l.Chords[i].Name
-			buf += "</span>"
-			pos = l.ChordPositions[i] + len(l.Chords[i].Name)
+			row.Bars = append(row.Bars, bar)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	return executeContent("grid", data)
+}
+
+func writeComment(c *songtools.Comment) (template.HTML, error) {
+	if c.Hidden {
+		return "", nil
+	}
+
+	return executeContent("comment", c.Text)
+}
+
+// writeKeyChange writes the key change, and the numerals that follow are in the new key.
+func (sw *songWriter) writeKeyChange(k *songtools.KeyChange) (template.HTML, error) {
+	sw.key = k.Key
+	return executeContent("keyChange", k.Key.String())
+}
+
+// lineData is the data of the line template.
+type lineData struct {
+	Numerals []*markData
+	Chords   []*markData
+	// Blank indicates the line has an empty chord line above it, so it lines up with the
+	// other lines of its section.
+	Blank bool
+	Text  string
+}
+
+// markData is a chord or a numeral above a line, with the spaces that put it in place.
+type markData struct {
+	Pad  string
+	Text string
+}
+
+func (sw *songWriter) writeLine(l *songtools.Line, blankLineForNoChords bool) (template.HTML, error) {
+	data := &lineData{
+		Blank: l.Chords == nil && blankLineForNoChords,
+		Text:  l.Text,
+	}
+
+	if l.Chords != nil && sw.opts.Numerals {
+		data.Numerals = sw.numerals(l)
+	}
+
+	pos := 0
+	for i, c := range l.Chords {
+		diff := l.ChordPositions[i] - pos
+		if i > 0 && diff <= 0 {
+			diff = l.ChordPositions[i] - l.ChordPositions[i-1]
+		}
+		data.Chords = append(data.Chords, &markData{
+			Pad:  strings.Repeat(" ", diff),
+			Text: c.Name,
+		})
+		pos = l.ChordPositions[i] + len(c.Name)
+	}
+
+	return executeContent("line", data)
+}
+
+// numerals gets the Roman numerals of the line's chords, lined up with the chords.
+func (sw *songWriter) numerals(l *songtools.Line) []*markData {
+	numerals := []*markData{}
+	width := 0
+	for i, c := range l.Chords {
+		numeral := "?"
+		if n, ok := analysis.AnalyzeChord(c, sw.key); ok {
+			numeral = n.String()
+		}
+
+		pad := ""
+		if n := l.ChordPositions[i] - width; n > 0 {
+			pad = strings.Repeat(" ", n)
+			width += n
+		} else if i > 0 {
+			pad = " "
+			width++
 		}
-		buf += "</div>"
-	} else if blankLineForNoChords {
-		buf += "<div class='song-chord-line'> </div>"
+		numerals = append(numerals, &markData{
+			Pad:  pad,
+			Text: numeral,
+		})
+		width += utf8.RuneCountInString(numeral)
 	}
 
-	buf += "<div class='song-lyric-line'>"
-	buf += l.Text
-	buf += "</div></div>"
-	return buf
+	return numerals
 }
@@ -0,0 +1,119 @@
+package html
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestWriteSongEscapesText(t *testing.T) {
+	hostile := "<script>alert('&')</script>"
+	s := &songtools.Song{
+		Title:     hostile,
+		Subtitles: []string{`<img src=x onerror="alert(1)">`},
+		Key:       songtools.MustParseKey("G"),
+		Nodes: []songtools.SongNode{
+			&songtools.Section{Kind: "Verse", Label: hostile, Nodes: []songtools.SectionNode{
+				&songtools.Line{
+					Text:           `<img src=x onerror="alert(1)"> & "you"`,
+					Chords:         []*songtools.Chord{songtools.UnknownChord("<b>")},
+					ChordPositions: []int{0},
+				},
+				&songtools.Comment{Text: hostile},
+			}},
+			&songtools.Section{Kind: "Intro", Nodes: []songtools.SectionNode{
+				&songtools.Grid{Rows: []*songtools.GridRow{
+					{Label: "<i>", Bars: []*songtools.Bar{{Barline: songtools.SingleBarline}}, End: songtools.SingleBarline, Comment: hostile},
+				}},
+			}},
+		},
+	}
+
+	for _, print := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if err := WriteSongWithOptions(buf, s, &WriteOptions{Print: print}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out := buf.String()
+
+		for _, raw := range []string{"<script>", "<img", "<b>", "<i>", `"you"`, "'&'"} {
+			if strings.Contains(out, raw) {
+				t.Errorf("print: %v, expected %q to be escaped in %q", print, raw, out)
+			}
+		}
+
+		if strings.Count(out, "</style>") != 1 {
+			t.Errorf("print: %v, expected the style to be closed just once", print)
+		}
+
+		escaped := []string{
+			"&lt;script&gt;alert(&#39;&amp;&#39;)&lt;/script&gt;",
+			"&lt;img src=x onerror=&#34;alert(1)&#34;&gt; &amp; &#34;you&#34;",
+			"<span class='song-chord'>&lt;b&gt;</span>",
+			"<td class='song-grid-label'>&lt;i&gt;</td>",
+			"<td class='song-grid-comment'>&lt;script&gt;",
+			"<div class='song-comment'>&lt;script&gt;",
+		}
+		for _, e := range escaped {
+			if !strings.Contains(out, e) {
+				t.Errorf("print: %v, expected the song to contain %q", print, e)
+			}
+		}
+	}
+}
+
+func TestWritePrintHeadersEscapeText(t *testing.T) {
+	style, err := writePrintStyle([]*songtools.Song{{Title: `</style><script>"x"\`}}, &WriteOptions{Print: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `content: "\3c \2f style\3e \3c script\3e \22 x\22 \5c ";`
+	if !strings.Contains(style, expected) {
+		t.Errorf("expected the header to be %q, but the style was %q", expected, style)
+	}
+}
+
+func TestSectionClass(t *testing.T) {
+	tests := []struct {
+		kind     songtools.SectionKind
+		expected string
+	}{
+		{"Chorus", "chorus"},
+		{"Pre Chorus", "pre"},
+		{"Pre-Chorus", "pre-chorus"},
+		{"verse_2", "verse_2"},
+		{"Chorus' onclick='alert(1)", "chorus"},
+		{"x'><script>alert(1)</script>", "xscriptalert1script"},
+		{"\"><img", "img"},
+		{"<>", "section"},
+		{"Ünterbrechung", "nterbrechung"},
+		{"   ", "section"},
+	}
+
+	for _, test := range tests {
+		if actual := sectionClass(test.kind); actual != test.expected {
+			t.Errorf("sectionClass(%q) = %q, expected %q", test.kind, actual, test.expected)
+		}
+	}
+}
+
+func TestWriteSongTabs(t *testing.T) {
+	s := &songtools.Song{Nodes: []songtools.SongNode{
+		&songtools.Section{Kind: "Tab", Nodes: []songtools.SectionNode{
+			&songtools.Tab{Lines: []string{"e|--0--<5>--|", "B|--1--&--|"}},
+		}},
+	}}
+
+	buf := &bytes.Buffer{}
+	if err := WriteSong(buf, s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "<pre class='song-tab'>e|--0--&lt;5&gt;--|\nB|--1--&amp;--|</pre>"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected the song to contain %q, but got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,59 @@
+package lyrics
+
+import (
+	"io"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
+)
+
+func init() {
+	rw := &lyricsWriter{}
+	f := &format.Format{
+		Name:       "lyrics",
+		Writer:     rw,
+		Extensions: []string{".txt"},
+	}
+
+	format.Register(f)
+}
+
+type lyricsWriter struct {
+	opts WriteOptions
+}
+
+func (lw *lyricsWriter) Write(w io.Writer, s *songtools.Song) error {
+	return WriteSongWithOptions(w, s, &lw.opts)
+}
+
+func (lw *lyricsWriter) WriteSet(w io.Writer, s *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, s, &lw.opts)
+}
+
+func (lw *lyricsWriter) WithOptions(opts format.Options) (format.Writer, error) {
+	if err := opts.Check(headingsOption, foldOption); err != nil {
+		return nil, err
+	}
+
+	headings, err := opts.Bool(headingsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	fold, err := opts.Bool(foldOption)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lyricsWriter{
+		opts: WriteOptions{
+			Headings: headings,
+			Fold:     fold,
+		},
+	}, nil
+}
+
+const (
+	headingsOption = "headings"
+	foldOption     = "fold"
+)
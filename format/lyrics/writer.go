@@ -0,0 +1,128 @@
+// Package lyrics writes just the words of songs, without their chords, for singers and for
+// projection.
+package lyrics
+
+import (
+	"io"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+// WriteOptions control how the lyrics are written.
+type WriteOptions struct {
+	// Headings writes the heading of each section, such as [Chorus], above its words.
+	Headings bool
+	// Fold writes a section with the same words as an earlier one as just a note to repeat it,
+	// such as (Repeat Chorus).
+	Fold bool
+}
+
+// songWriter writes the lyrics of a single song.
+type songWriter struct {
+	opts  *WriteOptions
+	lines []string
+	// written holds the words of the sections that have been written, for folding repeats.
+	written map[string]bool
+}
+
+// WriteSongSet writes the lyrics of all the songs in the set to the writer, with a blank
+// line between songs.
+func WriteSongSet(w io.Writer, set *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, set, &WriteOptions{})
+}
+
+// WriteSongSetWithOptions writes the lyrics of all the songs in the set to the writer using
+// the options.
+func WriteSongSetWithOptions(w io.Writer, set *songtools.SongSet, opts *WriteOptions) error {
+	for i, s := range set.Songs {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+
+		err := WriteSongWithOptions(w, s, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteSong writes the lyrics of a single song to the writer.
+func WriteSong(w io.Writer, s *songtools.Song) error {
+	return WriteSongWithOptions(w, s, &WriteOptions{})
+}
+
+// WriteSongWithOptions writes the lyrics of a single song to the writer using the options. The
+// title and subtitles come first, followed by each section, with a blank line before it.
+func WriteSongWithOptions(w io.Writer, s *songtools.Song, opts *WriteOptions) error {
+	s = songtools.LyricsOnly(s)
+	sw := &songWriter{
+		opts:    opts,
+		written: map[string]bool{},
+	}
+
+	if title := strings.TrimSpace(s.Title); title != "" {
+		sw.lines = append(sw.lines, title)
+	}
+	sw.lines = append(sw.lines, s.Subtitles...)
+
+	for _, n := range s.Nodes {
+		switch typedN := n.(type) {
+		case *songtools.Comment:
+			sw.writeComment(typedN)
+		case *songtools.Section:
+			sw.writeSection(typedN)
+		}
+	}
+
+	if len(sw.lines) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(w, strings.Join(sw.lines, "\n")+"\n")
+	return err
+}
+
+func (sw *songWriter) writeComment(c *songtools.Comment) {
+	if !c.Hidden {
+		sw.lines = append(sw.lines, c.Text)
+	}
+}
+
+// writeSection writes the words of the section. When folding, a section with a heading whose
+// words were already written is noted as a repeat instead.
+func (sw *songWriter) writeSection(s *songtools.Section) {
+	words := []string{}
+	for _, n := range s.Nodes {
+		if l, ok := n.(*songtools.Line); ok {
+			words = append(words, l.Text)
+		}
+	}
+	key := strings.Join(words, "\n")
+
+	if len(sw.lines) > 0 {
+		sw.lines = append(sw.lines, "")
+	}
+
+	if sw.opts.Fold && s.Kind != "" && sw.written[key] {
+		sw.lines = append(sw.lines, "(Repeat "+s.Heading()+")")
+		return
+	}
+	sw.written[key] = true
+
+	if sw.opts.Headings && s.Kind != "" {
+		sw.lines = append(sw.lines, "["+s.Heading()+"]")
+	}
+	for _, n := range s.Nodes {
+		switch typedN := n.(type) {
+		case *songtools.Comment:
+			sw.writeComment(typedN)
+		case *songtools.Line:
+			sw.lines = append(sw.lines, typedN.Text)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package lyrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
+	"github.com/songtools/songtools/format/chordpro"
+	_ "github.com/songtools/songtools/format/chordsOverLyrics" // formats are registered in the init functions.
+	_ "github.com/songtools/songtools/format/html"             // formats are registered in the init functions.
+)
+
+const song = `{title: Hello}
+{start_of_verse}
+Hal-[C]le-lu-[G]jah, hel[C]lo [G]there
+no chords here
+{end_of_verse}
+{start_of_chorus}
+[F]Sing it [C]out
+{end_of_chorus}
+{start_of_chorus}
+[F]Sing it [C]out
+{end_of_chorus}
+`
+
+func parseSong(t *testing.T) *songtools.Song {
+	s, err := chordpro.ParseSong(strings.NewReader(song))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return s
+}
+
+func TestWriteSong(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *WriteOptions
+		expected string
+	}{
+		{
+			name:     "words",
+			opts:     &WriteOptions{},
+			expected: "Hello\n\nHallelujah, hello there\nno chords here\n\nSing it out\n\nSing it out\n",
+		},
+		{
+			name:     "headings",
+			opts:     &WriteOptions{Headings: true},
+			expected: "Hello\n\n[Verse]\nHallelujah, hello there\nno chords here\n\n[Chorus]\nSing it out\n\n[Chorus]\nSing it out\n",
+		},
+		{
+			name:     "fold",
+			opts:     &WriteOptions{Fold: true},
+			expected: "Hello\n\nHallelujah, hello there\nno chords here\n\nSing it out\n\n(Repeat Chorus)\n",
+		},
+		{
+			name:     "headings and fold",
+			opts:     &WriteOptions{Headings: true, Fold: true},
+			expected: "Hello\n\n[Verse]\nHallelujah, hello there\nno chords here\n\n[Chorus]\nSing it out\n\n(Repeat Chorus)\n",
+		},
+	}
+
+	s := parseSong(t)
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := WriteSongWithOptions(&b, s, test.opts); err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		if b.String() != test.expected {
+			t.Errorf("%v: expected %q, but got %q", test.name, test.expected, b.String())
+		}
+	}
+}
+
+func TestWriteSongFoldsOnlyRepeatedWords(t *testing.T) {
+	s, err := chordpro.ParseSong(strings.NewReader("{soc}\n[C]One\n{eoc}\n{soc}\n[C]Two\n{eoc}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := WriteSongWithOptions(&b, s, &WriteOptions{Fold: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "One\n\nTwo\n"; b.String() != expected {
+		t.Errorf("expected %q, but got %q", expected, b.String())
+	}
+}
+
+func TestLyricsOption(t *testing.T) {
+	tests := []struct {
+		format     string
+		expected   string
+		unexpected string
+	}{
+		// the blank lines that stood in for missing chords are left out with the chords.
+		{"chordsOverLyrics", "[Verse]\nHallelujah, hello there\nno chords here\n\n[Chorus]\nSing it out\n", "Hal-le"},
+		{"chordpro", "{start_of_verse}\nHallelujah, hello there\nno chords here\n{end_of_verse}\n", "[C]"},
+		{"html", "Hallelujah, hello there", "<span class='song-chord'>"},
+	}
+
+	s := parseSong(t)
+	for _, test := range tests {
+		f, ok := format.ByName(test.format)
+		if !ok {
+			t.Fatalf("%v: expected the format to be registered", test.format)
+		}
+		w, err := f.Writer.(format.ConfigurableWriter).WithOptions(format.Options{"lyrics": "true"})
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.format, err)
+			continue
+		}
+
+		var b bytes.Buffer
+		if err := w.Write(&b, s); err != nil {
+			t.Errorf("%v: unexpected error: %v", test.format, err)
+			continue
+		}
+		if !strings.Contains(b.String(), test.expected) {
+			t.Errorf("%v: expected %q to be written, but got %q", test.format, test.expected, b.String())
+		}
+		if strings.Contains(b.String(), test.unexpected) {
+			t.Errorf("%v: expected no chords, but got %q", test.format, b.String())
+		}
+	}
+}
+
+func TestLyricsFormatOptions(t *testing.T) {
+	f, ok := format.ByName("lyrics")
+	if !ok {
+		t.Fatalf("expected the lyrics format to be registered")
+	}
+
+	w, err := f.Writer.(format.ConfigurableWriter).WithOptions(format.Options{headingsOption: "true", foldOption: ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := w.Write(&b, parseSong(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), "[Verse]") || !strings.Contains(b.String(), "(Repeat Chorus)") {
+		t.Errorf("expected headings and folded repeats, but got %q", b.String())
+	}
+
+	if _, err := f.Writer.(format.ConfigurableWriter).WithOptions(format.Options{"numerals": "true"}); err == nil {
+		t.Errorf("expected an error for an unknown option")
+	}
+}
@@ -0,0 +1,71 @@
+package nashville
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Detect scores how likely the sample is to be a number chart. Lines containing directives,
+// such as #key=G, section headers, such as [Chorus], and lines made up entirely of Nashville
+// numbers count towards the score. A sample without any lines of numbers isn't a number chart,
+// however many directives and headers it has, as a chordsOverLyrics song has those too.
+func Detect(sample []byte) float64 {
+	total := 0
+	hits := 0
+	numberLines := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(sample))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		total++
+		if isDirectiveLine(line) || isSectionHeaderLine(line) {
+			hits++
+		} else if isNumberLine(line) {
+			hits++
+			numberLines++
+		}
+	}
+
+	if numberLines == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(total)
+}
+
+func isNumberLine(line string) bool {
+	for _, field := range strings.Fields(line) {
+		if _, ok := parseNumber(field); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDirectiveLine(line string) bool {
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+
+	parts := strings.SplitN(line[1:], "=", 2)
+	return len(parts) == 2 && parts[0] != "" && !strings.Contains(parts[0], " ")
+}
+
+func isSectionHeaderLine(line string) bool {
+	if !strings.HasPrefix(line, "[") {
+		return false
+	}
+
+	end := strings.Index(line, "]")
+	if end == -1 {
+		return false
+	}
+
+	return !isNumberLine(line[1:end])
+}
@@ -0,0 +1,46 @@
+package nashville
+
+import (
+	"io"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format"
+)
+
+func init() {
+	rw := &nashvilleReaderWriter{}
+	f := &format.Format{
+		Name:       "nashville",
+		Reader:     rw,
+		Writer:     rw,
+		Detector:   rw,
+		Extensions: []string{".nns"},
+	}
+
+	format.Register(f)
+}
+
+type nashvilleReaderWriter struct{}
+
+func (nrw *nashvilleReaderWriter) Read(r io.Reader) (*songtools.Song, error) {
+	return ParseSong(r)
+}
+
+func (nrw *nashvilleReaderWriter) ReadSet(r io.Reader) (*songtools.SongSet, error) {
+	return ParseSongSet(r)
+}
+
+func (nrw *nashvilleReaderWriter) Detect(sample []byte) float64 {
+	return Detect(sample)
+}
+
+func (nrw *nashvilleReaderWriter) Write(w io.Writer, s *songtools.Song) error {
+	return WriteSong(w, s)
+}
+
+func (nrw *nashvilleReaderWriter) WriteSet(w io.Writer, s *songtools.SongSet) error {
+	return WriteSongSet(w, s)
+}
+
+// DefaultKey is the key used for a chart that doesn't specify one.
+var DefaultKey = songtools.MustParseKey("C")
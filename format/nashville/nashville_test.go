@@ -0,0 +1,148 @@
+package nashville
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format/chordsOverLyrics"
+)
+
+// chordNames gets the names of the chords in the song, separated by spaces.
+func chordNames(s *songtools.Song) string {
+	names := []string{}
+	for _, c := range s.Chords() {
+		names = append(names, c.Name)
+	}
+
+	return strings.Join(names, " ")
+}
+
+func TestParseSong(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		key      string
+		expected string
+	}{
+		{"major", "#key=G\n\n1    4    5\nAmazing grace\n", "G", "G C D"},
+		{"minor", "#key=Am\n\n1m   4m   57\nWhen the night\n", "Am", "Am Dm E7"},
+		{"slash", "#key=D\n\n1   5/7   6m   1/5\nHere I am\n", "D", "D A/C# Bm D/A"},
+		{"flats", "#key=F\n\n1   b7   4   b3\nLet it be\n", "F", "F Eb Bb Ab"},
+		{"sharps", "#key=Bb\n\n1   #4m7b5   4\nFalling\n", "Bb", "Bb Em7b5 Eb"},
+		{"minor accidentals", "#key=Em\n\n1m   b6   b7   1m\nRiver\n", "Em", "Em C D Em"},
+		{"default key", "1   4   5\nOnce more\n", "C", "C F G"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSong(strings.NewReader(test.src))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if s.Key.String() != test.key || chordNames(s) != test.expected {
+			t.Errorf("%v: ParseSong = %q in %v, expected %q in %v", test.name, chordNames(s), s.Key, test.expected, test.key)
+		}
+	}
+}
+
+func TestWriteSong(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"#key=G\n\nG    C/G    D7\nAmazing grace\n", "1    4/1    57"},
+		{"#key=Cm\n\nCm   Ab   G7/B\nWhen the night\n", "1m   b6   57/7"},
+	}
+
+	for _, test := range tests {
+		s, err := chordsOverLyrics.ParseSong(strings.NewReader(test.src))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.src, err)
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		if err := WriteSong(buf, s); err != nil {
+			t.Errorf("%q: unexpected error: %v", test.src, err)
+			continue
+		}
+		if !strings.Contains(buf.String(), test.expected) {
+			t.Errorf("%q: expected the chart to contain %q, but was %q", test.src, test.expected, buf.String())
+		}
+
+		// the chart reads back as the same chords.
+		read, err := ParseSong(buf)
+		if err != nil {
+			t.Errorf("%q: unexpected error reading the chart: %v", test.src, err)
+			continue
+		}
+		if chordNames(read) != chordNames(s) {
+			t.Errorf("%q: read the chords %q back from the chart, expected %q", test.src, chordNames(read), chordNames(s))
+		}
+	}
+
+	if err := WriteSong(&bytes.Buffer{}, &songtools.Song{}); err == nil {
+		t.Errorf("expected an error writing a song without a key")
+	}
+}
+
+func TestParseSongGrids(t *testing.T) {
+	src := "#key=G\n\n[Intro]\n| 1 . 4 . | 5/7 / / / |\n| 6m . . . | % |\n"
+	s, err := ParseSong(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "G C D/F# Em"; chordNames(s) != expected {
+		t.Errorf("expected the grid to have the chords %q, but got %q", expected, chordNames(s))
+	}
+	if _, ok := s.Nodes[0].(*songtools.Section).Nodes[0].(*songtools.Grid); !ok {
+		t.Fatalf("expected a grid, but got %T", s.Nodes[0].(*songtools.Section).Nodes[0])
+	}
+
+	// the grid is written back in numbers.
+	buf := &bytes.Buffer{}
+	if err := WriteSong(buf, s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"| 1  . 4 . | 5/7 / / / |", "| 6m . . . | %         |"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected the chart to contain %q, but was %q", expected, buf.String())
+		}
+	}
+
+	read, err := ParseSong(buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading the chart: %v", err)
+	}
+	if chordNames(read) != chordNames(s) {
+		t.Errorf("read the chords %q back from the chart, expected %q", chordNames(read), chordNames(s))
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample string
+		chart  bool
+	}{
+		{"numbers", "1 4 5 1\nAmazing grace\n", true},
+		{"directives and headers", "#title=Grace\n#key=G\n\n[Verse]\n1    4    1\nAmazing grace\n[Chorus]\n5/7 6m\nHow sweet\n", true},
+		{"accidentals", "#key=Am\n1m b6 b7 1m\n", true},
+		{"chords", "#title=Grace\n#key=G\n\n[Verse]\nG    C    G\nAmazing grace\n", false},
+		{"headers only", "#title=Grace\n[Verse]\n[Chorus]\nAmazing grace\n", false},
+		{"lyrics", "Amazing grace\nHow sweet the sound\n", false},
+		{"empty", "", false},
+	}
+
+	for _, test := range tests {
+		score := Detect([]byte(test.sample))
+		other := chordsOverLyrics.Detect([]byte(test.sample))
+		if (score > other) != test.chart {
+			t.Errorf("%v: Detect = %v against %v for chordsOverLyrics, expected a number chart: %v", test.name, score, other, test.chart)
+		}
+	}
+}
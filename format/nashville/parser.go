@@ -0,0 +1,63 @@
+package nashville
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format/chordsOverLyrics"
+)
+
+// ParseSong parses a number chart from the src to create a songtools.Song. The numbers
+// are turned into chords in the song's key, or DefaultKey when the chart has no key.
+func ParseSong(src io.Reader) (*songtools.Song, error) {
+	set, err := ParseSongSet(src)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(set.Songs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return set.Songs[0], nil
+	default:
+		return nil, fmt.Errorf("expected a single song, but found %d", len(set.Songs))
+	}
+}
+
+// ParseSongSet parses number charts from the src to create a songtools.SongSet. Number charts
+// are laid out just like chordsOverLyrics songs, with numbers in place of chords.
+func ParseSongSet(src io.Reader) (*songtools.SongSet, error) {
+	set, _, err := chordsOverLyrics.ParseSongSetWithOptions(src, &chordsOverLyrics.ParseOptions{
+		ParseChord: parseNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, s := range set.Songs {
+		if s.Key.IsZero() {
+			s.Key = DefaultKey
+		}
+
+		set.Songs[i], err = songtools.FromNashville(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+// parseNumber recognizes a Nashville number, keeping the number as the chord's name
+// until the song's key is known.
+func parseNumber(text string) (*songtools.Chord, bool) {
+	c, ok := songtools.ParseNashvilleChord(text, DefaultKey)
+	if !ok {
+		return nil, false
+	}
+
+	c.Name = text
+	return c, true
+}
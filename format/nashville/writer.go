@@ -0,0 +1,33 @@
+package nashville
+
+import (
+	"io"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/format/chordsOverLyrics"
+)
+
+// WriteSong writes a single song to the writer as a number chart. The song must have a key.
+func WriteSong(w io.Writer, s *songtools.Song) error {
+	numbered, err := songtools.ToNashville(s)
+	if err != nil {
+		return err
+	}
+
+	return chordsOverLyrics.WriteSong(w, numbered)
+}
+
+// WriteSongSet writes all the songs in the set to the writer as number charts.
+func WriteSongSet(w io.Writer, set *songtools.SongSet) error {
+	numbered := &songtools.SongSet{}
+	for _, s := range set.Songs {
+		n, err := songtools.ToNashville(s)
+		if err != nil {
+			return err
+		}
+
+		numbered.Songs = append(numbered.Songs, n)
+	}
+
+	return chordsOverLyrics.WriteSongSet(w, numbered)
+}
@@ -0,0 +1,80 @@
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Options are named settings that change how a format writes songs, such as numerals=true.
+type Options map[string]string
+
+// Bool returns whether the named option is turned on. An option given without a value is on.
+func (o Options) Bool(name string) (bool, error) {
+	value, ok := o[name]
+	if !ok {
+		return false, nil
+	}
+
+	if value == "" {
+		return true, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("the option %q must be true or false, but was %q", name, value)
+	}
+
+	return b, nil
+}
+
+// Check returns an error when any of the options are not one of the known names.
+func (o Options) Check(known ...string) error {
+	unknown := []string{}
+	for name := range o {
+		found := false
+		for _, k := range known {
+			if name == k {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options %v, the options are %v", unknown, known)
+	}
+
+	return nil
+}
+
+// ConfigurableWriter represents a Writer whose output can be changed with Options.
+type ConfigurableWriter interface {
+	WithOptions(Options) (Writer, error)
+}
+
+// WithOptions returns a copy of the format that writes using the options.
+func (f *Format) WithOptions(opts Options) (*Format, error) {
+	if len(opts) == 0 {
+		return f, nil
+	}
+
+	cw, ok := f.Writer.(ConfigurableWriter)
+	if !ok {
+		return nil, fmt.Errorf("the format %q does not have any options", f.Name)
+	}
+
+	w, err := cw.WithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	configured := *f
+	configured.Writer = w
+	return &configured, nil
+}
@@ -0,0 +1,159 @@
+package pdf
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/songtools/songtools"
+)
+
+const (
+	bookTitleSize    = 32
+	bookSubtitleSize = 16
+)
+
+// WriteBook writes the songbook to a single document.
+func WriteBook(w io.Writer, b *songtools.Book) error {
+	return WriteBookWithOptions(w, b, &WriteOptions{})
+}
+
+// WriteBookWithOptions writes the songbook to a single document using the options. It starts
+// with a title page and the contents, followed by the songs and an index of their titles and
+// first lines. The contents and the index both refer to a song by its number and the page it
+// starts on. Every page but the title page is numbered at the bottom.
+func WriteBookWithOptions(w io.Writer, b *songtools.Book, opts *WriteOptions) error {
+	doc, face, err := newSongDocument(b.Title, opts)
+	if err != nil {
+		return err
+	}
+
+	book := *b
+	book.Songs = lyricsOnly(b.Songs, opts)
+	b = &book
+
+	// the songs are written first, so the contents know the page each of them starts on.
+	starts := []int{}
+	for i, s := range b.Songs {
+		starts = append(starts, len(doc.pages))
+		sw := &songWriter{
+			doc:    doc,
+			opts:   opts,
+			face:   face,
+			song:   s,
+			number: i + 1,
+		}
+		sw.writeSong()
+	}
+	songs := doc.pages
+
+	// the contents are written once without their page numbers just to count their pages,
+	// which come before the songs.
+	scratch := newDocument(b.Title, doc.width, doc.height)
+	writeList(scratch, face, "Contents", contents(b, starts))
+	front := 1 + len(scratch.pages)
+
+	// the pages of the songs follow the front pages, which are the title page and the contents.
+	for i := range starts {
+		starts[i] += front + 1
+	}
+
+	doc.pages = nil
+	writeTitlePage(doc, face, b)
+	writeList(doc, face, "Contents", contents(b, starts))
+	doc.pages = append(doc.pages, songs...)
+	writeList(doc, face, "Index", index(b, face, starts))
+	writeFooters(doc, face, 1)
+
+	return doc.write(w)
+}
+
+// writeTitlePage writes the title and subtitle of the book, centered a third of the way down
+// a page of their own.
+func writeTitlePage(doc *document, face *typeface, b *songtools.Book) {
+	p := doc.addPage()
+	y := doc.height / 3
+	p.text(face.bold, bookTitleSize, (doc.width-face.bold.width(b.Title, bookTitleSize))/2, y, b.Title)
+	if b.Subtitle != "" {
+		y += bookTitleSize * leading
+		p.text(face.regular, bookSubtitleSize, (doc.width-face.regular.width(b.Subtitle, bookSubtitleSize))/2, y, b.Subtitle)
+	}
+}
+
+// listEntry is a line of the contents or the index. Its number is at the right margin, joined
+// to its text by a row of dots.
+type listEntry struct {
+	text   string
+	font   *font
+	number string
+}
+
+// songReference is how the contents and the index refer to a song, by its number and the page
+// it starts on, such as "No. 3, p. 7".
+func songReference(song, page int) string {
+	return "No. " + strconv.Itoa(song+1) + ", p. " + strconv.Itoa(page)
+}
+
+// contents lists the songs of the book in order, each with the page it starts on.
+func contents(b *songtools.Book, starts []int) []listEntry {
+	entries := []listEntry{}
+	for i, s := range b.Songs {
+		entries = append(entries, listEntry{
+			text:   strings.TrimSpace(s.Title),
+			number: songReference(i, starts[i]),
+		})
+	}
+
+	return entries
+}
+
+// index lists the titles and first lines of the songs, each with the page its song starts on.
+// The first lines are in italics.
+func index(b *songtools.Book, face *typeface, starts []int) []listEntry {
+	entries := []listEntry{}
+	for _, e := range b.Index() {
+		f := face.regular
+		if e.FirstLine {
+			f = face.italic
+		}
+		entries = append(entries, listEntry{
+			text:   e.Text,
+			font:   f,
+			number: songReference(e.Song, starts[e.Song]),
+		})
+	}
+
+	return entries
+}
+
+// writeList writes the entries under the heading, starting on a new page and going on to
+// more pages as they fill.
+func writeList(doc *document, face *typeface, heading string, entries []listEntry) {
+	p := doc.addPage()
+	p.text(face.bold, titleSize, margin, margin+titleSize, heading)
+	y := margin + titleSize*leading + sectionGap
+
+	right := doc.width - margin
+	dot := face.regular.width(".", lyricSize)
+	for _, e := range entries {
+		if y+lyricSize*leading > doc.height-margin {
+			p = doc.addPage()
+			y = margin
+		}
+
+		f := e.font
+		if f == nil {
+			f = face.regular
+		}
+		numberX := right - face.regular.width(e.number, lyricSize)
+		textEnd := margin + f.width(e.text, lyricSize)
+
+		p.text(f, lyricSize, margin, y+lyricSize, e.text)
+		p.text(face.regular, lyricSize, numberX, y+lyricSize, e.number)
+		if dots := int(math.Floor((numberX - textEnd - 2*dot) / dot)); dots > 0 {
+			p.text(face.regular, lyricSize, numberX-dot*float64(dots+1), y+lyricSize, strings.Repeat(".", dots))
+		}
+		y += lyricSize * leading
+	}
+}
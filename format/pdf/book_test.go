@@ -0,0 +1,45 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+func TestBookReferences(t *testing.T) {
+	b := &songtools.Book{Songs: []*songtools.Song{
+		{Title: "Be Thou My Vision", Nodes: []songtools.SongNode{&songtools.Section{Nodes: []songtools.SectionNode{
+			&songtools.Line{Text: "Be Thou my vision, O Lord of my heart"},
+		}}}},
+		{Title: "Amazing Grace"},
+	}}
+	starts := []int{3, 5}
+
+	expected := []listEntry{
+		{text: "Be Thou My Vision", number: "No. 1, p. 3"},
+		{text: "Amazing Grace", number: "No. 2, p. 5"},
+	}
+	for i, e := range contents(b, starts) {
+		if e != expected[i] {
+			t.Errorf("contents entry %d = %+v, expected %+v", i, e, expected[i])
+		}
+	}
+
+	// the index refers to the songs just as the contents do.
+	face := typefaces[defaultTypeface]
+	expected = []listEntry{
+		{text: "Amazing Grace", font: face.regular, number: "No. 2, p. 5"},
+		{text: "Be Thou My Vision", font: face.regular, number: "No. 1, p. 3"},
+		{text: "Be Thou my vision, O Lord of my heart", font: face.italic, number: "No. 1, p. 3"},
+	}
+	for i, e := range index(b, face, starts) {
+		if e != expected[i] {
+			t.Errorf("index entry %d = %+v, expected %+v", i, e, expected[i])
+		}
+	}
+
+	if err := WriteBook(&bytes.Buffer{}, b); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"strconv"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/diagram"
+	"github.com/songtools/songtools/fingering"
+)
+
+const (
+	stringGap = 10
+	fretGap   = 12
+	// nameHeight is the room above the box for the name of the chord.
+	nameHeight = 14
+	// markerHeight is the room above the box for the open and muted strings.
+	markerHeight = 10
+	// labelWidth is the room to the right of the box for the base fret.
+	labelWidth = 18
+	dotRadius  = 4
+	minFrets   = 4
+	// diagramGap is the space between diagrams.
+	diagramGap = 12
+)
+
+// writeDiagrams draws a chord diagram for each different chord in the song, using the song's
+// own definitions where it has them. The diagrams are drawn in rows, as many to a row as fit.
+func (sw *songWriter) writeDiagrams() {
+	t := sw.opts.Tuning
+	if t == nil {
+		t = fingering.Guitar
+	}
+
+	rows := []block{}
+	row := []*songtools.ChordDefinition{}
+	rowWidth := 0.0
+	for _, d := range fingering.SongDefinitions(sw.song, t, nil) {
+		if len(d.Frets) < 2 {
+			continue
+		}
+
+		width, _ := diagramSize(d)
+		if len(row) > 0 && rowWidth+width > sw.doc.width-2*margin {
+			rows = append(rows, sw.diagramRow(row))
+			row, rowWidth = nil, 0
+		}
+		row = append(row, d)
+		rowWidth += width + diagramGap
+	}
+	if len(row) > 0 {
+		rows = append(rows, sw.diagramRow(row))
+	}
+
+	if len(rows) > 0 {
+		sw.place(rows, false)
+		sw.y += sectionGap
+	}
+}
+
+// diagramSize is the width and height of the definition's diagram.
+func diagramSize(d *songtools.ChordDefinition) (float64, float64) {
+	return float64(stringGap*(len(d.Frets)-1) + labelWidth), float64(nameHeight + markerHeight + fretGap*diagramFrets(d) + dotRadius)
+}
+
+// diagramFrets is the number of frets the definition's diagram shows.
+func diagramFrets(d *songtools.ChordDefinition) int {
+	frets := minFrets
+	for _, f := range d.Frets {
+		if f > frets {
+			frets = f
+		}
+	}
+
+	return frets
+}
+
+// diagramRow draws the diagrams side by side, with their tops lined up.
+func (sw *songWriter) diagramRow(defs []*songtools.ChordDefinition) block {
+	height := 0.0
+	for _, d := range defs {
+		if _, h := diagramSize(d); h > height {
+			height = h
+		}
+	}
+
+	return block{
+		height: height,
+		draw: func(p *page, x, y float64) {
+			for _, d := range defs {
+				sw.drawDiagram(p, d, x, y)
+				width, _ := diagramSize(d)
+				x += width + diagramGap
+			}
+		},
+	}
+}
+
+// drawDiagram draws the definition the same way as the svg diagrams. The box has a line for each
+// string, from the lowest string on the left, and starts at the nut unless the definition has a
+// base fret, which is labeled to the right. Muted strings are marked with an x and open strings
+// with an o above the box. Each fretted string gets a dot with the number of its finger, and a
+// barre is drawn across the strings held down by one finger.
+func (sw *songWriter) drawDiagram(p *page, d *songtools.ChordDefinition, x, y float64) {
+	frets := diagramFrets(d)
+	top := y + nameHeight + markerHeight
+	boxWidth := float64(stringGap * (len(d.Frets) - 1))
+	boxHeight := float64(fretGap * frets)
+	bold, regular := sw.face.bold, sw.face.regular
+
+	p.text(bold, 10, x+(boxWidth-bold.width(d.Name, 10))/2, y+10, d.Name)
+
+	// the strings and frets.
+	for i := range d.Frets {
+		sx := x + float64(i*stringGap)
+		p.line(sx, top, sx, top+boxHeight, 0.5)
+	}
+	for f := 0; f <= frets; f++ {
+		fy := top + float64(f*fretGap)
+		width := 0.5
+		if f == 0 && d.BaseFret <= 1 {
+			width = 2
+		}
+		p.line(x, fy, x+boxWidth, fy, width)
+	}
+	if d.BaseFret > 1 {
+		p.text(regular, 8, x+boxWidth+3, top+fretGap/2+3, strconv.Itoa(d.BaseFret)+"fr")
+	}
+
+	// the open and muted strings.
+	for i, f := range d.Frets {
+		sx, my := x+float64(i*stringGap), top-markerHeight/2
+		switch f {
+		case songtools.MutedString:
+			p.line(sx-2.5, my-2.5, sx+2.5, my+2.5, 0.75)
+			p.line(sx-2.5, my+2.5, sx+2.5, my-2.5, 0.75)
+		case 0:
+			p.circle(sx, my, 2.5, false)
+		}
+	}
+
+	// the barres, then the dots of the strings that aren't under a barre.
+	barred := make([]bool, len(d.Frets))
+	for _, b := range diagram.Barres(d) {
+		by := top + float64(b.Fret*fretGap) - fretGap/2
+		x1, x2 := x+float64(b.First*stringGap), x+float64(b.Last*stringGap)
+		p.rect(x1, by-dotRadius, x2-x1, 2*dotRadius)
+		p.circle(x1, by, dotRadius, true)
+		p.circle(x2, by, dotRadius, true)
+		sw.drawFinger(p, (x1+x2)/2, by, b.Finger)
+		for i := b.First; i <= b.Last; i++ {
+			if d.Frets[i] == b.Fret {
+				barred[i] = true
+			}
+		}
+	}
+	for i, f := range d.Frets {
+		if f <= 0 || barred[i] {
+			continue
+		}
+
+		dx, dy := x+float64(i*stringGap), top+float64(f*fretGap)-fretGap/2
+		p.circle(dx, dy, dotRadius, true)
+		if i < len(d.Fingers) && d.Fingers[i] > 0 {
+			sw.drawFinger(p, dx, dy, d.Fingers[i])
+		}
+	}
+}
+
+// drawFinger writes the number of the finger in white, centered on the dot.
+func (sw *songWriter) drawFinger(p *page, x, y float64, finger int) {
+	text := strconv.Itoa(finger)
+	p.gray(1)
+	p.text(sw.face.regular, 6, x-sw.face.regular.width(text, 6)/2, y+2, text)
+	p.gray(0)
+}
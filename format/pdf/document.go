@@ -0,0 +1,318 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// document is a pdf being drawn. Its pages are drawn in any order and the file is put
+// together once they are done.
+type document struct {
+	title  string
+	width  float64
+	height float64
+	pages  []*page
+	// fonts are the fonts used by the pages, in the order they were first used.
+	fonts []*font
+	// glyphs are the glyphs of each embedded font the pages use, along with the characters
+	// they write. Only those glyphs are embedded.
+	glyphs map[*font]map[uint16]rune
+	// err is the first problem found while drawing the pages, which is returned when the
+	// document is written.
+	err error
+}
+
+// page is a page of the document. Its positions are in points from the top left corner of the
+// page, rather than the bottom left like pdf itself, so the page can be filled from the top.
+type page struct {
+	doc     *document
+	content bytes.Buffer
+}
+
+func newDocument(title string, width, height float64) *document {
+	return &document{
+		title:  title,
+		width:  width,
+		height: height,
+		glyphs: map[*font]map[uint16]rune{},
+	}
+}
+
+func (d *document) addPage() *page {
+	p := &page{doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// fontName gets the name the pages use for the font.
+func (d *document) fontName(f *font) string {
+	for i, used := range d.fonts {
+		if used == f {
+			return fmt.Sprintf("/F%d", i+1)
+		}
+	}
+
+	d.fonts = append(d.fonts, f)
+	return fmt.Sprintf("/F%d", len(d.fonts))
+}
+
+// text draws the text with its baseline at y.
+func (p *page) text(f *font, size, x, y float64, text string) {
+	var encoded string
+	var err error
+	if f.trueType != nil {
+		if p.doc.glyphs[f] == nil {
+			p.doc.glyphs[f] = map[uint16]rune{}
+		}
+		var glyphs []byte
+		glyphs, err = encodeGlyphs(f.trueType, text, p.doc.glyphs[f])
+		encoded = fmt.Sprintf("<%X>", glyphs)
+	} else {
+		var chars []byte
+		chars, err = encode(text)
+		encoded = pdfString(chars)
+	}
+	if err != nil && p.doc.err == nil {
+		p.doc.err = err
+	}
+
+	fmt.Fprintf(&p.content, "BT %v %v Tf %v %v Td %v Tj ET\n", p.doc.fontName(f), number(size), number(x), number(p.doc.height-y), encoded)
+}
+
+func (p *page) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%v w %v %v m %v %v l S\n", number(width), number(x1), number(p.doc.height-y1), number(x2), number(p.doc.height-y2))
+}
+
+// rect fills the rectangle whose top left corner is at x and y.
+func (p *page) rect(x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "%v %v %v %v re f\n", number(x), number(p.doc.height-y-height), number(width), number(height))
+}
+
+// circle draws a circle from four bezier curves, filled or outlined.
+func (p *page) circle(x, y, radius float64, fill bool) {
+	// k is how far the control points are from the ends of each quarter of the circle.
+	k := radius * 4 * (math.Sqrt2 - 1) / 3
+	y = p.doc.height - y
+	fmt.Fprintf(&p.content, "%v %v m\n", number(x+radius), number(y))
+	fmt.Fprintf(&p.content, "%v %v %v %v %v %v c\n", number(x+radius), number(y+k), number(x+k), number(y+radius), number(x), number(y+radius))
+	fmt.Fprintf(&p.content, "%v %v %v %v %v %v c\n", number(x-k), number(y+radius), number(x-radius), number(y+k), number(x-radius), number(y))
+	fmt.Fprintf(&p.content, "%v %v %v %v %v %v c\n", number(x-radius), number(y-k), number(x-k), number(y-radius), number(x), number(y-radius))
+	fmt.Fprintf(&p.content, "%v %v %v %v %v %v c\n", number(x+k), number(y-radius), number(x+radius), number(y-k), number(x+radius), number(y))
+	if fill {
+		p.content.WriteString("f\n")
+	} else {
+		p.content.WriteString("0.75 w S\n")
+	}
+}
+
+// gray sets the color of the lines and fills that follow, from 0 for black to 1 for white.
+func (p *page) gray(level float64) {
+	fmt.Fprintf(&p.content, "%v g %v G\n", number(level), number(level))
+}
+
+// write writes the document as a pdf file. The catalog, page tree and information come first,
+// followed by the fonts and then each page and its compressed content. An embedded font takes
+// five objects: the font, the font it descends from, its descriptor, its file and the map of
+// its glyphs to the characters they write.
+func (d *document) write(w io.Writer) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%v\nendobj\n", len(offsets), body)
+	}
+	stream := func(data []byte, dict string) error {
+		compressed, err := compress(data)
+		if err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode%v >>\nstream\n%s\nendstream", len(compressed), dict, compressed))
+		return nil
+	}
+
+	resources := []string{}
+	firstPage := 4
+	for i, f := range d.fonts {
+		resources = append(resources, fmt.Sprintf("/F%d %d 0 R", i+1, firstPage))
+		if f.trueType != nil {
+			firstPage += 5
+		} else {
+			firstPage++
+		}
+	}
+
+	kids := []string{}
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%v] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object(fmt.Sprintf("<< /Title %v /Producer (songtools) >>", textString(d.title)))
+	for _, f := range d.fonts {
+		if f.trueType == nil {
+			object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%v /Encoding /WinAnsiEncoding >>", f.name))
+			continue
+		}
+
+		if err := d.writeTrueType(f, len(offsets)+1, object, stream); err != nil {
+			return err
+		}
+	}
+	for i, p := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %v %v] /Resources << /Font << %v >> >> /Contents %d 0 R >>",
+			number(d.width), number(d.height), strings.Join(resources, " "), firstPage+2*i+1))
+
+		if err := stream(p.content.Bytes(), ""); err != nil {
+			return err
+		}
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeTrueType writes the objects of an embedded font, starting with the object numbered first.
+// The font is subset to the glyphs the pages use, and its text is written as two byte glyph
+// numbers.
+func (d *document) writeTrueType(f *font, first int, object func(string), stream func([]byte, string) error) error {
+	t := f.trueType
+	used := d.glyphs[f]
+	glyphs := []int{}
+	for g := range used {
+		glyphs = append(glyphs, int(g))
+	}
+	sort.Ints(glyphs)
+
+	// a subset's name starts with a tag of six capital letters, which is different for each
+	// set of glyphs.
+	hash := fnv.New32a()
+	fmt.Fprint(hash, glyphs)
+	tag := ""
+	for sum := hash.Sum32(); len(tag) < 6; sum /= 26 {
+		tag += string(rune('A' + sum%26))
+	}
+	name := tag + "+" + t.name
+
+	widths := []string{}
+	for _, g := range glyphs {
+		widths = append(widths, fmt.Sprintf("%d [%d]", g, t.width(uint16(g))))
+	}
+
+	flags := 32
+	if t.italicAngle != 0 {
+		flags |= 64
+	}
+
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%v /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", name, first+1, first+4))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%v /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 0 /W [%v] /CIDToGIDMap /Identity >>",
+		name, first+2, strings.Join(widths, " ")))
+	object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%v /Flags %d /FontBBox [%d %d %d %d] /ItalicAngle %v /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, flags, t.scale(t.bbox[0]), t.scale(t.bbox[1]), t.scale(t.bbox[2]), t.scale(t.bbox[3]), number(t.italicAngle), t.scale(t.ascent), t.scale(t.descent), t.scale(t.capHeight), first+3))
+
+	subset := t.subset(used)
+	if err := stream(subset, fmt.Sprintf(" /Length1 %d", len(subset))); err != nil {
+		return err
+	}
+
+	return stream(toUnicode(used, glyphs), "")
+}
+
+// toUnicode writes the map from the glyphs to the characters they write, so the text can be
+// searched and copied.
+func toUnicode(used map[uint16]rune, glyphs []int) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	buf.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	buf.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	buf.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	// a block holds at most a hundred glyphs.
+	for start := 0; start < len(glyphs); start += 100 {
+		end := start + 100
+		if end > len(glyphs) {
+			end = len(glyphs)
+		}
+
+		fmt.Fprintf(buf, "%d beginbfchar\n", end-start)
+		for _, g := range glyphs[start:end] {
+			units := ""
+			for _, u := range utf16.Encode([]rune{used[uint16(g)]}) {
+				units += fmt.Sprintf("%04X", u)
+			}
+			fmt.Fprintf(buf, "<%04X> <%v>\n", g, units)
+		}
+		buf.WriteString("endbfchar\n")
+	}
+
+	buf.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return buf.Bytes()
+}
+
+func compress(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// textString writes the text as a pdf text string, in UTF-16 so it can hold any character.
+func textString(text string) string {
+	buf := "<FEFF"
+	for _, u := range utf16.Encode([]rune(text)) {
+		buf += fmt.Sprintf("%04X", u)
+	}
+
+	return buf + ">"
+}
+
+// number writes the number with at most two decimal places.
+func number(n float64) string {
+	s := fmt.Sprintf("%.2f", n)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// pdfString writes the encoded text as a pdf string, escaping the characters that would end it.
+func pdfString(text []byte) string {
+	buf := "("
+	for _, b := range text {
+		switch b {
+		case '(', ')', '\\':
+			buf += "\\" + string(b)
+		default:
+			buf += string([]byte{b})
+		}
+	}
+
+	return buf + ")"
+}
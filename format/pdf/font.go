@@ -0,0 +1,194 @@
+package pdf
+
+import "fmt"
+
+// font is one of the standard fonts every pdf reader has, so it doesn't need to be embedded.
+// Its widths are the widths of the printable ascii characters, from the space to the tilde, in
+// thousandths of the font size. A font without widths is monospaced. A font with a trueType
+// is embedded instead, for the characters the standard fonts don't have.
+type font struct {
+	name     string
+	widths   []int
+	trueType *trueType
+}
+
+// fixedWidth is the width of every character of the monospaced fonts.
+const fixedWidth = 600
+
+var (
+	helveticaWidths = []int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+
+	helveticaBoldWidths = []int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+var (
+	helvetica        = &font{name: "Helvetica", widths: helveticaWidths}
+	helveticaBold    = &font{name: "Helvetica-Bold", widths: helveticaBoldWidths}
+	helveticaOblique = &font{name: "Helvetica-Oblique", widths: helveticaWidths}
+	courier          = &font{name: "Courier"}
+	courierBold      = &font{name: "Courier-Bold"}
+	courierOblique   = &font{name: "Courier-Oblique"}
+)
+
+// typeface is the fonts used for the text of a song.
+type typeface struct {
+	regular *font
+	bold    *font
+	italic  *font
+}
+
+// typefaces are the typefaces that can be chosen for the text. Tabs and grids are always
+// written in the monospace typeface so their columns line up.
+var typefaces = map[string]*typeface{
+	"proportional": {regular: helvetica, bold: helveticaBold, italic: helveticaOblique},
+	"monospace":    {regular: courier, bold: courierBold, italic: courierOblique},
+}
+
+const defaultTypeface = "proportional"
+
+// newTypeface creates a typeface of embedded TrueType fonts. The bold and italic fonts are
+// optional, and the regular font is used in their place when they aren't given.
+func newTypeface(regular, bold, italic []byte) (*typeface, error) {
+	face := &typeface{}
+	for _, f := range []struct {
+		data  []byte
+		font  **font
+		style string
+	}{{regular, &face.regular, "regular"}, {bold, &face.bold, "bold"}, {italic, &face.italic, "italic"}} {
+		if f.data == nil {
+			*f.font = face.regular
+			continue
+		}
+
+		t, err := parseTrueType(f.data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the %v font: %v", f.style, err)
+		}
+		*f.font = &font{name: t.name, trueType: t}
+	}
+
+	return face, nil
+}
+
+// width is the width of the text at the size. Characters the font doesn't have take no room.
+func (f *font) width(text string, size float64) float64 {
+	total := 0
+	if f.trueType != nil {
+		for _, r := range text {
+			if g, ok := f.trueType.glyph(tabToSpace(r)); ok {
+				total += f.trueType.width(g)
+			}
+		}
+	} else {
+		encoded, _ := encode(text)
+		for _, b := range encoded {
+			total += f.charWidth(b)
+		}
+	}
+
+	return float64(total) * size / 1000
+}
+
+func (f *font) charWidth(b byte) int {
+	if f.widths == nil {
+		return fixedWidth
+	}
+
+	switch {
+	case b >= ' ' && b <= '~':
+		return f.widths[b-' ']
+	case b == 0xa0:
+		return f.widths[0]
+	case b >= 0xc0:
+		// accented letters are as wide as the letters they are accented from.
+		return f.widths[latinLetters[b-0xc0]-' ']
+	}
+
+	if w, ok := punctuationWidths[b]; ok {
+		return w
+	}
+	return 556
+}
+
+// latinLetters are the unaccented letters of the characters from 0xc0 to 0xff.
+const latinLetters = "AAAAAAECEEEEIIIIDNOOOOOxOUUUUYPsaaaaaaeceeeeiiiidnooooo/ouuuuypy"
+
+// punctuationWidths are the widths of the typographic punctuation, which is the same in both
+// weights of helvetica.
+var punctuationWidths = map[byte]int{
+	0x85: 1000, 0x91: 222, 0x92: 222, 0x93: 333, 0x94: 333, 0x95: 350, 0x96: 556, 0x97: 1000,
+}
+
+// winAnsi are the characters outside of latin-1 that have a place in the encoding of the
+// standard fonts.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	// the sharp and flat signs aren't in the fonts, so they are spelled the way chords are typed.
+	'♯': '#', '♭': 'b',
+}
+
+// encode encodes the text for the standard fonts. Tabs become spaces. Characters the fonts
+// don't have are left out, and the first of them is reported as an error.
+func encode(text string) ([]byte, error) {
+	buf := []byte{}
+	var err error
+	for _, r := range text {
+		r = tabToSpace(r)
+		switch {
+		case r >= ' ' && r <= '~', r >= 0xa0 && r <= 0xff:
+			buf = append(buf, byte(r))
+		default:
+			if b, ok := winAnsi[r]; ok {
+				buf = append(buf, b)
+			} else if r >= ' ' && err == nil {
+				err = fmt.Errorf("the standard fonts can't write %q (%U), so give a TrueType font that can with the font option", r, r)
+			}
+		}
+	}
+
+	return buf, err
+}
+
+// encodeGlyphs encodes the text as the glyphs of the embedded font, two bytes for each. The
+// characters it writes are added to used. Characters the font doesn't have are left out, and
+// the first of them is reported as an error.
+func encodeGlyphs(t *trueType, text string, used map[uint16]rune) ([]byte, error) {
+	buf := []byte{}
+	var err error
+	for _, r := range text {
+		r = tabToSpace(r)
+		g, ok := t.glyph(r)
+		if !ok {
+			if r >= ' ' && err == nil {
+				err = fmt.Errorf("the font %v doesn't have %q (%U)", t.name, r, r)
+			}
+			continue
+		}
+
+		buf = append(buf, byte(g>>8), byte(g))
+		used[g] = r
+	}
+
+	return buf, err
+}
+
+func tabToSpace(r rune) rune {
+	if r == '\t' {
+		return ' '
+	}
+	return r
+}
@@ -0,0 +1,206 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+// testFont builds a small TrueType font. Its glyphs for the characters from space to z are
+// simple, and ł is a composite glyph made from A. The space is 300 wide and every other glyph is
+// 600.
+func testFont() []byte {
+	be := func(values ...interface{}) []byte {
+		buf := &bytes.Buffer{}
+		for _, v := range values {
+			binary.Write(buf, binary.BigEndian, v)
+		}
+		return buf.Bytes()
+	}
+
+	simple := be(int16(1), []int16{0, 0, 500, 700}, uint16(2), uint16(0), []byte{1, 1, 1, 1})
+	composite := be(int16(-1), []int16{0, 0, 500, 900}, uint16(0), testGlyph('A'), []byte{0, 0})
+	glyphs := [][]byte{simple}
+	for c := ' '; c <= 'z'; c++ {
+		glyphs = append(glyphs, simple)
+	}
+	glyphs = append(glyphs, composite)
+
+	glyf, loca := []byte{}, []byte{}
+	for _, g := range glyphs {
+		loca = append(loca, be(uint16(len(glyf)/2))...)
+		glyf = append(glyf, g...)
+	}
+	loca = append(loca, be(uint16(len(glyf)/2))...)
+
+	cmap := be(uint16(0), uint16(1), uint16(3), uint16(1), uint32(12),
+		uint16(4), uint16(40), uint16(0), uint16(6), uint16(4), uint16(1), uint16(2),
+		[]uint16{'z', 'ł', 0xffff}, uint16(0), []uint16{' ', 'ł', 0xffff},
+		[]int16{int16(testGlyph(' ')) - ' ', int16(testGlyph('ł')) - 'ł', 1}, []uint16{0, 0, 0})
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	copy(head[36:], be([]int16{0, -200, 600, 900}))
+	hhea := make([]byte, 36)
+	copy(hhea[4:], be(int16(800), int16(-200)))
+	binary.BigEndian.PutUint16(hhea[34:], 3)
+	hmtx := be([]uint16{500, 0, 300, 0, 600, 0})
+	maxp := be(uint32(0x5000), uint16(len(glyphs)))
+
+	name := "Test Sans"
+	nameTable := be(uint16(0), uint16(1), uint16(18), uint16(1), uint16(0), uint16(0), uint16(6), uint16(len(name)), uint16(0), []byte(name))
+
+	return writeTrueType([]string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name"}, map[string][]byte{
+		"cmap": cmap, "glyf": glyf, "head": head, "hhea": hhea, "hmtx": hmtx, "loca": loca, "maxp": maxp, "name": nameTable,
+	})
+}
+
+// testGlyph is the glyph of the character in the test font.
+func testGlyph(r rune) uint16 {
+	if r == 'ł' {
+		return 'z' - ' ' + 2
+	}
+	return uint16(r - ' ' + 1)
+}
+
+func TestParseTrueType(t *testing.T) {
+	tt, err := parseTrueType(testFont())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tt.name != "TestSans" {
+		t.Errorf("expected the name TestSans, but was %q", tt.name)
+	}
+
+	tests := []struct {
+		r     rune
+		glyph uint16
+		ok    bool
+		width int
+	}{
+		{' ', 1, true, 300},
+		{'A', 34, true, 600},
+		{'ł', 92, true, 600},
+		{'Ł', 0, false, 0},
+		{'{', 0, false, 0},
+	}
+
+	for _, test := range tests {
+		g, ok := tt.glyph(test.r)
+		if g != test.glyph || ok != test.ok {
+			t.Errorf("glyph(%q) = %v, %v, expected %v, %v", test.r, g, ok, test.glyph, test.ok)
+		}
+		if ok && tt.width(g) != test.width {
+			t.Errorf("width of %q = %v, expected %v", test.r, tt.width(g), test.width)
+		}
+	}
+}
+
+func TestParseTrueTypeErrors(t *testing.T) {
+	missing := writeTrueType([]string{"head"}, map[string][]byte{"head": make([]byte, 54)})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"short", []byte("true"), "too short"},
+		{"cff", append([]byte("OTTO"), make([]byte, 8)...), "PostScript outlines"},
+		{"collection", append([]byte("ttcf"), make([]byte, 8)...), "collection"},
+		{"other", make([]byte, 12), "not a TrueType font"},
+		{"missing tables", missing, `"cmap"`},
+	}
+
+	for _, test := range tests {
+		_, err := parseTrueType(test.data)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%v: expected an error about %q, but was %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	tt, err := parseTrueType(testFont())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	font := tt.subset(map[uint16]rune{testGlyph('ł'): 'ł', testGlyph('B'): 'B'})
+	if checksum(font) != 0xb1b0afba {
+		t.Errorf("expected the subset's checksum to be 0xb1b0afba, but was %#x", checksum(font))
+	}
+
+	// the subset has no cmap, as its glyphs are written by their numbers, so the font's own is
+	// added back to read it.
+	names := []string{"cmap"}
+	tables := map[string][]byte{"cmap": tt.tables["cmap"]}
+	for i := 0; i < int(u16(font, 4)); i++ {
+		record := 12 + 16*i
+		name := string(font[record : record+4])
+		offset, length := u32(font, record+8), u32(font, record+12)
+		names = append(names, name)
+		tables[name] = font[offset : offset+length]
+	}
+	sub, err := parseTrueType(writeTrueType(names, tables))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// ł is made from A, so A is kept along with it.
+	kept := map[uint16]bool{0: true, testGlyph('A'): true, testGlyph('B'): true, testGlyph('ł'): true}
+	for g := 0; g < sub.numGlyphs; g++ {
+		if (sub.loca[g+1] > sub.loca[g]) != kept[uint16(g)] {
+			t.Errorf("expected glyph %v to be kept: %v", g, kept[uint16(g)])
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		err      bool
+	}{
+		{"Amazing", "Amazing", false},
+		{"Café\tau lait", "Caf\xe9 au lait", false},
+		{"“Quoted” – ok", "\x93Quoted\x94 \x96 ok", false},
+		{"Łódź", "\xf3d", true},
+	}
+
+	for _, test := range tests {
+		actual, err := encode(test.text)
+		if string(actual) != test.expected || (err != nil) != test.err {
+			t.Errorf("encode(%q) = %q, %v, expected %q with an error: %v", test.text, actual, err, test.expected, test.err)
+		}
+	}
+}
+
+func TestWriteSongWithFont(t *testing.T) {
+	song := &songtools.Song{Title: "Łała"}
+
+	if err := WriteSong(&bytes.Buffer{}, song); err == nil {
+		t.Errorf("expected an error writing ł in the standard fonts")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteSongWithOptions(buf, song, &WriteOptions{Font: testFont()}); err == nil {
+		t.Errorf("expected an error writing Ł, which the font doesn't have")
+	}
+
+	song.Title = "BAł"
+	buf.Reset()
+	if err := WriteSongWithOptions(buf, song, &WriteOptions{Font: testFont()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pdf := buf.String()
+	for _, expected := range []string{"/Subtype /Type0", "+TestSans", "/FontFile2", "/ToUnicode", "1 [300]", "92 [600]"} {
+		if !strings.Contains(pdf, expected) {
+			t.Errorf("expected the pdf to contain %q", expected)
+		}
+	}
+}
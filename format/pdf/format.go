@@ -0,0 +1,134 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/fingering"
+	"github.com/songtools/songtools/format"
+)
+
+func init() {
+	rw := &pdfWriter{}
+	f := &format.Format{
+		Name:       "pdf",
+		Writer:     rw,
+		Extensions: []string{".pdf"},
+	}
+
+	format.Register(f)
+}
+
+type pdfWriter struct {
+	opts WriteOptions
+}
+
+func (pw *pdfWriter) Write(w io.Writer, s *songtools.Song) error {
+	return WriteSongWithOptions(w, s, &pw.opts)
+}
+
+func (pw *pdfWriter) WriteSet(w io.Writer, s *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, s, &pw.opts)
+}
+
+func (pw *pdfWriter) WriteBook(w io.Writer, b *songtools.Book) error {
+	return WriteBookWithOptions(w, b, &pw.opts)
+}
+
+func (pw *pdfWriter) WithOptions(opts format.Options) (format.Writer, error) {
+	if err := opts.Check(fontOption, boldOption, italicOption, diagramsOption, tuningOption, paperOption, lyricsOption); err != nil {
+		return nil, err
+	}
+
+	// the font is either one of the typefaces or a TrueType file.
+	face := opts[fontOption]
+	var font, bold, italic []byte
+	if _, ok := typefaces[face]; face != "" && !ok {
+		var err error
+		if font, err = readOptionFile(opts, fontOption); err != nil {
+			return nil, err
+		}
+		face = ""
+	}
+	for _, o := range []struct {
+		name string
+		data *[]byte
+	}{{boldOption, &bold}, {italicOption, &italic}} {
+		if _, ok := opts[o.name]; ok && font == nil {
+			return nil, fmt.Errorf("the option %q needs a TrueType file for the option %q", o.name, fontOption)
+		}
+
+		var err error
+		if *o.data, err = readOptionFile(opts, o.name); err != nil {
+			return nil, err
+		}
+	}
+	if font != nil {
+		if _, err := newTypeface(font, bold, italic); err != nil {
+			return nil, err
+		}
+	}
+
+	diagrams, err := opts.Bool(diagramsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	var tuning *fingering.Tuning
+	if name, ok := opts[tuningOption]; ok {
+		tuning, err = fingering.ParseTuning(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lyrics, err := opts.Bool(lyricsOption)
+	if err != nil {
+		return nil, err
+	}
+
+	paper := opts[paperOption]
+	if _, ok := papers[paper]; paper != "" && !ok {
+		return nil, fmt.Errorf("the option %q must be letter or a4, but was %q", paperOption, paper)
+	}
+
+	return &pdfWriter{
+		opts: WriteOptions{
+			Typeface:   face,
+			Font:       font,
+			BoldFont:   bold,
+			ItalicFont: italic,
+			Diagrams:   diagrams,
+			Tuning:     tuning,
+			Paper:      paper,
+			Lyrics:     lyrics,
+		},
+	}, nil
+}
+
+const (
+	fontOption     = "font"
+	boldOption     = "bold"
+	italicOption   = "italic"
+	diagramsOption = "diagrams"
+	tuningOption   = "tuning"
+	paperOption    = "paper"
+	lyricsOption   = "lyrics"
+)
+
+// readOptionFile reads the file named by the option, if it was given.
+func readOptionFile(opts format.Options, name string) ([]byte, error) {
+	path, ok := opts[name]
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the %v file: %v", name, err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,486 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// trueType is a TrueType font to embed in the document, for text the standard fonts can't
+// write. Only its glyph outlines are supported, not the compact font format of some OpenType
+// fonts.
+type trueType struct {
+	name       string
+	tables     map[string][]byte
+	unitsPerEm int
+	numGlyphs  int
+	// advances are the widths of the glyphs, in font units.
+	advances []int
+	// glyphs maps the characters to their glyphs.
+	glyphs map[rune]uint16
+	// loca holds the offset of each glyph in the glyf table, with one more for its end.
+	loca []int
+
+	bbox        [4]int
+	ascent      int
+	descent     int
+	capHeight   int
+	italicAngle float64
+}
+
+// trueTypeTables are the tables kept when the font is subset. The hinting tables are kept so
+// the subset renders as the whole font does.
+var trueTypeTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+func parseTrueType(data []byte) (*trueType, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("the font is too short to be a TrueType font")
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+	case "OTTO":
+		return nil, fmt.Errorf("the font has PostScript outlines, but only TrueType outlines can be embedded")
+	case "ttcf":
+		return nil, fmt.Errorf("the font is a collection, but only a single TrueType font can be embedded")
+	default:
+		return nil, fmt.Errorf("the font is not a TrueType font")
+	}
+
+	t := &trueType{tables: map[string][]byte{}}
+	numTables := int(u16(data, 4))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, fmt.Errorf("the font's table directory is cut off")
+		}
+		offset, length := int(u32(data, record+8)), int(u32(data, record+12))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("the font's %q table is cut off", data[record:record+4])
+		}
+		t.tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+
+	for _, name := range []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp"} {
+		if _, ok := t.tables[name]; !ok {
+			return nil, fmt.Errorf("the font doesn't have a %q table", name)
+		}
+	}
+
+	head, hhea, maxp := t.tables["head"], t.tables["hhea"], t.tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, fmt.Errorf("the font's tables are too short")
+	}
+	if os2 := t.tables["OS/2"]; len(os2) >= 10 && u16(os2, 8)&0xf == 0x2 {
+		return nil, fmt.Errorf("the font's license doesn't allow it to be embedded")
+	}
+
+	t.unitsPerEm = int(u16(head, 18))
+	if t.unitsPerEm == 0 {
+		return nil, fmt.Errorf("the font has no units per em")
+	}
+	t.bbox = [4]int{int(i16(head, 36)), int(i16(head, 38)), int(i16(head, 40)), int(i16(head, 42))}
+	t.ascent, t.descent = int(i16(hhea, 4)), int(i16(hhea, 6))
+	t.capHeight = t.ascent
+	if os2 := t.tables["OS/2"]; len(os2) >= 90 && u16(os2, 0) >= 2 {
+		t.capHeight = int(i16(os2, 88))
+	}
+	if post := t.tables["post"]; len(post) >= 8 {
+		t.italicAngle = float64(int32(u32(post, 4))) / 65536
+	}
+	t.numGlyphs = int(u16(maxp, 4))
+
+	if err := t.parseAdvances(int(u16(hhea, 34))); err != nil {
+		return nil, err
+	}
+	if err := t.parseLoca(i16(head, 50) == 1); err != nil {
+		return nil, err
+	}
+	if err := t.parseCmap(); err != nil {
+		return nil, err
+	}
+	t.name = t.postScriptName()
+
+	return t, nil
+}
+
+func (t *trueType) parseAdvances(numberOfHMetrics int) error {
+	hmtx := t.tables["hmtx"]
+	if numberOfHMetrics == 0 || len(hmtx) < 4*numberOfHMetrics {
+		return fmt.Errorf("the font's hmtx table is cut off")
+	}
+
+	// the glyphs after the last metric are as wide as it is.
+	for g := 0; g < t.numGlyphs; g++ {
+		m := g
+		if m >= numberOfHMetrics {
+			m = numberOfHMetrics - 1
+		}
+		t.advances = append(t.advances, int(u16(hmtx, 4*m)))
+	}
+
+	return nil
+}
+
+func (t *trueType) parseLoca(long bool) error {
+	loca, glyf := t.tables["loca"], t.tables["glyf"]
+	for g := 0; g <= t.numGlyphs; g++ {
+		var offset int
+		if long {
+			if 4*g+4 > len(loca) {
+				return fmt.Errorf("the font's loca table is cut off")
+			}
+			offset = int(u32(loca, 4*g))
+		} else {
+			if 2*g+2 > len(loca) {
+				return fmt.Errorf("the font's loca table is cut off")
+			}
+			offset = 2 * int(u16(loca, 2*g))
+		}
+
+		if offset > len(glyf) || (g > 0 && offset < t.loca[g-1]) {
+			return fmt.Errorf("the font's loca table is out of order")
+		}
+		t.loca = append(t.loca, offset)
+	}
+
+	return nil
+}
+
+// parseCmap reads the characters of the font from its unicode cmap, preferring the one that
+// covers every plane.
+func (t *trueType) parseCmap() error {
+	cmap := t.tables["cmap"]
+	if len(cmap) < 4 {
+		return fmt.Errorf("the font's cmap table is cut off")
+	}
+
+	best, bestRank := -1, 0
+	for i := 0; i < int(u16(cmap, 2)); i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			break
+		}
+
+		platform, encoding, offset := u16(cmap, record), u16(cmap, record+2), int(u32(cmap, record+4))
+		if offset+2 > len(cmap) {
+			continue
+		}
+
+		rank := 0
+		switch format := u16(cmap, offset); {
+		case format == 12 && (platform == 0 || platform == 3 && encoding == 10):
+			rank = 2
+		case format == 4 && (platform == 0 || platform == 3 && encoding == 1):
+			rank = 1
+		}
+		if rank > bestRank {
+			best, bestRank = offset, rank
+		}
+	}
+
+	t.glyphs = map[rune]uint16{}
+	switch bestRank {
+	case 2:
+		return t.parseCmap12(cmap[best:])
+	case 1:
+		return t.parseCmap4(cmap[best:])
+	}
+
+	return fmt.Errorf("the font doesn't map unicode characters to its glyphs")
+}
+
+func (t *trueType) parseCmap4(sub []byte) error {
+	if len(sub) < 14 {
+		return fmt.Errorf("the font's cmap table is cut off")
+	}
+
+	segments := int(u16(sub, 6)) / 2
+	ends, starts := 14, 16+2*segments
+	deltas, rangeOffsets := starts+2*segments, starts+4*segments
+	if rangeOffsets+2*segments > len(sub) {
+		return fmt.Errorf("the font's cmap table is cut off")
+	}
+
+	for s := 0; s < segments; s++ {
+		start, end := int(u16(sub, starts+2*s)), int(u16(sub, ends+2*s))
+		delta, rangeOffset := int(u16(sub, deltas+2*s)), int(u16(sub, rangeOffsets+2*s))
+		for c := start; c <= end && c != 0xffff; c++ {
+			g := (c + delta) & 0xffff
+			if rangeOffset != 0 {
+				at := rangeOffsets + 2*s + rangeOffset + 2*(c-start)
+				if at+2 > len(sub) {
+					break
+				}
+				if g = int(u16(sub, at)); g != 0 {
+					g = (g + delta) & 0xffff
+				}
+			}
+			if g != 0 && g < t.numGlyphs {
+				t.glyphs[rune(c)] = uint16(g)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t *trueType) parseCmap12(sub []byte) error {
+	if len(sub) < 16 {
+		return fmt.Errorf("the font's cmap table is cut off")
+	}
+
+	groups := int(u32(sub, 12))
+	if groups < 0 || 16+12*groups > len(sub) {
+		return fmt.Errorf("the font's cmap table is cut off")
+	}
+
+	for i := 0; i < groups; i++ {
+		group := 16 + 12*i
+		start, end, glyph := int(u32(sub, group)), int(u32(sub, group+4)), int(u32(sub, group+8))
+		for c := start; c <= end && c <= 0x10ffff; c++ {
+			if g := glyph + c - start; g != 0 && g < t.numGlyphs {
+				t.glyphs[rune(c)] = uint16(g)
+			}
+		}
+	}
+
+	return nil
+}
+
+// postScriptName gets the font's PostScript name from its name table, with only the
+// characters a pdf name can hold.
+func (t *trueType) postScriptName() string {
+	name := ""
+	table := t.tables["name"]
+	if len(table) >= 6 {
+		strs := int(u16(table, 4))
+		for i := 0; i < int(u16(table, 2)) && name == ""; i++ {
+			record := 6 + 12*i
+			if record+12 > len(table) {
+				break
+			}
+			if u16(table, record+6) != 6 {
+				continue
+			}
+
+			platform := u16(table, record)
+			length, offset := int(u16(table, record+8)), strs+int(u16(table, record+10))
+			if offset+length > len(table) {
+				continue
+			}
+			value := table[offset : offset+length]
+			switch platform {
+			case 1:
+				name = string(value)
+			case 0, 3:
+				units := []uint16{}
+				for j := 0; j+1 < len(value); j += 2 {
+					units = append(units, u16(value, j))
+				}
+				name = string(utf16.Decode(units))
+			}
+		}
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "Embedded"
+	}
+	return name
+}
+
+// glyph gets the glyph of the character.
+func (t *trueType) glyph(r rune) (uint16, bool) {
+	g, ok := t.glyphs[r]
+	return g, ok
+}
+
+// width is the width of the glyph in thousandths of the font size.
+func (t *trueType) width(g uint16) int {
+	if int(g) >= len(t.advances) {
+		return 0
+	}
+
+	return t.advances[g] * 1000 / t.unitsPerEm
+}
+
+// scale converts the font units to thousandths of the font size.
+func (t *trueType) scale(units int) int {
+	return units * 1000 / t.unitsPerEm
+}
+
+// subset writes a copy of the font with just the glyphs that are used, along with the glyphs
+// they are made of. The others are left empty, so every glyph keeps its number.
+func (t *trueType) subset(used map[uint16]rune) []byte {
+	keep := map[uint16]bool{0: true}
+	pending := []uint16{}
+	for g := range used {
+		pending = append(pending, g)
+	}
+	for len(pending) > 0 {
+		g := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if keep[g] || int(g) >= t.numGlyphs {
+			continue
+		}
+		keep[g] = true
+		pending = append(pending, t.components(g)...)
+	}
+
+	glyf := []byte{}
+	loca := []byte{}
+	for g := 0; g < t.numGlyphs; g++ {
+		loca = appendU32(loca, uint32(len(glyf)))
+		if keep[uint16(g)] {
+			glyf = append(glyf, t.tables["glyf"][t.loca[g]:t.loca[g+1]]...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	loca = appendU32(loca, uint32(len(glyf)))
+
+	head := append([]byte{}, t.tables["head"]...)
+	// the checksum of the whole font is worked out once it is put together, and the new loca
+	// table has long offsets.
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{"glyf": glyf, "head": head, "loca": loca}
+	names := []string{}
+	for _, name := range trueTypeTables {
+		if _, ok := tables[name]; !ok {
+			table, ok := t.tables[name]
+			if !ok {
+				continue
+			}
+			tables[name] = table
+		}
+		names = append(names, name)
+	}
+
+	font := writeTrueType(names, tables)
+	for i, name := range names {
+		if name == "head" {
+			offset := int(u32(font, 12+16*i+8))
+			binary.BigEndian.PutUint32(font[offset+8:], 0xb1b0afba-checksum(font))
+		}
+	}
+
+	return font
+}
+
+// components gets the glyphs a composite glyph is made of.
+func (t *trueType) components(g uint16) []uint16 {
+	data := t.tables["glyf"][t.loca[g]:t.loca[g+1]]
+	if len(data) < 10 || i16(data, 0) >= 0 {
+		return nil
+	}
+
+	const (
+		argsAreWords   = 0x1
+		haveScale      = 0x8
+		moreComponents = 0x20
+		haveXYScale    = 0x40
+		haveTwoByTwo   = 0x80
+	)
+
+	components := []uint16{}
+	for at := 10; at+4 <= len(data); {
+		flags := u16(data, at)
+		components = append(components, u16(data, at+2))
+		at += 4
+
+		if flags&argsAreWords != 0 {
+			at += 4
+		} else {
+			at += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			at += 2
+		case flags&haveXYScale != 0:
+			at += 4
+		case flags&haveTwoByTwo != 0:
+			at += 8
+		}
+
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+
+	return components
+}
+
+// writeTrueType puts the tables together into a font file.
+func writeTrueType(names []string, tables map[string][]byte) []byte {
+	sort.Strings(names)
+
+	entrySelector := 0
+	for 1<<uint(entrySelector+1) <= len(names) {
+		entrySelector++
+	}
+	searchRange := 16 << uint(entrySelector)
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, []uint16{1, 0, uint16(len(names)), uint16(searchRange), uint16(entrySelector), uint16(16*len(names) - searchRange)})
+
+	offset := 12 + 16*len(names)
+	for _, name := range names {
+		table := tables[name]
+		buf.WriteString(name)
+		binary.Write(buf, binary.BigEndian, []uint32{checksum(table), uint32(offset), uint32(len(table))})
+		offset += (len(table) + 3) &^ 3
+	}
+	for _, name := range names {
+		buf.Write(tables[name])
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// checksum adds up the data as big endian 32 bit numbers, padded with zeros.
+func checksum(data []byte) uint32 {
+	sum := uint32(0)
+	for i := 0; i < len(data); i += 4 {
+		word := [4]byte{}
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+
+	return sum
+}
+
+func u16(b []byte, at int) uint16 {
+	if at < 0 || at+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[at:])
+}
+
+func appendU32(b []byte, n uint32) []byte {
+	return append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func i16(b []byte, at int) int16 {
+	return int16(u16(b, at))
+}
+
+func u32(b []byte, at int) uint32 {
+	if at < 0 || at+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[at:])
+}
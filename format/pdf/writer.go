@@ -0,0 +1,602 @@
+// Package pdf writes songs as pdf documents, ready to print, without the help of a browser.
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/songtools/songtools"
+	"github.com/songtools/songtools/fingering"
+)
+
+// paperSize is the width and height of a page, in points.
+type paperSize struct {
+	width  float64
+	height float64
+}
+
+// papers are the sizes of paper that can be written for.
+var papers = map[string]paperSize{
+	"letter": {612, 792},
+	"a4":     {595.28, 841.89},
+}
+
+const (
+	defaultPaper = "letter"
+	// margin is the space around the song on each page. The headers and footers are in it.
+	margin = 54
+
+	titleSize    = 20
+	subtitleSize = 13
+	headingSize  = 12
+	lyricSize    = 11
+	tabSize      = 10
+	smallSize    = 9
+	// leading is the height of a line of text as a multiple of the size of its font.
+	leading = 1.3
+
+	// sectionGap is the space after each section.
+	sectionGap = 10
+	// chorusIndent is how far choruses are indented. A bar to their left marks them.
+	chorusIndent = 14
+)
+
+// WriteOptions control how a song is written.
+type WriteOptions struct {
+	// Typeface is proportional or monospace. It is proportional when not given. Tabs and grids
+	// are always monospace so their columns line up.
+	Typeface string
+	// Font is a TrueType font to write the songs in, in place of the typeface. It is embedded
+	// in the document, so it can write characters the standard fonts can't.
+	Font []byte
+	// BoldFont and ItalicFont are the TrueType fonts for the titles and comments. The Font is
+	// used in their place when they aren't given.
+	BoldFont   []byte
+	ItalicFont []byte
+	// Diagrams draws a chord diagram for each different chord in the song under its title.
+	Diagrams bool
+	// Tuning is the instrument the diagrams are for. It is the guitar when not given.
+	Tuning *fingering.Tuning
+	// Paper is the size of the page, letter or a4. It is letter when not given.
+	Paper string
+	// Lyrics writes just the words of the songs, without their chords.
+	Lyrics bool
+}
+
+// WriteSong writes a single song to the writer.
+func WriteSong(w io.Writer, s *songtools.Song) error {
+	return WriteSongWithOptions(w, s, &WriteOptions{})
+}
+
+// WriteSongWithOptions writes a single song to the writer using the options.
+func WriteSongWithOptions(w io.Writer, s *songtools.Song, opts *WriteOptions) error {
+	return WriteSongSetWithOptions(w, &songtools.SongSet{Songs: []*songtools.Song{s}}, opts)
+}
+
+// WriteSongSet writes all the songs in the set to a single document.
+func WriteSongSet(w io.Writer, set *songtools.SongSet) error {
+	return WriteSongSetWithOptions(w, set, &WriteOptions{})
+}
+
+// WriteSongSetWithOptions writes all the songs in the set to a single document using the
+// options. Each song starts on a new page and the pages are numbered at the bottom.
+func WriteSongSetWithOptions(w io.Writer, set *songtools.SongSet, opts *WriteOptions) error {
+	titles := []string{}
+	for _, s := range set.Songs {
+		if s.Title != "" {
+			titles = append(titles, s.Title)
+		}
+	}
+
+	doc, face, err := newSongDocument(strings.Join(titles, " / "), opts)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range lyricsOnly(set.Songs, opts) {
+		sw := &songWriter{
+			doc:  doc,
+			opts: opts,
+			face: face,
+			song: s,
+		}
+		sw.writeSong()
+	}
+
+	// a document needs at least one page, even without songs.
+	if len(doc.pages) == 0 {
+		doc.addPage()
+	}
+	writeFooters(doc, face, 0)
+
+	return doc.write(w)
+}
+
+// lyricsOnly takes just the words of the songs when the options ask for them.
+func lyricsOnly(songs []*songtools.Song, opts *WriteOptions) []*songtools.Song {
+	if !opts.Lyrics {
+		return songs
+	}
+
+	lyrics := []*songtools.Song{}
+	for _, s := range songs {
+		lyrics = append(lyrics, songtools.LyricsOnly(s))
+	}
+	return lyrics
+}
+
+// newSongDocument starts a document on the paper of the options, along with the typeface its
+// text is written in.
+func newSongDocument(title string, opts *WriteOptions) (*document, *typeface, error) {
+	paper := opts.Paper
+	if paper == "" {
+		paper = defaultPaper
+	}
+	size, ok := papers[paper]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown paper %q, the papers are letter and a4", paper)
+	}
+
+	if opts.Font != nil {
+		face, err := newTypeface(opts.Font, opts.BoldFont, opts.ItalicFont)
+		if err != nil {
+			return nil, nil, err
+		}
+		return newDocument(title, size.width, size.height), face, nil
+	}
+
+	name := opts.Typeface
+	if name == "" {
+		name = defaultTypeface
+	}
+	face, ok := typefaces[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown typeface %q, the typefaces are proportional and monospace", name)
+	}
+
+	return newDocument(title, size.width, size.height), face, nil
+}
+
+// writeFooters numbers the pages at the bottom of each, starting from the page at first. The
+// pages before it still count.
+func writeFooters(doc *document, face *typeface, first int) {
+	for i := first; i < len(doc.pages); i++ {
+		p := doc.pages[i]
+		text := fmt.Sprintf("Page %d of %d", i+1, len(doc.pages))
+		p.text(face.regular, smallSize, (doc.width-face.regular.width(text, smallSize))/2, doc.height-margin/2, text)
+	}
+}
+
+// songWriter writes a single song, starting on a new page.
+type songWriter struct {
+	doc  *document
+	opts *WriteOptions
+	face *typeface
+	song *songtools.Song
+	// number is the number of the song in a book, or 0 when it isn't in one.
+	number int
+	page   *page
+	// y is the top of the space left on the page.
+	y float64
+}
+
+// block is something drawn on a page that is never split between pages, such as a lyric line
+// along with its chords. It is drawn with its top left corner at x and y.
+type block struct {
+	height float64
+	draw   func(p *page, x, y float64)
+}
+
+func textBlock(f *font, size float64, text string) block {
+	return block{
+		height: size * leading,
+		draw: func(p *page, x, y float64) {
+			p.text(f, size, x, y+size, text)
+		},
+	}
+}
+
+// joinBlocks makes one block of the two, so they are kept on the same page.
+func joinBlocks(first, second block) block {
+	return block{
+		height: first.height + second.height,
+		draw: func(p *page, x, y float64) {
+			first.draw(p, x, y)
+			second.draw(p, x, y+first.height)
+		},
+	}
+}
+
+// newPage starts a new page. Every page but the first of a song has a running header with the
+// song's title and key, since the first has them written out in full.
+func (sw *songWriter) newPage() {
+	first := sw.page == nil
+	sw.page = sw.doc.addPage()
+	sw.y = margin
+	if first {
+		return
+	}
+
+	right := sw.doc.width - margin
+	sw.page.text(sw.face.bold, smallSize, margin, margin-18, sw.title())
+	if key := keyText(sw.song); key != "" {
+		sw.page.text(sw.face.regular, smallSize, right-sw.face.regular.width(key, smallSize), margin-18, key)
+	}
+	sw.page.line(margin, margin-12, right, margin-12, 0.5)
+}
+
+// title is the title of the song, after its number in a book.
+func (sw *songWriter) title() string {
+	if sw.number > 0 {
+		return fmt.Sprintf("%d. %v", sw.number, strings.TrimSpace(sw.song.Title))
+	}
+
+	return sw.song.Title
+}
+
+// keyText describes the key of the song, along with the capo when there is one.
+func keyText(s *songtools.Song) string {
+	if s.Key.IsZero() {
+		return ""
+	}
+
+	text := "Key: " + s.Key.String()
+	if s.Capo > 0 {
+		text += fmt.Sprintf(", Capo: %d", s.Capo)
+	}
+	return text
+}
+
+func (sw *songWriter) writeSong() {
+	sw.newPage()
+
+	s := sw.song
+	header := []block{}
+	if title := sw.title(); title != "" {
+		header = append(header, textBlock(sw.face.bold, titleSize, title))
+	}
+	if len(s.Subtitles) > 0 {
+		header = append(header, textBlock(sw.face.regular, subtitleSize, strings.Join(s.Subtitles, " / ")))
+	}
+	if len(s.Authors) > 0 {
+		header = append(header, textBlock(sw.face.italic, smallSize+1, "Author(s): "+strings.Join(s.Authors, " / ")))
+	}
+	if key := keyText(s); key != "" {
+		header = append(header, textBlock(sw.face.bold, smallSize+1, key))
+	}
+	sw.place(header, false)
+
+	sw.y += 4
+	sw.page.line(margin, sw.y, sw.doc.width-margin, sw.y, 0.5)
+	sw.y += sectionGap
+
+	if sw.opts.Diagrams {
+		sw.writeDiagrams()
+	}
+
+	for _, n := range s.Nodes {
+		sw.writeSongNode(n)
+	}
+}
+
+func (sw *songWriter) writeSongNode(n songtools.SongNode) {
+	switch typedN := n.(type) {
+	case *songtools.Comment:
+		if !typedN.Hidden {
+			sw.place([]block{textBlock(sw.face.italic, lyricSize, typedN.Text)}, false)
+		}
+	case *songtools.KeyChange:
+		sw.place([]block{keyChangeBlock(sw.face, typedN)}, false)
+	case *songtools.Section:
+		sw.writeSection(typedN)
+	}
+}
+
+// writeSection writes the section under a bold heading, which is kept on the same page as
+// the first line of the section. A comment at the start of the section is written as part of
+// its heading.
+func (sw *songWriter) writeSection(s *songtools.Section) {
+	anyChords := len(s.Chords()) > 0
+	nodes := s.Nodes
+
+	var heading *block
+	if s.Kind != "" {
+		text := s.Heading()
+		if len(nodes) > 0 {
+			if c, ok := nodes[0].(*songtools.Comment); ok {
+				text += " " + c.Text
+				nodes = nodes[1:]
+			}
+		}
+		b := textBlock(sw.face.bold, headingSize, text)
+		heading = &b
+	}
+
+	width := sw.doc.width - 2*margin
+	if isChorus(s.Kind) {
+		width -= chorusIndent
+	}
+
+	blocks := []block{}
+	for _, n := range nodes {
+		blocks = append(blocks, sw.sectionBlocks(n, anyChords, width)...)
+	}
+	if heading != nil {
+		if len(blocks) > 0 {
+			blocks[0] = joinBlocks(*heading, blocks[0])
+		} else {
+			blocks = append(blocks, *heading)
+		}
+	}
+
+	sw.place(blocks, isChorus(s.Kind))
+	sw.y += sectionGap
+}
+
+// isChorus indicates the first word of the kind of section is chorus.
+func isChorus(kind songtools.SectionKind) bool {
+	words := strings.Fields(string(kind))
+	return len(words) > 0 && strings.EqualFold(words[0], "chorus")
+}
+
+// place draws the blocks one after the other. When they would fit on a page, but not in the
+// space left on this one, they are moved to a new page. Otherwise, a new page is started
+// whenever the next block doesn't fit. Choruses are indented and marked with a bar.
+func (sw *songWriter) place(blocks []block, chorus bool) {
+	bottom := sw.doc.height - margin
+	total := 0.0
+	for _, b := range blocks {
+		total += b.height
+	}
+	if sw.y > margin && sw.y+total > bottom && total <= bottom-margin {
+		sw.newPage()
+	}
+
+	indent := 0.0
+	if chorus {
+		indent = chorusIndent
+	}
+	for _, b := range blocks {
+		if sw.y > margin && sw.y+b.height > bottom {
+			sw.newPage()
+		}
+		b.draw(sw.page, margin+indent, sw.y)
+		if chorus {
+			sw.page.line(margin+4, sw.y, margin+4, sw.y+b.height, 1.5)
+		}
+		sw.y += b.height
+	}
+}
+
+// sectionBlocks writes the node of a section. Lyric lines are wrapped to the width.
+func (sw *songWriter) sectionBlocks(n songtools.SectionNode, anyChords bool, width float64) []block {
+	switch typedN := n.(type) {
+	case *songtools.Comment:
+		if typedN.Hidden {
+			return nil
+		}
+		return []block{textBlock(sw.face.italic, lyricSize, typedN.Text)}
+	case *songtools.KeyChange:
+		return []block{keyChangeBlock(sw.face, typedN)}
+	case *songtools.Tab:
+		return monospaceBlocks(courier, typedN.Lines)
+	case *songtools.Grid:
+		return monospaceBlocks(courierBold, typedN.Lines())
+	case *songtools.Line:
+		return sw.lineBlocks(typedN, anyChords, width)
+	default:
+		return nil
+	}
+}
+
+func keyChangeBlock(face *typeface, k *songtools.KeyChange) block {
+	return textBlock(face.bold, lyricSize, "Key: "+k.Key.String())
+}
+
+// monospaceBlocks writes each line in the monospace font, so tabs and grids keep their columns.
+func monospaceBlocks(f *font, lines []string) []block {
+	blocks := []block{}
+	for _, l := range lines {
+		blocks = append(blocks, textBlock(f, tabSize, l))
+	}
+	return blocks
+}
+
+// lineBlocks writes the line with its chords above it, wrapped before the words that would
+// go past the width. Each row of the line is a block of its own. When any line in the section
+// has chords, the lines without them leave room for them so the lines are evenly spaced.
+func (sw *songWriter) lineBlocks(l *songtools.Line, blankLineForNoChords bool, width float64) []block {
+	regular, bold := sw.face.regular, sw.face.bold
+
+	chordHeight := 0.0
+	if l.Chords != nil || blankLineForNoChords {
+		chordHeight = lyricSize * leading
+	}
+
+	blocks := []block{}
+	for _, row := range wrapPieces(regular, bold, linePieces(regular, l), width) {
+		row := row
+		xs, _ := layoutPieces(regular, bold, row)
+
+		text := ""
+		for _, p := range row {
+			text += p.text
+		}
+		lyricHeight := lyricSize * leading
+		if l.Chords != nil && text == "" {
+			lyricHeight = 0
+		}
+
+		blocks = append(blocks, block{
+			height: chordHeight + lyricHeight,
+			draw: func(p *page, x, y float64) {
+				for i, piece := range row {
+					if piece.chord != nil {
+						p.text(bold, lyricSize, x+xs[i], y+lyricSize, piece.chord.Name)
+					}
+				}
+				if lyricHeight == 0 {
+					return
+				}
+
+				// the text is drawn in runs, which are broken where a chord widened it.
+				run, start := "", 0.0
+				for i, piece := range row {
+					if i > 0 && xs[i] != xs[i-1]+row[i-1].width {
+						p.text(regular, lyricSize, x+start, y+chordHeight+lyricSize, run)
+						run = ""
+					}
+					if run == "" {
+						start = xs[i]
+					}
+					run += piece.text
+				}
+				if run != "" {
+					p.text(regular, lyricSize, x+start, y+chordHeight+lyricSize, run)
+				}
+			},
+		})
+	}
+
+	return blocks
+}
+
+// linePiece is a piece of a lyric line that is laid out as a whole. The line is cut into pieces
+// at each chord and at the start of each word.
+type linePiece struct {
+	text string
+	// chord is the chord over the start of the piece, if any.
+	chord *songtools.Chord
+	// width is how far the next piece starts from this one, unless a chord widens it.
+	width float64
+	// wordStart indicates the line can be wrapped before the piece.
+	wordStart bool
+}
+
+func linePieces(f *font, l *songtools.Line) []linePiece {
+	type cut struct {
+		pos   int
+		chord *songtools.Chord
+	}
+
+	// chords past the end of the text are cut at their own positions, so they keep the columns
+	// between them.
+	last := len(l.Text)
+	for _, pos := range l.ChordPositions {
+		if pos > last {
+			last = pos
+		}
+	}
+
+	cuts := []cut{}
+	c := 0
+	for pos := 0; pos <= last; pos++ {
+		if pos < len(l.Text) && !utf8.RuneStart(l.Text[pos]) {
+			continue
+		}
+
+		chord := false
+		for ; c < len(l.Chords) && l.ChordPositions[c] <= pos; c++ {
+			cuts = append(cuts, cut{pos, l.Chords[c]})
+			chord = true
+		}
+		if !chord && (pos == 0 || pos < len(l.Text) && isWordStart(l.Text, pos)) {
+			cuts = append(cuts, cut{pos: pos})
+		}
+	}
+
+	pieces := []linePiece{}
+	for i, c := range cuts {
+		next := last
+		if i+1 < len(cuts) {
+			next = cuts[i+1].pos
+		}
+
+		text := ""
+		if c.pos < len(l.Text) {
+			text = l.Text[c.pos:minInt(next, len(l.Text))]
+		}
+		pieces = append(pieces, linePiece{
+			text:      text,
+			chord:     c.chord,
+			width:     textOffset(f, l.Text, next) - textOffset(f, l.Text, c.pos),
+			wordStart: c.pos > len(l.Text) || c.pos > 0 && isWordStart(l.Text, c.pos),
+		})
+	}
+
+	return pieces
+}
+
+// isWordStart indicates a word starts at the position of the text, after a space.
+func isWordStart(text string, pos int) bool {
+	return pos > 0 && text[pos-1] == ' ' && (pos == len(text) || text[pos] != ' ')
+}
+
+// wrapPieces splits the pieces into rows that fit in the width, wrapping before the word that
+// would go past it. A word wider than the width gets a row of its own.
+func wrapPieces(regular, bold *font, pieces []linePiece, width float64) [][]linePiece {
+	rows := [][]linePiece{}
+	start, lastBreak := 0, 0
+	for i := 1; i <= len(pieces); i++ {
+		if i < len(pieces) && !pieces[i].wordStart {
+			continue
+		}
+
+		if _, w := layoutPieces(regular, bold, pieces[start:i]); w > width && lastBreak > start {
+			rows = append(rows, pieces[start:lastBreak])
+			start = lastBreak
+		}
+		lastBreak = i
+	}
+
+	return append(rows, pieces[start:])
+}
+
+// layoutPieces places the pieces of a row, getting where each starts along with how wide the
+// row is. Each chord starts over its text. When it would overlap the chord
+// before it, the lyric is widened where the chord is, so the chord still starts over its
+// syllable.
+func layoutPieces(regular, bold *font, pieces []linePiece) (xs []float64, width float64) {
+	x, chordEnd := 0.0, 0.0
+	for _, p := range pieces {
+		if p.chord != nil {
+			if x < chordEnd {
+				x = chordEnd
+			}
+			chordEnd = x + bold.width(p.chord.Name+" ", lyricSize)
+			width = math.Max(width, x+bold.width(p.chord.Name, lyricSize))
+		}
+		xs = append(xs, x)
+
+		if words := strings.TrimRight(p.text, " "); words != "" {
+			width = math.Max(width, x+regular.width(words, lyricSize))
+		}
+		x += p.width
+	}
+
+	return xs, width
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// textOffset is how far into the text its byte position is. Positions past the end of the
+// text are counted in columns as wide as a digit, since that's how chords without lyrics
+// under them are spaced.
+func textOffset(f *font, text string, pos int) float64 {
+	if pos > len(text) {
+		return f.width(text, lyricSize) + float64(pos-len(text))*f.width("0", lyricSize)
+	}
+
+	for pos > 0 && pos < len(text) && !utf8.RuneStart(text[pos]) {
+		pos--
+	}
+	return f.width(text[:pos], lyricSize)
+}
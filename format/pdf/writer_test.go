@@ -0,0 +1,97 @@
+package pdf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/songtools/songtools"
+)
+
+// testLine builds a line from chordpro style text, such as "[G]Amazing [C]grace".
+func testLine(text string) *songtools.Line {
+	l := &songtools.Line{}
+	for {
+		start := strings.Index(text, "[")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text, "]")
+		c, ok := songtools.ParseChord(text[start+1 : end])
+		if !ok {
+			panic("invalid chord " + text[start+1:end])
+		}
+		l.Chords = append(l.Chords, c)
+		l.ChordPositions = append(l.ChordPositions, start)
+		text = text[:start] + text[end+1:]
+	}
+	l.Text = text
+	return l
+}
+
+func TestWrapPieces(t *testing.T) {
+	tests := []struct {
+		line     string
+		width    float64
+		expected []string
+	}{
+		{"Amazing grace how sweet the sound", 1000, []string{"Amazing grace how sweet the sound"}},
+		{"Amazing grace how sweet the sound", 100, []string{"Amazing grace how ", "sweet the sound"}},
+		{"[G]Amazing [G7]grace how [C]sweet the [G]sound", 100, []string{"Amazing grace how ", "sweet the sound"}},
+		{"Hal[F]le[C]lu[G]jah", 10, []string{"Hallelujah"}},
+		{"[G]   [C]   [D]", 1000, []string{"      "}},
+		{"", 100, []string{""}},
+	}
+
+	for _, test := range tests {
+		rows := wrapPieces(helvetica, helveticaBold, linePieces(helvetica, testLine(test.line)), test.width)
+		actual := []string{}
+		for _, row := range rows {
+			text := ""
+			for _, p := range row {
+				text += p.text
+			}
+			actual = append(actual, text)
+		}
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("wrapPieces(%q, %v) = %q, expected %q", test.line, test.width, actual, test.expected)
+		}
+	}
+}
+
+func TestLayoutPieces(t *testing.T) {
+	tests := []struct {
+		line string
+		// widened are the chords that widen the lyric under them.
+		widened []string
+	}{
+		{"[G]Amazing [C]grace", nil},
+		{"[G]A[C]mazing grace", []string{"C"}},
+		{"[Gmaj7]A[Cmaj7]ma[D7sus4]zing grace", []string{"Cmaj7", "D7sus4"}},
+		{"[G][C]Amazing", []string{"C"}},
+	}
+
+	for _, test := range tests {
+		pieces := linePieces(helvetica, testLine(test.line))
+		xs, _ := layoutPieces(helvetica, helveticaBold, pieces)
+
+		widened := []string(nil)
+		end := 0.0
+		for i, p := range pieces {
+			if p.chord != nil {
+				if xs[i] < end {
+					t.Errorf("%q: expected %v not to overlap the chord before it", test.line, p.chord.Name)
+				}
+				end = xs[i] + helveticaBold.width(p.chord.Name, lyricSize)
+			}
+			if i > 0 && xs[i] != xs[i-1]+pieces[i-1].width {
+				widened = append(widened, p.chord.Name)
+			}
+		}
+
+		if !reflect.DeepEqual(widened, test.widened) {
+			t.Errorf("%q: expected the lyric to be widened at %q, but was at %q", test.line, test.widened, widened)
+		}
+	}
+}
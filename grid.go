@@ -0,0 +1,297 @@
+package songtools
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Grid is a chord chart made up of rows of bars, such as | C . . . | G . . . |.
+type Grid struct {
+	Rows []*GridRow
+	Span *Span
+}
+
+// GridRow is a line of bars in a grid.
+type GridRow struct {
+	// Label is the text in the margin before the first bar, such as Intro. It is optional.
+	Label string
+	Bars  []*Bar
+	// End is the barline that closes the last bar.
+	End Barline
+	// Comment is the text in the margin after the last bar. It is optional.
+	Comment string
+}
+
+// Bar is a single measure of a grid.
+type Bar struct {
+	// Barline is the barline that opens the bar.
+	Barline Barline
+	// Volta is the number of the ending the bar starts, such as 1 for |1, or 0 when it
+	// doesn't start one.
+	Volta int
+	// Beats holds the chord played on each beat. A beat on which the previous chord
+	// carries on is nil.
+	Beats []*Chord
+	// Slashes indicates which of the beats on which the previous chord carries on are
+	// written as a / rather than a dot. It lines up with Beats and may be shorter.
+	Slashes []bool
+	// Simile indicates the bar is a repeat of the bar before it, written as %.
+	Simile bool
+}
+
+// Slash indicates the previous chord carries on in the beat and is written as a /.
+func (b *Bar) Slash(beat int) bool {
+	return beat < len(b.Beats) && b.Beats[beat] == nil && beat < len(b.Slashes) && b.Slashes[beat]
+}
+
+// Barline is the kind of line between two bars.
+type Barline int
+
+// Barlines
+const (
+	NoBarline Barline = iota
+	SingleBarline
+	DoubleBarline
+	FinalBarline
+	RepeatStartBarline
+	RepeatEndBarline
+	RepeatBothBarline
+)
+
+var barlineSymbols = []string{"", "|", "||", "|.", "|:", ":|", ":|:"}
+
+func (b Barline) String() string {
+	if b < 0 || int(b) >= len(barlineSymbols) {
+		return ""
+	}
+
+	return barlineSymbols[b]
+}
+
+// RepeatStart indicates the barline starts a repeated passage.
+func (b Barline) RepeatStart() bool {
+	return b == RepeatStartBarline || b == RepeatBothBarline
+}
+
+// RepeatEnd indicates the barline ends a repeated passage.
+func (b Barline) RepeatEnd() bool {
+	return b == RepeatEndBarline || b == RepeatBothBarline
+}
+
+const (
+	continueBeat = "."
+	slashBeat    = "/"
+	simileBar    = "%"
+)
+
+// Chords gets all the chords present in the grid.
+func (g *Grid) Chords() []*Chord {
+	chords := []*Chord{}
+	for _, r := range g.Rows {
+		for _, b := range r.Bars {
+			for _, c := range b.Beats {
+				if c != nil {
+					chords = append(chords, c)
+				}
+			}
+		}
+	}
+
+	return chords
+}
+
+// Lines formats each row of the grid as text. The barlines and beats of each bar are lined up
+// in columns with those of the other rows.
+func (g *Grid) Lines() []string {
+	labelWidth := 0
+	barlineWidths := []int{}
+	beatWidths := [][]int{}
+	for _, r := range g.Rows {
+		labelWidth = maxInt(labelWidth, utf8.RuneCountInString(r.Label))
+		for i, b := range r.Bars {
+			if i == len(barlineWidths) {
+				barlineWidths = append(barlineWidths, 0)
+				beatWidths = append(beatWidths, []int{})
+			}
+			barlineWidths[i] = maxInt(barlineWidths[i], utf8.RuneCountInString(barlineText(b)))
+			for j, beat := range beatTexts(b) {
+				if j == len(beatWidths[i]) {
+					beatWidths[i] = append(beatWidths[i], 0)
+				}
+				beatWidths[i][j] = maxInt(beatWidths[i][j], utf8.RuneCountInString(beat))
+			}
+		}
+	}
+
+	lines := []string{}
+	for _, r := range g.Rows {
+		line := ""
+		if labelWidth > 0 {
+			line += pad(r.Label, labelWidth) + " "
+		}
+		for i, b := range r.Bars {
+			line += pad(barlineText(b), barlineWidths[i]) + " "
+			beats := beatTexts(b)
+			for j, w := range beatWidths[i] {
+				beat := ""
+				if j < len(beats) {
+					beat = beats[j]
+				}
+				line += pad(beat, w) + " "
+			}
+		}
+		line += r.End.String()
+		if r.Comment != "" {
+			line += " " + r.Comment
+		}
+
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+
+	return lines
+}
+
+// ParseGridRow parses a row of a grid, such as "Intro |: C . . . | G . . . :|". The row needs
+// at least two barlines and every beat between them needs to be a chord, a . or / for a beat on
+// which the previous chord carries on, or a % for a bar that repeats the one before it. The
+// text before the first barline is the row's label and any text after the last barline that
+// isn't a bar is its comment.
+func ParseGridRow(text string) (*GridRow, bool) {
+	return ParseGridRowWithChords(text, ParseChord)
+}
+
+// ParseGridRowWithChords parses a row of a grid as ParseGridRow does, recognizing the chords
+// with parseChord, such as to read the Nashville numbers of a number chart.
+func ParseGridRowWithChords(text string, parseChord func(string) (*Chord, bool)) (*GridRow, bool) {
+	fields := strings.Fields(text)
+
+	first, last := -1, -1
+	for i, f := range fields {
+		if _, _, ok := parseBarline(f); ok {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 || first == last {
+		return nil, false
+	}
+
+	row := &GridRow{
+		Label: strings.Join(fields[:first], " "),
+	}
+
+	var bar *Bar
+	for _, f := range fields[first : last+1] {
+		if barline, v, ok := parseBarline(f); ok {
+			bar = &Bar{
+				Barline: barline,
+				Volta:   v,
+			}
+			row.Bars = append(row.Bars, bar)
+		} else if !bar.addBeat(f, parseChord) {
+			return nil, false
+		}
+	}
+
+	// whatever follows the last barline is either its bar or a comment.
+	for _, f := range fields[last+1:] {
+		if !bar.addBeat(f, parseChord) {
+			bar.Beats = nil
+			bar.Slashes = nil
+			bar.Simile = false
+			row.Comment = strings.Join(fields[last+1:], " ")
+			break
+		}
+	}
+
+	// the last barline closes the row rather than opening a bar, unless a bar follows it.
+	if end := row.Bars[len(row.Bars)-1]; len(end.Beats) == 0 && !end.Simile && end.Volta == 0 {
+		row.End = end.Barline
+		row.Bars = row.Bars[:len(row.Bars)-1]
+	}
+
+	return row, true
+}
+
+// addBeat adds the beat to the bar, or indicates the text isn't a beat.
+func (b *Bar) addBeat(text string, parseChord func(string) (*Chord, bool)) bool {
+	switch text {
+	case continueBeat, slashBeat:
+		b.Beats = append(b.Beats, nil)
+		b.Slashes = append(b.Slashes, text == slashBeat)
+	case simileBar:
+		b.Simile = true
+	default:
+		chord, ok := parseChord(text)
+		if !ok {
+			return false
+		}
+		b.Beats = append(b.Beats, chord)
+		b.Slashes = append(b.Slashes, false)
+	}
+
+	return true
+}
+
+// parseBarline parses a barline, which may be followed by the number of an ending, as in |1.
+func parseBarline(text string) (Barline, int, bool) {
+	symbol := strings.TrimRight(text, "0123456789")
+	v := 0
+	if symbol != text {
+		n, err := strconv.Atoi(text[len(symbol):])
+		if err != nil || n == 0 {
+			return NoBarline, 0, false
+		}
+		v = n
+	}
+
+	for i, s := range barlineSymbols {
+		if s != "" && s == symbol {
+			return Barline(i), v, true
+		}
+	}
+
+	return NoBarline, 0, false
+}
+
+func beatTexts(b *Bar) []string {
+	if b.Simile {
+		return []string{simileBar}
+	}
+
+	texts := []string{}
+	for i, c := range b.Beats {
+		if b.Slash(i) {
+			texts = append(texts, slashBeat)
+		} else if c == nil {
+			texts = append(texts, continueBeat)
+		} else {
+			texts = append(texts, c.Name)
+		}
+	}
+
+	return texts
+}
+
+func barlineText(b *Bar) string {
+	if b.Volta == 0 {
+		return b.Barline.String()
+	}
+
+	return b.Barline.String() + strconv.Itoa(b.Volta)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func pad(text string, width int) string {
+	return text + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+}
@@ -0,0 +1,146 @@
+package songtools
+
+import (
+	"fmt"
+	"testing"
+)
+
+// barText describes a bar by its barline and beats, as in "|1 C . G .".
+func barText(b *Bar) string {
+	text := barlineText(b)
+	for _, beat := range beatTexts(b) {
+		text += " " + beat
+	}
+
+	return text
+}
+
+func TestParseGridRow(t *testing.T) {
+	tests := []struct {
+		text    string
+		label   string
+		bars    []string
+		end     Barline
+		comment string
+	}{
+		{"Intro |: C . . . | G . . . :|", "Intro", []string{"|: C . . .", "| G . . ."}, RepeatEndBarline, ""},
+		{"| C / / / | % | G . . . |.", "", []string{"| C / / /", "| %", "| G . . ."}, FinalBarline, ""},
+		{"| C . / . | G / . / |", "", []string{"| C . / .", "| G / . /"}, SingleBarline, ""},
+		{"|: C . . . |1 G . . . :|2 F . . . ||", "", []string{"|: C . . .", "|1 G . . .", ":|2 F . . ."}, DoubleBarline, ""},
+		// the text after the last barline is a comment unless it's all beats.
+		{"| C . . . | G . . . | play twice", "", []string{"| C . . .", "| G . . ."}, SingleBarline, "play twice"},
+		{"| C . . . | G C", "", []string{"| C . . .", "| G C"}, NoBarline, ""},
+		{"| C . . . | %", "", []string{"| C . . .", "| %"}, NoBarline, ""},
+		{"| C . . . | G x2", "", []string{"| C . . ."}, SingleBarline, "G x2"},
+		// an ending opens a bar even when nothing follows it.
+		{"|: C . . . |1", "", []string{"|: C . . .", "|1"}, NoBarline, ""},
+	}
+
+	for _, test := range tests {
+		row, ok := ParseGridRow(test.text)
+		if !ok {
+			t.Errorf("ParseGridRow(%q) failed", test.text)
+			continue
+		}
+
+		bars := []string{}
+		for _, b := range row.Bars {
+			bars = append(bars, barText(b))
+		}
+		if row.Label != test.label || fmt.Sprint(bars) != fmt.Sprint(test.bars) || row.End != test.end || row.Comment != test.comment {
+			t.Errorf("ParseGridRow(%q) = %q %q %q %q, expected %q %q %q %q",
+				test.text, row.Label, bars, row.End, row.Comment, test.label, test.bars, test.end, test.comment)
+		}
+	}
+}
+
+func TestParseGridRowFailures(t *testing.T) {
+	tests := []string{
+		"",
+		"C G Am F",
+		"| C . . .",
+		"| C . xyz . |",
+		"| C . . . |0",
+	}
+
+	for _, test := range tests {
+		if row, ok := ParseGridRow(test); ok {
+			t.Errorf("ParseGridRow(%q) = %v, expected a failure", test, row)
+		}
+	}
+}
+
+func TestGridLines(t *testing.T) {
+	rows := []string{
+		"Intro |: C . . . |1 G . . . :|",
+		"| Am7 . . . |2 % |. Fine",
+		"| C . | G . . . | F",
+	}
+
+	g := &Grid{}
+	for _, text := range rows {
+		row, ok := ParseGridRow(text)
+		if !ok {
+			t.Fatalf("ParseGridRow(%q) failed", text)
+		}
+		g.Rows = append(g.Rows, row)
+	}
+
+	expected := []string{
+		"Intro |: C   . . . |1 G . . . :|",
+		"      |  Am7 . . . |2 %       |. Fine",
+		"      |  C   .     |  G . . . | F",
+	}
+
+	actual := g.Lines()
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Lines() = %q, expected %q", actual, expected)
+	}
+}
+
+func TestGridLinesRoundTrip(t *testing.T) {
+	rows := []string{
+		"Intro |: C / / / | G . . . :|",
+		"      |  Am7 / . / |2 % |. Fine",
+	}
+
+	g := &Grid{}
+	for _, text := range rows {
+		row, ok := ParseGridRow(text)
+		if !ok {
+			t.Fatalf("ParseGridRow(%q) failed", text)
+		}
+		g.Rows = append(g.Rows, row)
+	}
+
+	expected := []string{
+		"Intro |: C   / / / |  G . . . :|",
+		"      |  Am7 / . / |2 %       |. Fine",
+	}
+	if actual := g.Lines(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Lines() = %q, expected %q", actual, expected)
+	}
+
+	// the lines read back as the same grid, and so do the lines of a transposed grid.
+	transposed, err := TransposeSection(&Section{Nodes: []SectionNode{g}}, MustParseKey("C"), MustParseKey("D"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, grid := range []*Grid{g, transposed.Nodes[0].(*Grid)} {
+		read := &Grid{}
+		for _, text := range grid.Lines() {
+			row, ok := ParseGridRow(text)
+			if !ok {
+				t.Fatalf("ParseGridRow(%q) failed", text)
+			}
+			read.Rows = append(read.Rows, row)
+		}
+
+		if fmt.Sprint(read.Lines()) != fmt.Sprint(grid.Lines()) {
+			t.Errorf("expected %q to read back, but got %q", grid.Lines(), read.Lines())
+		}
+	}
+	if line := transposed.Nodes[0].(*Grid).Lines()[0]; line != "Intro |: D   / / / |  A . . . :|" {
+		t.Errorf("expected the transposed grid to keep its slashes, but got %q", line)
+	}
+}
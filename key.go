@@ -0,0 +1,293 @@
+package songtools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode is the scale a key is built on. Ionian is the major scale and Aeolian is the
+// natural minor scale.
+type Mode int
+
+// The modes, in the order they appear on the degrees of the major scale.
+const (
+	Ionian Mode = iota
+	Dorian
+	Phrygian
+	Lydian
+	Mixolydian
+	Aeolian
+	Locrian
+)
+
+var modeNames = []string{"major", "dorian", "phrygian", "lydian", "mixolydian", "minor", "locrian"}
+
+func (m Mode) String() string {
+	if m < Ionian || m > Locrian {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+
+	return modeNames[m]
+}
+
+// intervals returns the semitones of each degree of the mode above its tonic.
+func (m Mode) intervals() []int {
+	intervals := []int{}
+	for i := range majorScale {
+		intervals = append(intervals, (majorScale[(i+int(m))%len(majorScale)]-majorScale[m]+noteCount)%noteCount)
+	}
+
+	return intervals
+}
+
+var modeSuffixes = map[string]Mode{
+	"":           Ionian,
+	"maj":        Ionian,
+	"major":      Ionian,
+	"ionian":     Ionian,
+	"m":          Aeolian,
+	"min":        Aeolian,
+	"minor":      Aeolian,
+	"aeolian":    Aeolian,
+	"dor":        Dorian,
+	"dorian":     Dorian,
+	"phr":        Phrygian,
+	"phrygian":   Phrygian,
+	"lyd":        Lydian,
+	"lydian":     Lydian,
+	"mix":        Mixolydian,
+	"mixolydian": Mixolydian,
+	"loc":        Locrian,
+	"locrian":    Locrian,
+}
+
+// Key is the tonic and mode of a song, such as G major, F# minor or D dorian. The zero
+// Key means the key is not known.
+type Key struct {
+	Tonic Spelling
+	Mode  Mode
+}
+
+// ParseKey parses the text as a key. The tonic is a note with at most one sharp or flat, and
+// is followed by the mode, such as Em, F# minor, Bbmaj or D dorian. A tonic on its own is major.
+func ParseKey(text string) (Key, error) {
+	text = strings.TrimSpace(text)
+	tonic, n, ok := parseSpellingPrefix(text)
+	if !ok {
+		return Key{}, fmt.Errorf("not a key: %q", text)
+	}
+	if tonic.Accidental < -1 || tonic.Accidental > 1 {
+		return Key{}, fmt.Errorf("not a key: %q has a double sharp or flat", text)
+	}
+
+	suffix := strings.TrimSpace(text[n:])
+	if suffix == "M" {
+		return Key{Tonic: tonic, Mode: Ionian}, nil
+	}
+
+	mode, ok := modeSuffixes[strings.ToLower(suffix)]
+	if !ok {
+		return Key{}, fmt.Errorf("not a key: %q has an unknown mode %q", text, suffix)
+	}
+
+	return Key{Tonic: tonic, Mode: mode}, nil
+}
+
+// MustParseKey is like ParseKey but panics if the text is not a key.
+func MustParseKey(text string) Key {
+	k, err := ParseKey(text)
+	if err != nil {
+		panic(err)
+	}
+
+	return k
+}
+
+// IsZero indicates whether the key is unknown.
+func (k Key) IsZero() bool {
+	return k.Tonic.Letter == 0
+}
+
+// IsMinor indicates whether the key has a minor third above its tonic.
+func (k Key) IsMinor() bool {
+	switch k.Mode {
+	case Dorian, Phrygian, Aeolian, Locrian:
+		return true
+	default:
+		return false
+	}
+}
+
+func (k Key) String() string {
+	switch {
+	case k.IsZero():
+		return ""
+	case k.Mode == Ionian:
+		return k.Tonic.String()
+	case k.Mode == Aeolian:
+		return k.Tonic.String() + "m"
+	default:
+		return k.Tonic.String() + " " + k.Mode.String()
+	}
+}
+
+// Scale returns the spelled notes of the key's scale, starting from the tonic. Minor keys
+// use the natural minor scale. The zero key has no scale.
+func (k Key) Scale() []Spelling {
+	if k.IsZero() {
+		return nil
+	}
+
+	scale := []Spelling{}
+	for i, semitones := range k.Mode.intervals() {
+		scale = append(scale, k.Tonic.spellAbove(i, semitones))
+	}
+
+	return scale
+}
+
+// ScaleDegree returns the degree of the key's scale that the note is spelled on, starting from 1,
+// and how many semitones it has been raised or lowered from the note in the scale.
+func (k Key) ScaleDegree(s Spelling) (int, int, bool) {
+	scale := k.Scale()
+	if scale == nil {
+		return 0, 0, false
+	}
+
+	steps := (s.letterIndex() - scale[0].letterIndex() + len(letters)) % len(letters)
+	return steps + 1, s.Accidental - scale[steps].Accidental, true
+}
+
+// Relative returns the key that shares the same key signature. The relative of a major key is
+// its relative minor, and the relative of a minor key or any other mode is its relative major.
+func (k Key) Relative() Key {
+	if k.IsZero() {
+		return k
+	}
+
+	if k.Mode == Ionian {
+		return Key{Tonic: k.Tonic.spellAbove(5, majorScale[5]), Mode: Aeolian}
+	}
+
+	return k.relativeMajor()
+}
+
+// Parallel returns the key with the same tonic on the opposite scale. Keys with a minor third
+// become major, and the rest become minor.
+func (k Key) Parallel() Key {
+	if k.IsZero() {
+		return k
+	}
+
+	if k.IsMinor() {
+		return Key{Tonic: k.Tonic, Mode: Ionian}
+	}
+
+	return Key{Tonic: k.Tonic, Mode: Aeolian}
+}
+
+func (k Key) relativeMajor() Key {
+	steps := (len(majorScale) - int(k.Mode)) % len(majorScale)
+	semitones := (noteCount - majorScale[k.Mode]) % noteCount
+	return Key{Tonic: k.Tonic.spellAbove(steps, semitones), Mode: Ionian}
+}
+
+var letterFifths = map[byte]int{'F': -1, 'C': 0, 'G': 1, 'D': 2, 'A': 3, 'E': 4, 'B': 5}
+
+// Signature returns the number of sharps in the key signature when positive, and the number of
+// flats when negative. Keys such as G# major need more than seven, in which case some are double.
+func (k Key) Signature() int {
+	if k.IsZero() {
+		return 0
+	}
+
+	major := k.relativeMajor().Tonic
+	return letterFifths[major.Letter] + len(letters)*major.Accidental
+}
+
+// SpellNote spells the note as it is written in the key. Notes in the scale use the scale's
+// spelling. The rest are written as a scale note raised or lowered by a semitone, using as few
+// accidentals as possible and favouring sharps in sharp keys and flats in flat keys.
+func (k Key) SpellNote(n Note) Spelling {
+	scale := k.Scale()
+	if scale == nil {
+		return spellingFromNames(n, sharpNoteNames)
+	}
+
+	for _, s := range scale {
+		if s.Note() == n {
+			return s
+		}
+	}
+
+	// prefer whichever needs fewer accidentals, and the key's own accidentals when it is a tie.
+	direction := 1
+	if k.Signature() < 0 {
+		direction = -1
+	}
+	first, firstOK := alterScaleNote(scale, n, direction)
+	second, secondOK := alterScaleNote(scale, n, -direction)
+	switch {
+	case firstOK && (!secondOK || abs(first.Accidental) <= abs(second.Accidental)):
+		return first
+	case secondOK:
+		return second
+	default:
+		return spellingFromNames(n, sharpNoteNames)
+	}
+}
+
+// alterScaleNote spells the note as the scale note a semitone away raised or lowered by
+// the accidental, as long as that doesn't need a double sharp or flat.
+func alterScaleNote(scale []Spelling, n Note, accidental int) (Spelling, bool) {
+	for _, s := range scale {
+		altered := Spelling{Letter: s.Letter, Accidental: s.Accidental + accidental}
+		if s.Note() == n.Interval(-accidental) && abs(altered.Accidental) <= 1 {
+			return altered, true
+		}
+	}
+
+	return Spelling{}, false
+}
+
+// NoteNames returns the names of the notes as they are written in the key.
+func (k Key) NoteNames() *NoteNames {
+	names := &NoteNames{}
+	for i := range names {
+		names[i] = k.SpellNote(Note(i)).String()
+	}
+
+	return names
+}
+
+// Interval returns the key with the same mode whose tonic is at the specified interval. The
+// tonic is spelled to give the key signature with the fewest sharps or flats.
+func (k Key) Interval(interval int) Key {
+	if k.IsZero() {
+		return k
+	}
+
+	tonic := k.Tonic.Note().Interval(interval)
+	var best Key
+	for i := 0; i < len(letters); i++ {
+		s := spellWithLetter(letters[i], tonic)
+		if s.Accidental < -1 || s.Accidental > 1 {
+			continue
+		}
+
+		candidate := Key{Tonic: s, Mode: k.Mode}
+		if best.IsZero() || abs(candidate.Signature()) < abs(best.Signature()) {
+			best = candidate
+		}
+	}
+
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
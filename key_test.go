@@ -0,0 +1,101 @@
+package songtools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		ok       bool
+	}{
+		{"G", "G", true},
+		{"Em", "Em", true},
+		{"F# minor", "F#m", true},
+		{"Bbmaj", "Bb", true},
+		{"D dorian", "D dorian", true},
+		{" A mixolydian ", "A mixolydian", true},
+		{"CM", "C", true},
+		{"H", "", false},
+		{"C##", "", false},
+		{"C blues", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		k, err := ParseKey(test.text)
+		if (err == nil) != test.ok || k.String() != test.expected {
+			t.Errorf("ParseKey(%q) = %q, %v, expected %q with an error: %v", test.text, k, err, test.expected, !test.ok)
+		}
+	}
+}
+
+func TestKeySignature(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected int
+	}{
+		{"C", 0},
+		{"Am", 0},
+		{"G", 1},
+		{"F", -1},
+		{"Ebm", -6},
+		{"C#", 7},
+		{"D dorian", 0},
+		{"E phrygian", 0},
+		{"G#", 8},
+	}
+
+	for _, test := range tests {
+		if actual := MustParseKey(test.key).Signature(); actual != test.expected {
+			t.Errorf("%v.Signature() = %v, expected %v", test.key, actual, test.expected)
+		}
+	}
+}
+
+func TestKeyNoteNames(t *testing.T) {
+	tests := []struct {
+		key string
+		// expected are the names of the notes from A.
+		expected string
+	}{
+		{"C", "A A# B C C# D D# E F F# G G#"},
+		{"F", "A Bb B C Db D Eb E F Gb G Ab"},
+		{"E", "A A# B C C# D D# E F F# G G#"},
+		{"Eb", "A Bb B C Db D Eb E F Gb G Ab"},
+		{"F#", "A A# B C C# D D# E E# F# G G#"},
+	}
+
+	for _, test := range tests {
+		names := MustParseKey(test.key).NoteNames()
+		if actual := strings.Join(names[:], " "); actual != test.expected {
+			t.Errorf("%v.NoteNames() = %q, expected %q", test.key, actual, test.expected)
+		}
+	}
+}
+
+func TestKeyInterval(t *testing.T) {
+	tests := []struct {
+		key      string
+		interval int
+		expected string
+	}{
+		{"C", 2, "D"},
+		{"C", 1, "Db"},
+		{"C", 6, "F#"},
+		{"G", 1, "Ab"},
+		{"Am", 1, "Bbm"},
+		{"Am", 4, "C#m"},
+		{"E", 1, "F"},
+		{"D dorian", 1, "Eb dorian"},
+		{"F", -1, "E"},
+	}
+
+	for _, test := range tests {
+		if actual := MustParseKey(test.key).Interval(test.interval); actual.String() != test.expected {
+			t.Errorf("%v.Interval(%v) = %q, expected %q", test.key, test.interval, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package songtools
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LyricsOnly copies the song with just its words, for singers and for projection. The chords
+// are left out, along with the lines, tabs and grids that only held chords, the key, the key
+// changes and the chord definitions. Syllables that were split apart to fit the chords above
+// them are joined back together, and the spaces that lined the words up with the chords are
+// collapsed. Sections left without any words are left out.
+func LyricsOnly(s *Song) *Song {
+	lyrics := &Song{
+		Title:     s.Title,
+		Subtitles: s.Subtitles,
+		Authors:   s.Authors,
+	}
+
+	for _, n := range s.Nodes {
+		switch typedN := n.(type) {
+		case *Comment, *Directive:
+			lyrics.Nodes = append(lyrics.Nodes, n)
+		case *Section:
+			if section, ok := lyricsSection(typedN); ok {
+				lyrics.Nodes = append(lyrics.Nodes, section)
+			}
+		}
+	}
+
+	return lyrics
+}
+
+// lyricsSection copies the words of the section, or indicates it doesn't have any.
+func lyricsSection(s *Section) (*Section, bool) {
+	section := &Section{
+		Kind:      s.Kind,
+		Label:     s.Label,
+		Delimited: s.Delimited,
+		Span:      s.Span,
+	}
+
+	words := false
+	for _, n := range s.Nodes {
+		switch typedN := n.(type) {
+		case *Comment, *Directive:
+			section.Nodes = append(section.Nodes, n)
+		case *Line:
+			if text := joinSyllables(typedN); text != "" {
+				section.Nodes = append(section.Nodes, &Line{
+					Text: text,
+					Span: typedN.Span,
+				})
+				words = true
+			}
+		}
+	}
+
+	return section, words
+}
+
+var (
+	// syllables matches the syllables of a word that were split apart with hyphens.
+	syllables = regexp.MustCompile(`\pL+(?:-+\pL+)+`)
+	// spacedSyllables matches the syllables of a word that were split apart with hyphens, which
+	// may have spaces around them.
+	spacedSyllables = regexp.MustCompile(`\pL+(?: *-+ *\pL+)+`)
+	// syllableBreak matches the hyphen, and any spaces around it, between two syllables.
+	syllableBreak = regexp.MustCompile(` *-+ *`)
+	// spacedBreak matches a hyphen with a space on either side of it.
+	spacedBreak = regexp.MustCompile(` +-+ *| *-+ +`)
+)
+
+// joinSyllables gets the text of the line with the words that were split into syllables for
+// its chords joined back together. A word is split with hyphens between its syllables, such as
+// Hal-[C]le-lu-jah. When the chords are above the text, the hyphens may also have spaces around
+// them, such as A - ma - zing, since the syllables were spaced out to line up with the chords.
+// Otherwise a hyphen with spaces around it is a dash, as in Lord - [G]you are my strength.
+// Only words with a chord after one of their breaks are joined, so hyphenated words are kept,
+// and runs of spaces become a single space.
+func joinSyllables(l *Line) string {
+	pattern := syllables
+	if l.ChordsAbove {
+		pattern = spacedSyllables
+	}
+
+	text := ""
+	last := 0
+	for _, m := range pattern.FindAllStringIndex(l.Text, -1) {
+		for _, w := range spacedWords(l, m[0], m[1]) {
+			word := l.Text[w[0]:w[1]]
+			if splitForChord(l, w[0], w[1]) {
+				word = syllableBreak.ReplaceAllString(word, "")
+			}
+			text += l.Text[last:w[0]] + word
+			last = w[1]
+		}
+	}
+	text += l.Text[last:]
+
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// spacedWords splits the syllables between start and end into words, at a spaced hyphen that
+// is a dash between two words rather than a break in one. A word spaced out into three or more
+// syllables, such as A - ma - zing, is kept whole, since the syllables in its middle aren't
+// words. With only two, the hyphen is a break when the syllable before it is narrower than the
+// chord over it, which is what spaced it out, as in Je - sus under Em7 and G. Otherwise it is a
+// dash, as in Lord - you.
+func spacedWords(l *Line, start, end int) [][2]int {
+	breaks := spacedBreak.FindAllStringIndex(l.Text[start:end], -1)
+	if len(breaks) != 1 {
+		return [][2]int{{start, end}}
+	}
+
+	dashStart, dashEnd := start+breaks[0][0], start+breaks[0][1]
+	if paddedForChord(l, dashStart) {
+		return [][2]int{{start, end}}
+	}
+
+	return [][2]int{{start, dashStart}, {dashEnd, end}}
+}
+
+// paddedForChord indicates the syllable that ends at the position is narrower than a chord over
+// it, along with the space after the chord.
+func paddedForChord(l *Line, end int) bool {
+	start := end
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(l.Text[:start])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		start -= size
+	}
+
+	width := utf8.RuneCountInString(l.Text[start:end])
+	for i, pos := range l.ChordPositions {
+		if pos >= start && pos < end && width < utf8.RuneCountInString(l.Chords[i].Name)+1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitForChord indicates the line has a chord at a break in the word between start and end.
+func splitForChord(l *Line, start, end int) bool {
+	isBreak := func(b byte) bool {
+		return b == '-' || b == ' '
+	}
+
+	for _, pos := range l.ChordPositions {
+		if pos > start && pos < end && (isBreak(l.Text[pos-1]) || isBreak(l.Text[pos])) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package songtools
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// lyricLine builds a line from the chords written above its text, each starting at the column
+// of the character it is over.
+func lyricLine(chords, text string) *Line {
+	l := &Line{Text: text}
+	column := 0
+	for _, f := range strings.Fields(chords) {
+		column += strings.Index(chords, f)
+		chords = chords[strings.Index(chords, f)+len(f):]
+
+		pos := len(text)
+		if column < utf8.RuneCountInString(text) {
+			pos = len(string([]rune(text)[:column]))
+		}
+		c, ok := ParseChord(f)
+		if !ok {
+			c = UnknownChord(f)
+		}
+		l.Chords = append(l.Chords, c)
+		l.ChordPositions = append(l.ChordPositions, pos)
+		column += len(f)
+	}
+
+	return l
+}
+
+func TestJoinSyllables(t *testing.T) {
+	tests := []struct {
+		chords      string
+		text        string
+		chordsAbove bool
+		expected    string
+	}{
+		{"G   C  D  Em", "Hal-le-lu-jah", false, "Hallelujah"},
+		{"    C", "Hal-le-lu-jah", false, "Hallelujah"},
+		{"    C", "Hal-le-lu-jah", true, "Hallelujah"},
+		{"", "well-known   song", false, "well-known song"},
+		{"G", "a well-known song", false, "a well-known song"},
+		{"  G         C", "  padded    words ", false, "padded words"},
+		{"       G", "Lord - you are my strength", false, "Lord - you are my strength"},
+		{"      G", "Lord -you are my strength", false, "Lord -you are my strength"},
+		{"G   C    D", "A - ma - zing grace", false, "A - ma - zing grace"},
+		// the chords are spaced out for the syllables, so they are joined.
+		{"G   C    D", "A - ma - zing grace", true, "Amazing grace"},
+		{"Em7  G", "Je - sus", true, "Jesus"},
+		{"Am  G", "É - té", true, "Été"},
+		// a dash between two words, even with a chord after it.
+		{"       G", "Lord - you are my strength", true, "Lord - you are my strength"},
+		{"D      G", "Lord - you are my strength", true, "Lord - you are my strength"},
+		{"G            C", "I was lost - now I'm found", true, "I was lost - now I'm found"},
+		{"G             C", "I was lost - now I'm found", true, "I was lost - now I'm found"},
+	}
+
+	for _, test := range tests {
+		l := lyricLine(test.chords, test.text)
+		l.ChordsAbove = test.chordsAbove
+		if actual := joinSyllables(l); actual != test.expected {
+			t.Errorf("joinSyllables(%q over %q, chords above: %v) = %q, expected %q", test.chords, test.text, test.chordsAbove, actual, test.expected)
+		}
+	}
+}
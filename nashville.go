@@ -0,0 +1,130 @@
+package songtools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NashvilleNumber returns the Nashville number of the chord in the key, such as 1, 4, 5/7, b7
+// or 2m. Numbers count up the major scale of the key's tonic, so the tonic chord of a minor key
+// is 1m. The suffix of the chord is kept as it is written.
+func NashvilleNumber(c *Chord, key Key) (string, bool) {
+	if key.IsZero() || c.Unknown {
+		return "", false
+	}
+	tonic := key.Tonic
+
+	number := nashvilleDegree(c.RootSpelling(key), tonic) + c.Suffix
+	if c.Base != c.Root {
+		number += "/" + nashvilleDegree(c.BaseSpelling(key), tonic)
+	}
+
+	return number, true
+}
+
+func nashvilleDegree(s, tonic Spelling) string {
+	steps := (s.letterIndex() - tonic.letterIndex() + len(letters)) % len(letters)
+	diatonic := tonic.spellAbove(steps, majorScale[steps])
+	accidental := s.Accidental - diatonic.Accidental
+	if accidental < -1 || accidental > 1 {
+		// spelled too strangely to count from the letter, so count the semitones instead.
+		return semitoneDegrees[int(s.Note().Interval(-int(tonic.Note())))]
+	}
+
+	return accidentalString(accidental) + strconv.Itoa(steps+1)
+}
+
+var semitoneDegrees = []string{"1", "b2", "2", "b3", "3", "4", "b5", "5", "b6", "6", "b7", "7"}
+
+// ParseNashvilleChord parses a Nashville number, such as 1, 4, 5/7, b7 or 2m, and
+// returns the chord it stands for in the key.
+func ParseNashvilleChord(text string, key Key) (*Chord, bool) {
+	if key.IsZero() {
+		return nil, false
+	}
+	tonic := key.Tonic
+
+	root, n, ok := parseNashvilleDegree(text, tonic)
+	if !ok {
+		return nil, false
+	}
+
+	suffix := text[n:]
+	bass := ""
+	if idx := strings.LastIndex(suffix, "/"); idx != -1 {
+		if b, bn, ok := parseNashvilleDegree(suffix[idx+1:], tonic); ok && bn == len(suffix)-idx-1 {
+			bass = "/" + b.String()
+			suffix = suffix[:idx]
+		}
+	}
+
+	return ParseChord(root.String() + suffix + bass)
+}
+
+func parseNashvilleDegree(text string, tonic Spelling) (Spelling, int, bool) {
+	accidental := 0
+	n := 0
+	for n < len(text) {
+		if text[n] == 'b' {
+			accidental--
+		} else if text[n] == '#' {
+			accidental++
+		} else {
+			break
+		}
+		n++
+	}
+
+	if n >= len(text) || text[n] < '1' || text[n] > '7' {
+		return Spelling{}, 0, false
+	}
+
+	steps := int(text[n] - '1')
+	return tonic.spellAbove(steps, majorScale[steps]+accidental), n + 1, true
+}
+
+// ToNashville converts the chords of the song into Nashville numbers relative to the
+// song's key, or to the key of the latest key change. The chords keep their notes, only
+// their names change.
+func ToNashville(s *Song) (*Song, error) {
+	if s.Key.IsZero() {
+		return nil, fmt.Errorf("unable to use Nashville numbers without a key")
+	}
+
+	m := &chordMapper{key: s.Key, chord: func(c *Chord, key Key) (*Chord, error) {
+		number, ok := NashvilleNumber(c, key)
+		if !ok {
+			return c, nil
+		}
+
+		numbered := *c
+		numbered.Name = number
+		return &numbered, nil
+	}}
+
+	return m.song(s)
+}
+
+// FromNashville converts the chords of the song, named with Nashville numbers, into
+// chords in the song's key, or in the key of the latest key change.
+func FromNashville(s *Song) (*Song, error) {
+	if s.Key.IsZero() {
+		return nil, fmt.Errorf("unable to use Nashville numbers without a key")
+	}
+
+	m := &chordMapper{key: s.Key, chord: func(c *Chord, key Key) (*Chord, error) {
+		if c.Unknown {
+			return c, nil
+		}
+
+		chord, ok := ParseNashvilleChord(c.Name, key)
+		if !ok {
+			return nil, fmt.Errorf("the chord %q is not a Nashville number", c.Name)
+		}
+
+		return chord, nil
+	}}
+
+	return m.song(s)
+}
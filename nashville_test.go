@@ -0,0 +1,147 @@
+package songtools
+
+import "testing"
+
+func TestNashvilleNumber(t *testing.T) {
+	tests := []struct {
+		chord    string
+		key      string
+		expected string
+	}{
+		{"C", "C", "1"},
+		{"F", "C", "4"},
+		{"G7", "C", "57"},
+		{"Dm", "C", "2m"},
+		{"G/B", "C", "5/7"},
+		{"C/E", "C", "1/3"},
+		{"Bb", "C", "b7"},
+		{"Ab", "C", "b6"},
+		{"F#m7b5", "C", "#4m7b5"},
+		{"Eb", "Bb", "4"},
+		{"B", "F#", "4"},
+		{"Am", "Am", "1m"},
+		{"Dm", "Am", "4m"},
+		{"C", "Am", "b3"},
+		{"G", "Am", "b7"},
+		{"E/G#", "Am", "5/7"},
+		{"Bb", "Am", "b2"},
+		// spelled too strangely for the letter, so counted by semitones.
+		{"Cbb", "C", "b7"},
+	}
+
+	for _, test := range tests {
+		c, _ := ParseChord(test.chord)
+		if actual, ok := NashvilleNumber(c, MustParseKey(test.key)); !ok || actual != test.expected {
+			t.Errorf("NashvilleNumber(%q, %v) = %q, %v, expected %q", test.chord, test.key, actual, ok, test.expected)
+		}
+	}
+
+	c, _ := ParseChord("C")
+	if _, ok := NashvilleNumber(c, Key{}); ok {
+		t.Errorf("expected no number without a key")
+	}
+	if _, ok := NashvilleNumber(UnknownChord("N.C."), MustParseKey("C")); ok {
+		t.Errorf("expected no number for an unknown chord")
+	}
+}
+
+func TestParseNashvilleChord(t *testing.T) {
+	tests := []struct {
+		text     string
+		key      string
+		expected string
+		ok       bool
+	}{
+		{"1", "C", "C", true},
+		{"4", "G", "C", true},
+		{"57", "D", "A7", true},
+		{"2m", "Eb", "Fm", true},
+		{"5/7", "C", "G/B", true},
+		{"4/1", "F", "Bb/F", true},
+		{"b7", "C", "Bb", true},
+		{"#4m7b5", "C", "F#m7b5", true},
+		{"b3", "E", "G", true},
+		{"1m", "Am", "Am", true},
+		{"b6", "Am", "F", true},
+		{"5/7", "Am", "E/G#", true},
+		{"bb7", "C", "Bbb", true},
+		{"8", "C", "", false},
+		{"m", "C", "", false},
+		{"C", "C", "", false},
+		{"", "C", "", false},
+	}
+
+	for _, test := range tests {
+		c, ok := ParseNashvilleChord(test.text, MustParseKey(test.key))
+		if ok != test.ok || (ok && c.Name != test.expected) {
+			t.Errorf("ParseNashvilleChord(%q, %v) = %v, %v, expected %q, %v", test.text, test.key, c, ok, test.expected, test.ok)
+		}
+	}
+
+	if _, ok := ParseNashvilleChord("1", Key{}); ok {
+		t.Errorf("expected no chord without a key")
+	}
+}
+
+func TestNashvilleSong(t *testing.T) {
+	tests := []struct {
+		key     string
+		chords  string
+		numbers string
+	}{
+		{"G", "G C/G D7 Em", "1 4/1 57 6m"},
+		{"Bb", "Bb Eb/G F Ab", "1 4/6 5 b7"},
+		{"Em", "Em Am B7 C D", "1m 4m 57 b6 b7"},
+		{"C", "C N.C. G", "1 N.C. 5"},
+	}
+
+	for _, test := range tests {
+		s := chordSong(test.key, 0, test.chords)
+		numbered, err := ToNashville(s)
+		if err != nil {
+			t.Errorf("ToNashville(%q): unexpected error: %v", test.chords, err)
+			continue
+		}
+		if actual := chordNames(numbered.Nodes[0].(*Section).Nodes[0].(*Line)); actual != test.numbers {
+			t.Errorf("ToNashville(%q in %v) = %q, expected %q", test.chords, test.key, actual, test.numbers)
+		}
+
+		lettered, err := FromNashville(numbered)
+		if err != nil {
+			t.Errorf("FromNashville(%q): unexpected error: %v", test.numbers, err)
+			continue
+		}
+		if actual := chordNames(lettered.Nodes[0].(*Section).Nodes[0].(*Line)); actual != test.chords {
+			t.Errorf("FromNashville(%q in %v) = %q, expected %q", test.numbers, test.key, actual, test.chords)
+		}
+	}
+
+	if _, err := ToNashville(&Song{}); err == nil {
+		t.Errorf("expected an error converting a song without a key")
+	}
+	if _, err := FromNashville(chordSong("C", 0, "C")); err == nil {
+		t.Errorf("expected an error converting a chord that isn't a number")
+	}
+}
+
+func TestNashvilleSongKeyChanges(t *testing.T) {
+	s := &Song{
+		Key: MustParseKey("C"),
+		Nodes: []SongNode{
+			&Section{Nodes: []SectionNode{chordLine("C G")}},
+			&KeyChange{Key: MustParseKey("D")},
+			&Section{Nodes: []SectionNode{chordLine("D A")}},
+		},
+	}
+
+	numbered, err := ToNashville(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, expected := range map[int]string{0: "1 5", 2: "1 5"} {
+		if actual := chordNames(numbered.Nodes[i].(*Section).Nodes[0].(*Line)); actual != expected {
+			t.Errorf("section %d = %q, expected %q", i, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package songtools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quality is the basic character of a chord, determined by its third and fifth.
+type Quality int
+
+// The chord qualities.
+const (
+	Major Quality = iota
+	Minor
+	Diminished
+	HalfDiminished
+	Augmented
+	Suspended2
+	Suspended4
+	Power
+)
+
+func (q Quality) String() string {
+	switch q {
+	case Major:
+		return "major"
+	case Minor:
+		return "minor"
+	case Diminished:
+		return "diminished"
+	case HalfDiminished:
+		return "half-diminished"
+	case Augmented:
+		return "augmented"
+	case Suspended2:
+		return "suspended 2nd"
+	case Suspended4:
+		return "suspended 4th"
+	case Power:
+		return "power"
+	default:
+		return "unknown"
+	}
+}
+
+// Alteration is a chord tone that has been raised or lowered, such as the b9 in E7b9.
+type Alteration struct {
+	Degree     int
+	Accidental int
+}
+
+func (a Alteration) String() string {
+	return accidentalString(a.Accidental) + strconv.Itoa(a.Degree)
+}
+
+func accidentalString(accidental int) string {
+	if accidental < 0 {
+		return strings.Repeat("b", -accidental)
+	}
+
+	return strings.Repeat("#", accidental)
+}
+
+var (
+	minorMarkers        = []string{"min", "mi", "m", "-"}
+	majorSeventhMarkers = []string{"maj", "Maj", "MAJ", "M", "Δ", "^"}
+	// shortMajorSeventhMarkers are only major seventh markers before a degree, as in Cma7,
+	// since the m of Cmadd9 is a minor marker.
+	shortMajorSeventhMarkers = []string{"ma", "Ma"}
+	diminishedMarkers        = []string{"dim", "°", "o"}
+	halfDiminishedMarkers    = []string{"ø", "Ø"}
+	augmentedMarkers         = []string{"aug", "+"}
+	flatMarkers              = []string{"b", "♭", "-"}
+	sharpMarkers             = []string{"#", "♯", "+"}
+)
+
+// suffixParser breaks the suffix of a chord, everything after the root and before the bass,
+// into its quality, extension, alterations, added and omitted tones. The grammar is
+//
+//	suffix    = [quality] [extension] {modifier}
+//	quality   = "m" | "min" | "mi" | "-" | "dim" | "°" | "o" | "ø" | "aug" | "+"
+//	extension = ["maj" | "ma" | "M" | "Δ"] ("5" | "6" | "6/9" | "69" | "7" | "9" | "11" | "13" | "2" | "4")
+//	modifier  = "sus" ["2" | "4"] | "add" degree | ("no" | "omit") degree | ("b" | "#") degree
+//	          | "alt" | "maj7" | "(" | ")" | ","
+//
+// A bare 2 is an added 2nd, as in G2, and a bare 4 is a suspended 4th, as in D4.
+type suffixParser struct {
+	text  string
+	pos   int
+	chord *Chord
+}
+
+func parseSuffix(text string, c *Chord) bool {
+	p := &suffixParser{text: text, chord: c}
+	p.parseQuality()
+	if !p.parseExtension() {
+		return false
+	}
+
+	for p.pos < len(p.text) {
+		if !p.parseModifier() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *suffixParser) accept(markers ...string) bool {
+	for _, m := range markers {
+		if strings.HasPrefix(p.text[p.pos:], m) {
+			p.pos += len(m)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *suffixParser) peek(markers ...string) bool {
+	for _, m := range markers {
+		if strings.HasPrefix(p.text[p.pos:], m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// peekMajorSeventh returns the length of the major seventh marker at the position, or 0 when
+// there isn't one.
+func (p *suffixParser) peekMajorSeventh() int {
+	rest := p.text[p.pos:]
+	for _, m := range shortMajorSeventhMarkers {
+		if strings.HasPrefix(rest, m) && len(rest) > len(m) && rest[len(m)] >= '0' && rest[len(m)] <= '9' {
+			return len(m)
+		}
+	}
+	for _, m := range majorSeventhMarkers {
+		if strings.HasPrefix(rest, m) {
+			return len(m)
+		}
+	}
+
+	return 0
+}
+
+func (p *suffixParser) acceptMajorSeventh() bool {
+	n := p.peekMajorSeventh()
+	p.pos += n
+	return n > 0
+}
+
+func (p *suffixParser) acceptDegree() (int, bool) {
+	start := p.pos
+	for p.pos < len(p.text) && p.text[p.pos] >= '0' && p.text[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if start == p.pos {
+		return 0, false
+	}
+
+	degree, _ := strconv.Atoi(p.text[start:p.pos])
+	switch degree {
+	case 1, 2, 3, 4, 5, 6, 7, 9, 11, 13:
+		return degree, true
+	}
+
+	p.pos = start
+	return 0, false
+}
+
+func (p *suffixParser) parseQuality() {
+	switch {
+	case p.peekMajorSeventh() > 0:
+		// major sevenths are handled as part of the extension.
+	case p.accept(minorMarkers...):
+		p.chord.Quality = Minor
+	case !p.peek("omit") && p.accept(diminishedMarkers...):
+		p.chord.Quality = Diminished
+	case p.accept(halfDiminishedMarkers...):
+		p.chord.Quality = HalfDiminished
+		p.chord.Extension = 7
+	case p.accept(augmentedMarkers...):
+		p.chord.Quality = Augmented
+	}
+}
+
+func (p *suffixParser) parseExtension() bool {
+	if p.acceptMajorSeventh() {
+		marker := p.text[:p.pos]
+		degree, ok := p.acceptDegree()
+		if !ok {
+			if strings.HasSuffix(marker, "Δ") || strings.HasSuffix(marker, "^") {
+				p.chord.MajorSeventh = true
+				p.chord.Extension = 7
+			}
+			return true
+		}
+
+		switch degree {
+		case 7, 9, 11, 13:
+			p.chord.MajorSeventh = true
+			p.chord.Extension = degree
+			return true
+		default:
+			return false
+		}
+	}
+
+	start := p.pos
+	degree, ok := p.acceptDegree()
+	if !ok {
+		return true
+	}
+
+	switch degree {
+	case 2:
+		p.chord.Added = append(p.chord.Added, 2)
+	case 4:
+		if p.chord.Quality != Major {
+			return false
+		}
+		p.chord.Quality = Suspended4
+	case 5:
+		if p.chord.Quality != Major {
+			return false
+		}
+		p.chord.Quality = Power
+	case 6:
+		p.chord.Added = append(p.chord.Added, 6)
+		if p.accept("/9") || p.accept("9") {
+			p.chord.Added = append(p.chord.Added, 9)
+		}
+	case 7, 9, 11, 13:
+		p.chord.Extension = degree
+	default:
+		p.pos = start
+	}
+
+	return true
+}
+
+func (p *suffixParser) parseModifier() bool {
+	switch {
+	case p.accept("(", ")", ","):
+		return true
+	case p.accept("sus"):
+		degree, ok := p.acceptDegree()
+		switch {
+		case !ok || degree == 4:
+			p.chord.Quality = Suspended4
+		case degree == 2:
+			p.chord.Quality = Suspended2
+		case degree == 9:
+			p.chord.Quality = Suspended2
+			p.chord.Extension = 9
+		default:
+			return false
+		}
+		return true
+	case p.accept("add"):
+		degree, ok := p.acceptDegree()
+		if !ok {
+			return false
+		}
+		p.chord.Added = append(p.chord.Added, degree)
+		return true
+	case p.accept("no", "omit"):
+		degree, ok := p.acceptDegree()
+		if !ok {
+			return false
+		}
+		p.chord.Omitted = append(p.chord.Omitted, degree)
+		return true
+	case p.accept("alt"):
+		p.chord.Alterations = append(p.chord.Alterations,
+			Alteration{5, -1}, Alteration{5, 1}, Alteration{9, -1}, Alteration{9, 1})
+		return true
+	case p.acceptMajorSeventh():
+		// minor major sevenths, such as Cm(maj7)
+		degree, ok := p.acceptDegree()
+		if !ok {
+			degree = 7
+		}
+		if degree != 7 && degree != 9 && degree != 11 && degree != 13 {
+			return false
+		}
+		p.chord.MajorSeventh = true
+		if degree > p.chord.Extension {
+			p.chord.Extension = degree
+		}
+		return true
+	}
+
+	accidental := 0
+	if p.accept(flatMarkers...) {
+		accidental = -1
+	} else if p.accept(sharpMarkers...) {
+		accidental = 1
+	}
+
+	degree, ok := p.acceptDegree()
+	if !ok {
+		return false
+	}
+
+	if accidental == 0 {
+		// a bare degree, such as the 9 in C7(9), is an added tone.
+		p.chord.Added = append(p.chord.Added, degree)
+		return true
+	}
+
+	switch degree {
+	case 5, 9, 11, 13:
+		p.chord.Alterations = append(p.chord.Alterations, Alteration{degree, accidental})
+		return true
+	default:
+		return false
+	}
+}
+
+// SuffixString describes the structure of the chord's suffix, such as "minor 7 b5".
+func (c *Chord) SuffixString() string {
+	parts := []string{c.Quality.String()}
+	if c.Extension > 0 {
+		if c.MajorSeventh {
+			parts = append(parts, fmt.Sprintf("maj%d", c.Extension))
+		} else {
+			parts = append(parts, strconv.Itoa(c.Extension))
+		}
+	}
+	for _, a := range c.Alterations {
+		parts = append(parts, a.String())
+	}
+	for _, d := range c.Added {
+		parts = append(parts, fmt.Sprintf("add%d", d))
+	}
+	for _, d := range c.Omitted {
+		parts = append(parts, fmt.Sprintf("no%d", d))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -1,12 +1,22 @@
 package songtools
 
+// SongSet is an ordered collection of songs, such as a songbook.
+type SongSet struct {
+	Songs []*Song
+}
+
 // Song is a set of nodes.
 type Song struct {
 	Title     string
 	Subtitles []string
 	Authors   []string
 	Key       Key
-	Nodes     []SongNode
+	// Capo is the fret the capo is placed on, or 0 for no capo. With a capo, the chords are the
+	// shapes that are played and Key is still the key that sounds.
+	Capo int
+	// Definitions are the fingerings the song gives for its chords.
+	Definitions []*ChordDefinition
+	Nodes       []SongNode
 }
 
 // Chords gets all the chords present in the song.
@@ -23,6 +33,43 @@ func (s *Song) Chords() []*Chord {
 	return chords
 }
 
+// KeyedChord is a chord along with the key in effect where it appears in a song.
+type KeyedChord struct {
+	Chord *Chord
+	Key   Key
+}
+
+// KeyedChords gets all the chords present in the song along with the key in effect where each
+// appears. The key starts as the song's key and follows the song's key changes.
+func (s *Song) KeyedChords() []KeyedChord {
+	chords := []KeyedChord{}
+	key := s.Key
+
+	for _, n := range s.Nodes {
+		switch typedN := n.(type) {
+		case *KeyChange:
+			key = typedN.Key
+		case *Section:
+			for _, sn := range typedN.Nodes {
+				switch typedSN := sn.(type) {
+				case *KeyChange:
+					key = typedSN.Key
+				case *Line:
+					for _, c := range typedSN.Chords {
+						chords = append(chords, KeyedChord{Chord: c, Key: key})
+					}
+				case *Grid:
+					for _, c := range typedSN.Chords() {
+						chords = append(chords, KeyedChord{Chord: c, Key: key})
+					}
+				}
+			}
+		}
+	}
+
+	return chords
+}
+
 // SongNode represents a node that can appear in a song.
 type SongNode interface {
 	songNode()
@@ -30,6 +77,7 @@ type SongNode interface {
 
 func (c *Comment) songNode()   {}
 func (d *Directive) songNode() {}
+func (k *KeyChange) songNode() {}
 func (s *Section) songNode()   {}
 
 // Directive contains a name and value associated with either
@@ -37,12 +85,21 @@ func (s *Section) songNode()   {}
 type Directive struct {
 	Name  string
 	Value string
+	Span  *Span
 }
 
 // Comment contains text that represents a comment.
 type Comment struct {
 	Text   string
 	Hidden bool
+	Span   *Span
+}
+
+// KeyChange changes the key of the song from where it appears, such as when the last
+// chorus modulates up a whole step.
+type KeyChange struct {
+	Key  Key
+	Span *Span
 }
 
 // SectionKind is the type of section. Examples are Chorus, Verse, and Bridge.
@@ -50,8 +107,23 @@ type SectionKind string
 
 // Section contains nodes
 type Section struct {
-	Kind  SectionKind
-	Nodes []SectionNode
+	Kind SectionKind
+	// Label names this particular section, such as Verse 2. It is optional.
+	Label string
+	// Delimited indicates the section has an explicit start and end, like a ChordPro
+	// environment, rather than ending at the next blank lines.
+	Delimited bool
+	Nodes     []SectionNode
+	Span      *Span
+}
+
+// Heading is the label of the section when it has one, otherwise its kind.
+func (s *Section) Heading() string {
+	if s.Label != "" {
+		return s.Label
+	}
+
+	return string(s.Kind)
 }
 
 // Chords gets all the chords present in the section.
@@ -61,6 +133,8 @@ func (s *Section) Chords() []*Chord {
 		switch typedN := n.(type) {
 		case *Line:
 			chords = append(chords, typedN.Chords...)
+		case *Grid:
+			chords = append(chords, typedN.Chords()...)
 		}
 	}
 
@@ -74,11 +148,24 @@ type SectionNode interface {
 
 func (c *Comment) sectionNode()   {}
 func (d *Directive) sectionNode() {}
+func (k *KeyChange) sectionNode() {}
 func (l *Line) sectionNode()      {}
+func (t *Tab) sectionNode()       {}
+func (g *Grid) sectionNode()      {}
+
+// Tab is a block of tablature. Its lines are kept exactly as they were written.
+type Tab struct {
+	Lines []string
+	Span  *Span
+}
 
 // Line represents a lyric line and/or chords.
 type Line struct {
 	Text           string
 	Chords         []*Chord
 	ChordPositions []int
+	// ChordsAbove indicates the chords were written on a line of their own above the text, so
+	// the text may have been spaced out to line its syllables up with them.
+	ChordsAbove bool
+	Span        *Span
 }
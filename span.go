@@ -0,0 +1,27 @@
+package songtools
+
+import "fmt"
+
+// Position is a location in the source a song was parsed from.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Line is the line number, starting at 1.
+	Line int
+	// Column is the byte offset within the line, starting at 1.
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the range of source a node was parsed from. End is exclusive.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s *Span) String() string {
+	return fmt.Sprintf("%v-%v", s.Start, s.End)
+}
@@ -0,0 +1,309 @@
+package songtools
+
+import (
+	"sort"
+	"strings"
+)
+
+// Spelling is the written name of a note, made up of a letter and its sharps or flats.
+// Ab and G# are different spellings of the same Note.
+type Spelling struct {
+	Letter byte
+	// Accidental is the number of sharps when positive and the number of flats when negative.
+	Accidental int
+}
+
+const letters = "CDEFGAB"
+
+// ParseSpelling parses the text as the name of a note, such as Eb or F#.
+func ParseSpelling(text string) (Spelling, bool) {
+	s, n, ok := parseSpellingPrefix(text)
+	if !ok || n != len(text) {
+		return Spelling{}, false
+	}
+
+	return s, true
+}
+
+func parseSpellingPrefix(text string) (Spelling, int, bool) {
+	if text == "" || strings.IndexByte(letters, text[0]) == -1 {
+		return Spelling{}, 0, false
+	}
+
+	s := Spelling{Letter: text[0]}
+	n := 1
+	for i := 0; i < 2; i++ {
+		switch {
+		case strings.HasPrefix(text[n:], "#"):
+			s.Accidental++
+			n++
+		case strings.HasPrefix(text[n:], "♯"):
+			s.Accidental++
+			n += len("♯")
+		case strings.HasPrefix(text[n:], "b"):
+			s.Accidental--
+			n++
+		case strings.HasPrefix(text[n:], "♭"):
+			s.Accidental--
+			n += len("♭")
+		}
+	}
+
+	return s, n, true
+}
+
+// Note returns the Note the spelling names.
+func (s Spelling) Note() Note {
+	return letterNotes[s.Letter].Interval(s.Accidental)
+}
+
+func (s Spelling) String() string {
+	return string(s.Letter) + accidentalString(s.Accidental)
+}
+
+// letterIndex is the position of the letter counting up from C.
+func (s Spelling) letterIndex() int {
+	return strings.IndexByte(letters, s.Letter)
+}
+
+// spellAbove spells the note the number of semitones above s, using the letter that
+// is the number of letters above s. For instance, a third above Eb is spelled with a G.
+func (s Spelling) spellAbove(letterSteps, semitones int) Spelling {
+	letter := letters[(s.letterIndex()+letterSteps)%len(letters)]
+	target := s.Note().Interval(semitones)
+	return spellWithLetter(letter, target)
+}
+
+// spellWithLetter spells the note using the letter and however many sharps or flats are needed.
+func spellWithLetter(letter byte, n Note) Spelling {
+	accidental := (int(n) - int(letterNotes[letter]) + noteCount) % noteCount
+	if accidental > noteCount/2 {
+		accidental -= noteCount
+	}
+
+	return Spelling{Letter: letter, Accidental: accidental}
+}
+
+// spellingFromNames spells the note with the NoteNames.
+func spellingFromNames(n Note, names *NoteNames) Spelling {
+	s, _ := ParseSpelling(n.StringFromNames(names))
+	return s
+}
+
+// chordTone is a note in a chord, described by its scale degree and its
+// distance in semitones from the root.
+type chordTone struct {
+	degree    int
+	semitones int
+}
+
+var naturalSemitones = map[int]int{1: 0, 2: 2, 3: 4, 4: 5, 5: 7, 6: 9, 7: 11, 9: 14, 11: 17, 13: 21}
+
+// tones computes the chord tones from the structure of the chord.
+func (c *Chord) tones() []chordTone {
+	tones := []chordTone{{1, 0}}
+
+	switch c.Quality {
+	case Major, Augmented:
+		tones = append(tones, chordTone{3, 4})
+	case Minor, Diminished, HalfDiminished:
+		tones = append(tones, chordTone{3, 3})
+	case Suspended2:
+		tones = append(tones, chordTone{2, 2})
+	case Suspended4:
+		tones = append(tones, chordTone{4, 5})
+	}
+
+	switch c.Quality {
+	case Diminished, HalfDiminished:
+		tones = append(tones, chordTone{5, 6})
+	case Augmented:
+		tones = append(tones, chordTone{5, 8})
+	default:
+		tones = append(tones, chordTone{5, 7})
+	}
+
+	if c.Extension >= 7 {
+		switch {
+		case c.MajorSeventh:
+			tones = append(tones, chordTone{7, 11})
+		case c.Quality == Diminished:
+			tones = append(tones, chordTone{7, 9})
+		default:
+			tones = append(tones, chordTone{7, 10})
+		}
+	}
+	if c.Extension >= 9 {
+		tones = append(tones, chordTone{9, 14})
+	}
+	// the 11th clashes with the major 3rd, so a major 13th chord leaves it out.
+	if c.Extension == 11 || (c.Extension == 13 && c.Quality == Minor) {
+		tones = append(tones, chordTone{11, 17})
+	}
+	if c.Extension >= 13 {
+		tones = append(tones, chordTone{13, 21})
+	}
+
+	for _, d := range c.Added {
+		tones = append(tones, chordTone{d, naturalSemitones[d]})
+	}
+
+	altered := map[int]bool{}
+	for _, a := range c.Alterations {
+		if !altered[a.Degree] {
+			tones = removeDegree(tones, a.Degree)
+			altered[a.Degree] = true
+		}
+		tones = append(tones, chordTone{a.Degree, naturalSemitones[a.Degree] + a.Accidental})
+	}
+
+	for _, d := range c.Omitted {
+		tones = removeDegree(tones, d)
+	}
+
+	sort.SliceStable(tones, func(i, j int) bool {
+		return tones[i].degree < tones[j].degree
+	})
+
+	return tones
+}
+
+func removeDegree(tones []chordTone, degree int) []chordTone {
+	kept := []chordTone{}
+	for _, t := range tones {
+		if t.degree != degree {
+			kept = append(kept, t)
+		}
+	}
+
+	return kept
+}
+
+// Notes returns the notes in the chord. The root comes first followed by the rest of the chord
+// tones in order. For slash chords, the bass comes first instead. Unknown chords have no notes.
+func (c *Chord) Notes() []Note {
+	notes := []Note{}
+	for _, s := range c.Spell(Key{}) {
+		notes = append(notes, s.Note())
+	}
+
+	return notes
+}
+
+// Omittable returns the notes that may be left out when the chord is played with fewer notes,
+// in the order they are left out. The perfect 5th goes first, then the 11th and the 9th of
+// chords with a 7th, and last the root. The bass of a slash chord is never left out.
+func (c *Chord) Omittable() []Note {
+	if c.Unknown {
+		return nil
+	}
+
+	tones := c.tones()
+	seventh := false
+	for _, t := range tones {
+		if t.degree == 7 {
+			seventh = true
+		}
+	}
+
+	notes := []Note{}
+	for _, degree := range []int{5, 11, 9, 1} {
+		for _, t := range tones {
+			switch {
+			case t.degree != degree:
+			case degree == 5 && t.semitones != naturalSemitones[5]:
+			case (degree == 9 || degree == 11) && !seventh:
+			case c.Base != c.Root && c.Root.Interval(t.semitones) == c.Base:
+			default:
+				notes = append(notes, c.Root.Interval(t.semitones))
+			}
+		}
+	}
+
+	return notes
+}
+
+// Spell returns the correctly spelled names of the notes in the chord, in the same order as Notes.
+// Each chord tone is spelled relative to the root, so Ebmaj7 is Eb G Bb D. The root and bass are
+// spelled as they are written in the chord's name, unless the key has an enharmonic equivalent in
+// its scale, in which case that is used. The key may be the zero Key.
+func (c *Chord) Spell(key Key) []Spelling {
+	if c.Unknown {
+		return nil
+	}
+
+	root := c.RootSpelling(key)
+	spellings := []Spelling{}
+	for _, t := range c.tones() {
+		spellings = append(spellings, root.spellAbove(t.degree-1, t.semitones))
+	}
+
+	if c.Base == c.Root {
+		return spellings
+	}
+
+	// when the bass is a chord tone, use the chord's spelling of it.
+	base := c.BaseSpelling(key)
+	rest := []Spelling{}
+	for _, s := range spellings {
+		if s.Note() == c.Base {
+			base = s
+		} else {
+			rest = append(rest, s)
+		}
+	}
+
+	return append([]Spelling{base}, rest...)
+}
+
+// RootSpelling spells the root of the chord as it is written in the name, preferring the key's spelling.
+func (c *Chord) RootSpelling(key Key) Spelling {
+	root, _, ok := parseSpellingPrefix(c.Name)
+	if !ok || root.Note() != c.Root {
+		root = spellingFromNames(c.Root, noteNamesOrSharps(key))
+	}
+
+	return preferScale(root, key.Scale())
+}
+
+// BaseSpelling spells the bass of the chord as it is written in the name, preferring the key's spelling.
+func (c *Chord) BaseSpelling(key Key) Spelling {
+	if c.Base == c.Root {
+		return c.RootSpelling(key)
+	}
+
+	base := spellingFromNames(c.Base, noteNamesOrSharps(key))
+	if idx := strings.LastIndex(c.Name, "/"); idx != -1 {
+		if written, ok := ParseSpelling(c.Name[idx+1:]); ok && written.Note() == c.Base {
+			base = written
+		}
+	}
+
+	return preferScale(base, key.Scale())
+}
+
+func noteNamesOrSharps(key Key) *NoteNames {
+	if key.IsZero() {
+		return sharpNoteNames
+	}
+
+	return key.NoteNames()
+}
+
+// preferScale returns the enharmonic equivalent of s that is in the scale, if there is one.
+func preferScale(s Spelling, scale []Spelling) Spelling {
+	for _, ss := range scale {
+		if ss == s {
+			return s
+		}
+	}
+	for _, ss := range scale {
+		if ss.Note() == s.Note() {
+			return ss
+		}
+	}
+
+	return s
+}
+
+var majorScale = []int{0, 2, 4, 5, 7, 9, 11}
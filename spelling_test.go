@@ -0,0 +1,102 @@
+package songtools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpell(t *testing.T) {
+	tests := []struct {
+		chord    string
+		key      string
+		expected string
+	}{
+		{"Ebmaj7", "", "Eb G Bb D"},
+		{"Ebmaj7", "Bb", "Eb G Bb D"},
+		{"Db7#9", "", "Db F Ab Cb E"},
+		{"C#7b9", "", "C# E# G# B D"},
+		// the key decides between sharps and flats.
+		{"D", "D", "D F# A"},
+		{"F#", "D", "F# A# C#"},
+		{"Gb", "D", "F# A# C#"},
+		{"F#", "Db", "Gb Bb Db"},
+		{"Gb", "Db", "Gb Bb Db"},
+		{"A#m", "Bbm", "Bb Db F"},
+		{"B", "Gb", "Cb Eb Gb"},
+		// double sharps and flats.
+		{"Ebdim7", "", "Eb Gb Bbb Dbb"},
+		{"G#aug", "", "G# B# D##"},
+		{"E#", "", "E# G## B#"},
+		// the bass of a slash chord comes first.
+		{"D/F#", "D", "F# D A"},
+		{"Eb/G", "Eb", "G Eb Bb"},
+		{"C/D", "", "D C E G"},
+		{"C/Bb", "C", "Bb C E G"},
+		{"C/A#", "F", "Bb C E G"},
+		{"Abm/Cb", "", "Cb Ab Eb"},
+	}
+
+	for _, test := range tests {
+		c, ok := ParseChord(test.chord)
+		if !ok {
+			t.Fatalf("unable to parse %q", test.chord)
+		}
+
+		key := Key{}
+		if test.key != "" {
+			key = MustParseKey(test.key)
+		}
+
+		names := []string{}
+		for _, s := range c.Spell(key) {
+			names = append(names, s.String())
+		}
+		if actual := strings.Join(names, " "); actual != test.expected {
+			t.Errorf("%q.Spell(%v) = %q, expected %q", test.chord, test.key, actual, test.expected)
+		}
+	}
+}
+
+func TestChordNotes(t *testing.T) {
+	tests := []struct {
+		chord     string
+		notes     string
+		omittable string
+	}{
+		{"C", "C E G", "G C"},
+		{"Ebmaj7", "D# G A# D", "A# D#"},
+		{"C9", "C E G A# D", "G D C"},
+		{"Cm11", "C D# G A# D F", "G F D C"},
+		{"Cadd9", "C E G D", "G C"},
+		{"Cdim", "C D# F#", "C"},
+		// the bass of a slash chord is never left out.
+		{"D/F#", "F# D A", "A D"},
+		{"C/G", "G C E", "C"},
+	}
+
+	join := func(notes []Note) string {
+		names := []string{}
+		for _, n := range notes {
+			names = append(names, n.String())
+		}
+		return strings.Join(names, " ")
+	}
+
+	for _, test := range tests {
+		c, ok := ParseChord(test.chord)
+		if !ok {
+			t.Fatalf("unable to parse %q", test.chord)
+		}
+
+		if actual := join(c.Notes()); actual != test.notes {
+			t.Errorf("%q.Notes() = %q, expected %q", test.chord, actual, test.notes)
+		}
+		if actual := join(c.Omittable()); actual != test.omittable {
+			t.Errorf("%q.Omittable() = %q, expected %q", test.chord, actual, test.omittable)
+		}
+	}
+
+	if notes := UnknownChord("N.C.").Notes(); len(notes) != 0 {
+		t.Errorf("expected an unknown chord to have no notes, but got %v", notes)
+	}
+}
@@ -1,69 +1,323 @@
 package songtools
 
-// TransposeSong transposes a Song.
-func TransposeSong(s *Song, interval int, names *NoteNames) (*Song, error) {
+import "fmt"
+
+// TransposeSong transposes a Song from one key to another. Each chord is spelled in the new
+// key on the same scale degree, with the same accidental, as it had in the original key, so
+// the chords keep their function. Transposing doesn't change the mode, so the target key must
+// have the same mode as the original key, such as Am to Cm. Key changes in the song move by
+// the same amount, so a song that modulates up a whole step still does. The keys are the
+// sounding keys, so a song with a capo keeps its capo and its chords are transposed as
+// shapes. Chord definitions are kept in the same order, and those whose fingerings use open
+// strings, which can't be moved, are kept by name only.
+func TransposeSong(s *Song, from, to Key) (*Song, error) {
+	t, err := newTransposer(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	newSong, err := newTransposeMapper(t, from, s.Capo).song(s)
+	if err != nil {
+		return nil, err
+	}
+
+	newSong.Key = t.to
+	if !s.Key.IsZero() {
+		newSong.Key = t.key(s.Key)
+	}
+
+	return newSong, nil
+}
+
+// TransposeSection transposes a Section from one key to another, as TransposeSong does.
+func TransposeSection(s *Section, from, to Key) (*Section, error) {
+	t, err := newTransposer(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransposeMapper(t, from, 0).section(s)
+}
+
+// TransposeLine transposes a Line from one key to another, as TransposeSong does.
+func TransposeLine(l *Line, from, to Key) (*Line, error) {
+	t, err := newTransposer(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransposeMapper(t, from, 0).line(l)
+}
+
+// newTransposeMapper creates a chordMapper that transposes each chord between the key in effect
+// where it appears and that key moved by t. With a capo, the chords are transposed as shapes.
+func newTransposeMapper(t *transposer, from Key, capo int) *chordMapper {
+	return &chordMapper{
+		key: from,
+		chord: func(c *Chord, key Key) (*Chord, error) {
+			shapes, err := newTransposer(shapeKey(key, capo), shapeKey(t.key(key), capo))
+			if err != nil {
+				return nil, err
+			}
+
+			return shapes.chord(c)
+		},
+		keyChange: t.key,
+	}
+}
+
+// transposer moves notes from one key to another, keeping their scale degree and accidental.
+type transposer struct {
+	from Key
+	to   Key
+}
+
+func newTransposer(from, to Key) (*transposer, error) {
+	if from.IsZero() {
+		return nil, fmt.Errorf("the original key is unknown")
+	}
+	if to.IsZero() {
+		return nil, fmt.Errorf("the target key is unknown")
+	}
+	if to.Mode != from.Mode {
+		return nil, fmt.Errorf("the target key %v is %v, but the original key %v is %v, and transposing doesn't change the mode", to, to.Mode, from, from.Mode)
+	}
+
+	return &transposer{
+		from: from,
+		to:   to,
+	}, nil
+}
+
+// spell spells the note in the target key. Chromatic notes may need a double sharp or flat,
+// such as the raised 4th of C#, F##, but never more than that.
+func (t *transposer) spell(s Spelling) Spelling {
+	degree, accidental, _ := t.from.ScaleDegree(s)
+	spelled := t.to.Scale()[degree-1]
+	spelled.Accidental += accidental
+	if abs(spelled.Accidental) > 2 {
+		return t.to.SpellNote(spelled.Note())
+	}
+
+	return spelled
+}
+
+// key moves the key by the same amount as the transposer, keeping its mode. The original key
+// becomes the target key as it was given. Any other key, such as a key change, is respelled
+// with the fewest sharps or flats when that takes fewer than its spelling on the same degree,
+// so F# moved from F to E is F rather than E#.
+func (t *transposer) key(k Key) Key {
+	if k == t.from {
+		return t.to
+	}
+
+	moved := Key{Tonic: t.spell(k.Tonic), Mode: k.Mode}
+	if practical := moved.Interval(0); abs(practical.Signature()) < abs(moved.Signature()) {
+		return practical
+	}
+
+	return moved
+}
+
+func (t *transposer) chord(c *Chord) (*Chord, error) {
+	if c.Unknown {
+		return UnknownChord(c.Name), nil
+	}
+
+	root := t.spell(c.RootSpelling(t.from))
+	base := root
+	name := root.String() + c.Suffix
+	if c.Base != c.Root {
+		base = t.spell(c.BaseSpelling(t.from))
+		name += "/" + base.String()
+	}
+
+	transposed := *c
+	transposed.Name = name
+	transposed.Root = root.Note()
+	transposed.Base = base.Note()
+	return &transposed, nil
+}
+
+// chordMapper creates copies of songs, sections and lines with each chord replaced. Chord is
+// called with the key in effect where the chord appears, which starts as key and is updated
+// by each key change. When keyChange is set, it replaces the key of each key change.
+type chordMapper struct {
+	key       Key
+	chord     func(c *Chord, key Key) (*Chord, error)
+	keyChange func(key Key) Key
+}
+
+// song creates a copy of the song with each chord replaced.
+func (m *chordMapper) song(s *Song) (*Song, error) {
+	newDefinitions, err := m.definitions(s.Definitions)
+	if err != nil {
+		return nil, err
+	}
+
 	newNodes := []SongNode{}
 	for _, n := range s.Nodes {
 		switch typedN := n.(type) {
 		case *Section:
-			newSection, err := TransposeSection(typedN, interval, names)
+			newSection, err := m.section(typedN)
 			if err != nil {
 				return nil, err
 			}
 
 			newNodes = append(newNodes, newSection)
+		case *KeyChange:
+			newNodes = append(newNodes, m.changeKey(typedN))
 		default:
 			newNodes = append(newNodes, n)
 		}
 	}
 
-	key := s.Key
-	if key != "" {
-		if c, ok := ParseChord(string(key)); ok {
-			key = Key(c.Interval(interval, names).Name)
+	return &Song{
+		Title:       s.Title,
+		Subtitles:   s.Subtitles,
+		Authors:     s.Authors,
+		Key:         s.Key,
+		Capo:        s.Capo,
+		Definitions: newDefinitions,
+		Nodes:       newNodes,
+	}, nil
+}
+
+// definitions creates copies of the definitions for the replaced chords, in the key the song
+// starts in, with each copy in the same place as its original. The fingerings move with the
+// chords, and those that can't be moved are left out, so the copy only refers to the chord by
+// name. Definitions of names that aren't chords are kept as they are.
+func (m *chordMapper) definitions(defs []*ChordDefinition) ([]*ChordDefinition, error) {
+	var newDefs []*ChordDefinition
+	for _, d := range defs {
+		c, ok := ParseChord(d.Name)
+		if !ok {
+			newDefs = append(newDefs, d)
+			continue
 		}
+
+		newChord, err := m.chord(c, m.key)
+		if err != nil {
+			return nil, err
+		}
+
+		newDefs = append(newDefs, d.renamed(c, newChord))
 	}
 
-	return &Song{
-		Title:     s.Title,
-		Subtitles: s.Subtitles,
-		Authors:   s.Authors,
-		Key:       key,
-		Nodes:     newNodes,
-	}, nil
+	return newDefs, nil
 }
 
-// TransposeSection transposes a Section.
-func TransposeSection(s *Section, interval int, names *NoteNames) (*Section, error) {
+// section creates a copy of the section with each chord replaced.
+func (m *chordMapper) section(s *Section) (*Section, error) {
 	newNodes := []SectionNode{}
 	for _, n := range s.Nodes {
 		switch typedN := n.(type) {
 		case *Line:
-			newLine, err := TransposeLine(typedN, interval, names)
+			newLine, err := m.line(typedN)
 			if err != nil {
 				return nil, err
 			}
 
 			newNodes = append(newNodes, newLine)
+		case *Grid:
+			newGrid, err := m.grid(typedN)
+			if err != nil {
+				return nil, err
+			}
+
+			newNodes = append(newNodes, newGrid)
+		case *KeyChange:
+			newNodes = append(newNodes, m.changeKey(typedN))
 		default:
 			newNodes = append(newNodes, n)
 		}
 	}
 
-	return &Section{s.Kind, newNodes}, nil
+	return &Section{
+		Kind:      s.Kind,
+		Label:     s.Label,
+		Delimited: s.Delimited,
+		Nodes:     newNodes,
+		Span:      s.Span,
+	}, nil
 }
 
-// TransposeLine transposes a Line.
-func TransposeLine(l *Line, interval int, names *NoteNames) (*Line, error) {
+// line creates a copy of the line with each chord replaced.
+func (m *chordMapper) line(l *Line) (*Line, error) {
 	if l.Chords == nil {
 		return l, nil
 	}
 
 	newChords := []*Chord{}
 	for _, c := range l.Chords {
-		newChord := c.Interval(interval, names)
+		newChord, err := m.chord(c, m.key)
+		if err != nil {
+			return nil, err
+		}
 		newChords = append(newChords, newChord)
 	}
 
-	return &Line{l.Text, newChords, l.ChordPositions}, nil
+	return &Line{
+		Text:           l.Text,
+		Chords:         newChords,
+		ChordPositions: l.ChordPositions,
+		ChordsAbove:    l.ChordsAbove,
+		Span:           l.Span,
+	}, nil
+}
+
+// grid creates a copy of the grid with each chord replaced.
+func (m *chordMapper) grid(g *Grid) (*Grid, error) {
+	newRows := []*GridRow{}
+	for _, r := range g.Rows {
+		newBars := []*Bar{}
+		for _, b := range r.Bars {
+			newBeats := []*Chord{}
+			for _, c := range b.Beats {
+				if c == nil {
+					newBeats = append(newBeats, nil)
+					continue
+				}
+
+				newChord, err := m.chord(c, m.key)
+				if err != nil {
+					return nil, err
+				}
+				newBeats = append(newBeats, newChord)
+			}
+
+			newBars = append(newBars, &Bar{
+				Barline: b.Barline,
+				Volta:   b.Volta,
+				Beats:   newBeats,
+				Slashes: b.Slashes,
+				Simile:  b.Simile,
+			})
+		}
+
+		newRows = append(newRows, &GridRow{
+			Label:   r.Label,
+			Bars:    newBars,
+			End:     r.End,
+			Comment: r.Comment,
+		})
+	}
+
+	return &Grid{
+		Rows: newRows,
+		Span: g.Span,
+	}, nil
+}
+
+// changeKey moves the mapper into the new key and returns the key change to use in the copy.
+func (m *chordMapper) changeKey(k *KeyChange) *KeyChange {
+	m.key = k.Key
+	if m.keyChange == nil {
+		return k
+	}
+
+	return &KeyChange{
+		Key:  m.keyChange(k.Key),
+		Span: k.Span,
+	}
 }
@@ -0,0 +1,213 @@
+package songtools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransposeChords(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		chords   string
+		expected string
+	}{
+		{"C", "D", "C G Am F", "D A Bm G"},
+		{"C", "Db", "C G Am F D7 F#dim7 Bb E7 G/B Ab C/E", "Db Ab Bbm Gb Eb7 Gdim7 Cb F7 Ab/C Bbb Db/F"},
+		{"C", "F#", "C G Am F D7 G/B", "F# C# D#m B G#7 C#/E#"},
+		{"C", "F", "C G Am F D7 Bb E7 C#dim", "F C Dm Bb G7 Eb A7 F#dim"},
+		{"Am", "Ebm", "Am E7 G#dim F C G", "Ebm Bb7 Ddim Cb Gb Db"},
+		{"G", "Eb", "G D/F# Em C Cm A7", "Eb Bb/D Cm Ab Abm F7"},
+	}
+
+	for _, test := range tests {
+		transposed, err := TransposeLine(chordLine(test.chords), MustParseKey(test.from), MustParseKey(test.to))
+		if err != nil {
+			t.Errorf("%v to %v: unexpected error: %v", test.from, test.to, err)
+			continue
+		}
+
+		if actual := chordNames(transposed); actual != test.expected {
+			t.Errorf("%v to %v: expected %q, but got %q", test.from, test.to, test.expected, actual)
+		}
+	}
+}
+
+func TestTransposeSongKeyChanges(t *testing.T) {
+	tests := []struct {
+		key      string
+		change   string
+		chords   string
+		to       string
+		newKey   string
+		expected string
+	}{
+		{"F", "F#", "F# C# B", "E", "F", "F C Bb"},
+		{"C", "D", "D A G", "Bb", "C", "C G F"},
+		{"C", "Db", "Db Ab Gb", "B", "C", "C G F"},
+		{"G", "Ab", "Ab Eb Db", "Gb", "G", "G D C"},
+		{"E", "F", "F C Bb", "Db", "D", "D A G"},
+		{"C", "D", "D A G", "C#", "Eb", "Eb Bb Ab"},
+	}
+
+	for _, test := range tests {
+		s := &Song{
+			Key: MustParseKey(test.key),
+			Nodes: []SongNode{
+				&Section{Nodes: []SectionNode{
+					&KeyChange{Key: MustParseKey(test.change)},
+					chordLine(test.chords),
+				}},
+			},
+		}
+
+		transposed, err := TransposeSong(s, s.Key, MustParseKey(test.to))
+		if err != nil {
+			t.Errorf("%v to %v: unexpected error: %v", test.key, test.to, err)
+			continue
+		}
+
+		if transposed.Key.String() != test.to {
+			t.Errorf("%v to %v: expected the song to be in %v, but got %v", test.key, test.to, test.to, transposed.Key)
+		}
+
+		section := transposed.Nodes[0].(*Section)
+		if k := section.Nodes[0].(*KeyChange).Key.String(); k != test.newKey {
+			t.Errorf("%v to %v: expected the key change to %v, but got %v", test.key, test.to, test.newKey, k)
+		}
+
+		if actual := chordNames(section.Nodes[1].(*Line)); actual != test.expected {
+			t.Errorf("%v to %v: expected %q after the key change, but got %q", test.key, test.to, test.expected, actual)
+		}
+	}
+}
+
+func TestTransposeSongKeyChangeBetweenSections(t *testing.T) {
+	s := &Song{
+		Key: MustParseKey("G"),
+		Nodes: []SongNode{
+			&Section{Nodes: []SectionNode{chordLine("G C D")}},
+			&KeyChange{Key: MustParseKey("A")},
+			&Section{Nodes: []SectionNode{chordLine("A D E")}},
+		},
+	}
+
+	transposed, err := TransposeSong(s, s.Key, MustParseKey("C"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if k := transposed.Nodes[1].(*KeyChange).Key.String(); k != "D" {
+		t.Errorf("expected the key change to D, but got %v", k)
+	}
+	if actual := chordNames(transposed.Nodes[0].(*Section).Nodes[0].(*Line)); actual != "C F G" {
+		t.Errorf("expected %q before the key change, but got %q", "C F G", actual)
+	}
+	if actual := chordNames(transposed.Nodes[2].(*Section).Nodes[0].(*Line)); actual != "D G A" {
+		t.Errorf("expected %q after the key change, but got %q", "D G A", actual)
+	}
+}
+
+func TestTransposeSongKeepsTabs(t *testing.T) {
+	lines := []string{"e|--[C]--0--|", "B|--1--G--|"}
+	s := &Song{
+		Key: MustParseKey("C"),
+		Nodes: []SongNode{
+			&Section{Kind: "Tab", Nodes: []SectionNode{&Tab{Lines: lines}}},
+			&Section{Nodes: []SectionNode{chordLine("C G")}},
+		},
+	}
+
+	transposed, err := TransposeSong(s, s.Key, MustParseKey("D"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tab := transposed.Nodes[0].(*Section).Nodes[0].(*Tab)
+	if strings.Join(tab.Lines, "\n") != strings.Join(lines, "\n") {
+		t.Errorf("expected the tab %q to be untouched, but got %q", lines, tab.Lines)
+	}
+	if actual := chordNames(transposed.Nodes[1].(*Section).Nodes[0].(*Line)); actual != "D A" {
+		t.Errorf("expected %q, but got %q", "D A", actual)
+	}
+}
+
+// chordLine makes a line of the chords, which are separated by spaces.
+func chordLine(chords string) *Line {
+	l := &Line{}
+	for i, name := range strings.Fields(chords) {
+		c, ok := ParseChord(name)
+		if !ok {
+			c = UnknownChord(name)
+		}
+		l.Chords = append(l.Chords, c)
+		l.ChordPositions = append(l.ChordPositions, i)
+	}
+
+	return l
+}
+
+// chordNames gets the names of the chords in the line, separated by spaces.
+func chordNames(l *Line) string {
+	names := []string{}
+	for _, c := range l.Chords {
+		names = append(names, c.Name)
+	}
+
+	return strings.Join(names, " ")
+}
+
+func TestTransposeSongModes(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		expected string
+		ok       bool
+	}{
+		{"G", "A", "A", true},
+		{"Am", "Cm", "Cm", true},
+		{"D dorian", "E dorian", "E dorian", true},
+		{"G", "F#m", "", false},
+		{"Am", "C", "", false},
+		{"G", "A mixolydian", "", false},
+	}
+
+	for _, test := range tests {
+		s := &Song{Key: MustParseKey(test.from), Nodes: []SongNode{&Section{Nodes: []SectionNode{chordLine("G C D")}}}}
+		transposed, err := TransposeSong(s, s.Key, MustParseKey(test.to))
+		if (err == nil) != test.ok {
+			t.Errorf("%v to %v: expected an error: %v, but was %v", test.from, test.to, !test.ok, err)
+			continue
+		}
+		if err == nil && transposed.Key.String() != test.expected {
+			t.Errorf("%v to %v: expected the key %q, but got %q", test.from, test.to, test.expected, transposed.Key)
+		}
+	}
+}
+
+func TestNoteNamesAndIntervalFromKeyToKeyModes(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		interval int
+		ok       bool
+	}{
+		{"G", "A", 2, true},
+		{"Am", "Cm", 3, true},
+		{"D dorian", "E dorian", 2, true},
+		{"G", "F#m", 0, false},
+		{"Am", "C", 0, false},
+		{"G", "A mixolydian", 0, false},
+	}
+
+	for _, test := range tests {
+		_, interval, err := NoteNamesAndIntervalFromKeyToKey(MustParseKey(test.from), MustParseKey(test.to))
+		if (err == nil) != test.ok {
+			t.Errorf("%v to %v: expected an error: %v, but was %v", test.from, test.to, !test.ok, err)
+			continue
+		}
+		if err == nil && (interval+12)%12 != test.interval {
+			t.Errorf("%v to %v: expected the interval %d, but got %d", test.from, test.to, test.interval, interval)
+		}
+	}
+}